@@ -1,11 +1,27 @@
 package utils
 
-import "huancuilou/configs"
+import (
+	"fmt"
+	"html"
+	"huancuilou/configs"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
 
 func ValidateArticleKind(kind string) bool {
 	return configs.GetConfig().Article.KindMap[kind]
 }
 
+// HotScore 按配置的衰减公式计算文章热度，age 为文章发布至今的时长
+func HotScore(like int, comment int, view int, age time.Duration) float64 {
+	hot := configs.GetConfig().Article.Hot
+	points := hot.LikeWeight*float64(like) + hot.CommentWeight*float64(comment) + hot.ViewWeight*float64(view) + 1
+	return points / math.Pow(max(age.Hours(), 0)+hot.AgeOffset, hot.Gravity)
+}
+
 // Substring 截取字符串的前 n 个字符
 func Substring(s string, n int) string {
 	// 先将字符串转换为 rune 切片，rune 可以正确处理多字节字符
@@ -16,3 +32,143 @@ func Substring(s string, n int) string {
 	// 截取前 n 个 rune 并转换回 string
 	return string(runes[:n])
 }
+
+// NormalizeTags 去除标签首尾空白并去重，校验标签数量与长度
+func NormalizeTags(tags []string) ([]string, error) {
+	articleConfig := configs.GetConfig().Article
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("标签不能包含逗号: %s", tag)
+		}
+		if len([]rune(tag)) > articleConfig.MaxTagLength {
+			return nil, fmt.Errorf("标签长度不能超过%d个字符: %s", articleConfig.MaxTagLength, tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > articleConfig.MaxTags {
+		return nil, fmt.Errorf("标签数量不能超过%d个", articleConfig.MaxTags)
+	}
+	return normalized, nil
+}
+
+// SplitTags 将缓存中以逗号拼接的标签还原为切片
+func SplitTags(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// SegmentKeyword 将搜索关键词切分为用于高亮的词项
+// 先按空白和标点切分，连续的中文再按 n 元切分，与 MySQL ngram 解析器的分词方式保持一致
+// 返回的词项按长度降序排列，高亮时优先匹配完整的词
+func SegmentKeyword(keyword string, n int) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(term string) {
+		if term == "" || seen[term] {
+			return
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+
+	fields := strings.FieldsFunc(strings.ToLower(keyword), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	for _, field := range fields {
+		add(field)
+		runes := []rune(field)
+		if n <= 0 || len(runes) <= n {
+			continue
+		}
+		for i := 0; i+n <= len(runes); i++ {
+			if isHan(runes[i : i+n]) {
+				add(string(runes[i : i+n]))
+			}
+		}
+	}
+
+	sort.SliceStable(terms, func(i, j int) bool {
+		return len([]rune(terms[i])) > len([]rune(terms[j]))
+	})
+	return terms
+}
+
+// HighlightFragment 在文本中用 <em> 标记命中的词项，并截取命中位置附近长度为 length 的片段
+// 文本中的其余内容会做 HTML 转义，length 小于等于 0 时返回完整文本
+func HighlightFragment(text string, terms []string, length int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		termRunes := []rune(term)
+		if len(termRunes) == 0 {
+			continue
+		}
+		for i := 0; i+len(termRunes) <= len(lower); i++ {
+			if string(lower[i:i+len(termRunes)]) != term {
+				continue
+			}
+			for j := i; j < i+len(termRunes); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+			i += len(termRunes) - 1
+		}
+	}
+
+	start, end := 0, len(runes)
+	if length > 0 && len(runes) > length {
+		if first > length/4 {
+			start = first - length/4
+		}
+		end = start + length
+		if end > len(runes) {
+			end = len(runes)
+			start = end - length
+		}
+	}
+
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("...")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			builder.WriteString("<em>")
+		}
+		builder.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			builder.WriteString("</em>")
+		}
+	}
+	if end < len(runes) {
+		builder.WriteString("...")
+	}
+	return builder.String()
+}
+
+// isHan 判断字符是否全部为汉字
+func isHan(runes []rune) bool {
+	for _, r := range runes {
+		if !unicode.Is(unicode.Han, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// BloomOffsets 计算元素在布隆过滤器位图中对应的 k 个位置，位图共 m 位
+// 使用双重哈希 h1 + i*h2 模拟 k 个独立的哈希函数，h1 与 h2 分别取 FNV-1a 64 位哈希的高低 32 位
+func BloomOffsets(data string, k int, m uint64) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(data))
+	sum := h.Sum64()
+	h1, h2 := sum>>32, sum&math.MaxUint32|1
+	offsets := make([]uint64, k)
+	for i := range offsets {
+		offsets[i] = (h1 + uint64(i)*h2) % m
+	}
+	return offsets
+}
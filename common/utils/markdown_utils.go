@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 文章正文格式
+const (
+	ArticleFormatPlain    = "plain"
+	ArticleFormatMarkdown = "markdown"
+)
+
+var (
+	headingRegex      = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	unorderedRegex    = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	orderedRegex      = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	quoteRegex        = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	ruleRegex         = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))+\s*$`)
+	fenceRegex        = regexp.MustCompile("^\\s*```\\s*([\\w+-]*)\\s*$")
+	codeSpanRegex     = regexp.MustCompile("`([^`]+)`")
+	imageRegex        = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	linkRegex         = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldRegex         = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	italicRegex       = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	strikeRegex       = regexp.MustCompile(`~~(.+?)~~`)
+	placeholderRegex  = regexp.MustCompile("\x00(\\d+)\x00")
+	whitespaceRegex   = regexp.MustCompile(`\s+`)
+	blankLineRegex    = regexp.MustCompile(`\n\s*\n`)
+	safeURLPrefixList = []string{"http://", "https://", "mailto:", "/"}
+	// 浏览器解析链接时删除的字符
+	urlWhitespaceReplacer = strings.NewReplacer("\t", "", "\n", "", "\r", "")
+)
+
+// ValidateArticleFormat 校验文章正文格式，空字符串视为纯文本
+func ValidateArticleFormat(format string) bool {
+	return format == "" || format == ArticleFormatPlain || format == ArticleFormatMarkdown
+}
+
+// RenderArticleHTML 将文章正文渲染为可以直接展示的 HTML
+func RenderArticleHTML(content string, format string) string {
+	if format == ArticleFormatMarkdown {
+		return RenderMarkdown(content)
+	}
+	var builder strings.Builder
+	for _, paragraph := range splitParagraphs(content) {
+		builder.WriteString("<p>")
+		builder.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>"))
+		builder.WriteString("</p>\n")
+	}
+	return builder.String()
+}
+
+// ArticlePlainText 获取文章正文的纯文本，用于生成摘要和搜索片段
+func ArticlePlainText(content string, format string) string {
+	if format == ArticleFormatMarkdown {
+		return MarkdownToPlainText(content)
+	}
+	return content
+}
+
+// RenderMarkdown 将 Markdown 渲染为 HTML
+// 原文中的所有 HTML 都会被转义，只输出渲染器自己生成的标签，链接和图片只允许 http(s)、mailto 和站内路径，从而保证输出是安全的
+func RenderMarkdown(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var builder strings.Builder
+	var paragraph []string
+	listTag := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			builder.WriteString("<p>")
+			builder.WriteString(renderInline(strings.Join(paragraph, "\n")))
+			builder.WriteString("</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			builder.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			closeList()
+			builder.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			flushParagraph()
+			closeList()
+			var code []string
+			for i++; i < len(lines) && !fenceRegex.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			if match[1] != "" {
+				builder.WriteString(fmt.Sprintf(`<pre><code class="language-%s">`, html.EscapeString(match[1])))
+			} else {
+				builder.WriteString("<pre><code>")
+			}
+			builder.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			builder.WriteString("</code></pre>\n")
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+
+		if match := headingRegex.FindStringSubmatch(line); match != nil {
+			flushParagraph()
+			closeList()
+			level := len(match[1])
+			builder.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, renderInline(match[2]), level))
+			continue
+		}
+
+		if ruleRegex.MatchString(line) {
+			flushParagraph()
+			closeList()
+			builder.WriteString("<hr>\n")
+			continue
+		}
+
+		if match := quoteRegex.FindStringSubmatch(line); match != nil {
+			flushParagraph()
+			closeList()
+			quote := []string{match[1]}
+			for i+1 < len(lines) {
+				next := quoteRegex.FindStringSubmatch(lines[i+1])
+				if next == nil {
+					break
+				}
+				quote = append(quote, next[1])
+				i++
+			}
+			builder.WriteString("<blockquote>\n")
+			builder.WriteString(RenderMarkdown(strings.Join(quote, "\n")))
+			builder.WriteString("</blockquote>\n")
+			continue
+		}
+
+		if match := unorderedRegex.FindStringSubmatch(line); match != nil {
+			flushParagraph()
+			openList("ul")
+			builder.WriteString("<li>" + renderInline(match[1]) + "</li>\n")
+			continue
+		}
+
+		if match := orderedRegex.FindStringSubmatch(line); match != nil {
+			flushParagraph()
+			openList("ol")
+			builder.WriteString("<li>" + renderInline(match[1]) + "</li>\n")
+			continue
+		}
+
+		closeList()
+		paragraph = append(paragraph, strings.TrimSpace(line))
+	}
+	flushParagraph()
+	closeList()
+	return builder.String()
+}
+
+// renderInline 渲染行内元素，先整体转义 HTML，再把 Markdown 语法替换为标签
+func renderInline(text string) string {
+	text = strings.ReplaceAll(text, "\x00", "")
+	// 行内代码中的内容不再做其他替换，先用占位符保护起来
+	var codes []string
+	text = codeSpanRegex.ReplaceAllStringFunc(text, func(s string) string {
+		codes = append(codes, "<code>"+html.EscapeString(codeSpanRegex.FindStringSubmatch(s)[1])+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+
+	text = html.EscapeString(text)
+	text = imageRegex.ReplaceAllStringFunc(text, func(s string) string {
+		match := imageRegex.FindStringSubmatch(s)
+		if !isSafeURL(html.UnescapeString(match[2])) {
+			return match[1]
+		}
+		return fmt.Sprintf(`<img src="%s" alt="%s">`, match[2], match[1])
+	})
+	text = linkRegex.ReplaceAllStringFunc(text, func(s string) string {
+		match := linkRegex.FindStringSubmatch(s)
+		if !isSafeURL(html.UnescapeString(match[2])) {
+			return match[1]
+		}
+		return fmt.Sprintf(`<a href="%s" rel="nofollow noopener" target="_blank">%s</a>`, match[2], match[1])
+	})
+	text = boldRegex.ReplaceAllString(text, "<strong>$1</strong>")
+	text = italicRegex.ReplaceAllString(text, "<em>$1</em>")
+	text = strikeRegex.ReplaceAllString(text, "<del>$1</del>")
+	text = strings.ReplaceAll(text, "\n", "<br>")
+
+	return placeholderRegex.ReplaceAllStringFunc(text, func(s string) string {
+		var index int
+		fmt.Sscanf(placeholderRegex.FindStringSubmatch(s)[1], "%d", &index)
+		return codes[index]
+	})
+}
+
+// MarkdownToPlainText 去掉 Markdown 标记，只保留文字，图片保留其替代文本
+func MarkdownToPlainText(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	plain := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if fenceRegex.MatchString(line) || ruleRegex.MatchString(line) {
+			continue
+		}
+		if match := headingRegex.FindStringSubmatch(line); match != nil {
+			line = match[2]
+		} else if match := quoteRegex.FindStringSubmatch(line); match != nil {
+			line = match[1]
+		} else if match := unorderedRegex.FindStringSubmatch(line); match != nil {
+			line = match[1]
+		} else if match := orderedRegex.FindStringSubmatch(line); match != nil {
+			line = match[1]
+		}
+		line = codeSpanRegex.ReplaceAllString(line, "$1")
+		line = imageRegex.ReplaceAllString(line, "$1")
+		line = linkRegex.ReplaceAllString(line, "$1")
+		line = boldRegex.ReplaceAllString(line, "$1")
+		line = italicRegex.ReplaceAllString(line, "$1")
+		line = strikeRegex.ReplaceAllString(line, "$1")
+		plain = append(plain, line)
+	}
+	return strings.TrimSpace(strings.Join(plain, "\n"))
+}
+
+// ExtractCoverImage 取 Markdown 正文中第一张地址合法的图片作为封面，没有图片时返回空字符串
+func ExtractCoverImage(content string, format string) string {
+	if format != ArticleFormatMarkdown {
+		return ""
+	}
+	for _, match := range imageRegex.FindAllStringSubmatch(content, -1) {
+		if isSafeURL(match[2]) {
+			return match[2]
+		}
+	}
+	return ""
+}
+
+// Excerpt 从纯文本中截取长度不超过 n 个字符的摘要，n 不大于 0 时返回空字符串
+// 截断位置落在英文单词或数字中间时回退到单词边界，被截断的摘要以省略号结尾
+func Excerpt(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	text = strings.TrimSpace(whitespaceRegex.ReplaceAllString(text, " "))
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+
+	cut := n
+	if isWordRune(runes[cut-1]) && isWordRune(runes[cut]) {
+		for i := cut - 1; i > n/2; i-- {
+			if !isWordRune(runes[i]) {
+				cut = i + 1
+				break
+			}
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// isWordRune 判断字符是否属于以空格分词的文字（英文字母、数字等），汉字之间任意位置都可以截断
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !unicode.Is(unicode.Han, r)
+}
+
+// isSafeURL 判断链接是否为 http、https、mailto 或站内路径
+// 浏览器会忽略链接中的制表符和换行，并把 \ 当作 /，"//"、"/\" 开头的链接都会指向其他站点
+func isSafeURL(url string) bool {
+	lower := strings.ToLower(urlWhitespaceReplacer.Replace(strings.TrimSpace(url)))
+	if strings.HasPrefix(lower, "//") || strings.HasPrefix(lower, `/\`) {
+		return false
+	}
+	for _, prefix := range safeURLPrefixList {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitParagraphs(content string) []string {
+	var paragraphs []string
+	for _, paragraph := range blankLineRegex.Split(strings.ReplaceAll(content, "\r\n", "\n"), -1) {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
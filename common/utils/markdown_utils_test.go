@@ -0,0 +1,63 @@
+package utils
+
+import "testing"
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		n    int
+		want string
+	}{
+		{"n 为 0", "环翠楼", 0, ""},
+		{"n 为负数", "环翠楼", -1, ""},
+		{"不超过长度", "环翠楼公告", 5, "环翠楼公告"},
+		{"合并空白", "  环翠楼\n\n  公告 ", 10, "环翠楼 公告"},
+		{"汉字任意位置截断", "环翠楼小区停水通知", 4, "环翠楼小…"},
+		{"回退到单词边界", "hello world again", 8, "hello…"},
+		{"单词过长时直接截断", "abcdefghij", 4, "abcd…"},
+		{"去掉结尾的标点", "停水通知，请提前储水", 5, "停水通知…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excerpt(tt.text, tt.n); got != tt.want {
+				t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSafeURL(t *testing.T) {
+	safe := []string{
+		"https://example.com",
+		"http://example.com",
+		"HTTPS://EXAMPLE.COM",
+		"mailto:admin@example.com",
+		"/article/1",
+		"  /article/1  ",
+	}
+	for _, url := range safe {
+		if !isSafeURL(url) {
+			t.Errorf("isSafeURL(%q) = false, want true", url)
+		}
+	}
+
+	// 浏览器会去掉链接中的制表符和换行并把 \ 当作 /，这些写法都会跳到脚本或其他站点
+	unsafe := []string{
+		"javascript:alert(1)",
+		"JavaScript:alert(1)",
+		"java\tscript:alert(1)",
+		"data:text/html,<script>",
+		"//evil.com",
+		`/\evil.com`,
+		"/\t/evil.com",
+		"/\n/evil.com",
+		"article/1",
+		"",
+	}
+	for _, url := range unsafe {
+		if isSafeURL(url) {
+			t.Errorf("isSafeURL(%q) = true, want false", url)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import "unicode"
+
+// SensitiveHit 一次敏感词命中，Start 与 End 为原文中的字符（rune）下标，左闭右开
+type SensitiveHit struct {
+	Word  string
+	Start int
+	End   int
+}
+
+type acNode struct {
+	children map[rune]int
+	fail     int
+	output   []string // 以当前节点结尾的敏感词
+	depth    int
+}
+
+// SensitiveMatcher 基于 Aho-Corasick 自动机的多模式敏感词匹配器
+// 匹配时忽略大小写，并跳过空白与标点，防止通过插入符号绕过检测
+type SensitiveMatcher struct {
+	nodes []acNode
+}
+
+// NewSensitiveMatcher 根据敏感词列表构建匹配器
+func NewSensitiveMatcher(words []string) *SensitiveMatcher {
+	m := &SensitiveMatcher{nodes: []acNode{{children: map[rune]int{}}}}
+	for _, word := range words {
+		runes := normalizeRunes([]rune(word))
+		if len(runes) == 0 {
+			continue
+		}
+		cur := 0
+		for _, r := range runes {
+			next, ok := m.nodes[cur].children[r]
+			if !ok {
+				m.nodes = append(m.nodes, acNode{children: map[rune]int{}, depth: m.nodes[cur].depth + 1})
+				next = len(m.nodes) - 1
+				m.nodes[cur].children[r] = next
+			}
+			cur = next
+		}
+		m.nodes[cur].output = append(m.nodes[cur].output, word)
+	}
+
+	// 按层次遍历构建失败指针
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].children {
+			fail := m.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].children[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].children[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			m.nodes[child].output = append(m.nodes[child].output, m.nodes[m.nodes[child].fail].output...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// Match 返回文本中所有的敏感词命中
+func (m *SensitiveMatcher) Match(text string) []SensitiveHit {
+	if m == nil || len(m.nodes) <= 1 {
+		return nil
+	}
+	runes := []rune(text)
+	// positions 记录参与匹配的字符在原文中的下标
+	positions := make([]int, 0, len(runes))
+	for i, r := range runes {
+		if isSkippable(r) {
+			continue
+		}
+		positions = append(positions, i)
+	}
+
+	var hits []SensitiveHit
+	cur := 0
+	for i, pos := range positions {
+		r := unicode.ToLower(runes[pos])
+		for cur != 0 {
+			if _, ok := m.nodes[cur].children[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if next, ok := m.nodes[cur].children[r]; ok {
+			cur = next
+		}
+		for _, word := range m.nodes[cur].output {
+			length := len(normalizeRunes([]rune(word)))
+			hits = append(hits, SensitiveHit{
+				Word:  word,
+				Start: positions[i-length+1],
+				End:   pos + 1,
+			})
+		}
+	}
+	return hits
+}
+
+// Mask 将命中的敏感词替换为 *，敏感词中间夹杂的空白与标点保持不变
+func (m *SensitiveMatcher) Mask(text string, hits []SensitiveHit) string {
+	if len(hits) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	for _, hit := range hits {
+		for i := hit.Start; i < hit.End; i++ {
+			if !isSkippable(runes[i]) {
+				runes[i] = '*'
+			}
+		}
+	}
+	return string(runes)
+}
+
+func normalizeRunes(runes []rune) []rune {
+	normalized := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		if isSkippable(r) {
+			continue
+		}
+		normalized = append(normalized, unicode.ToLower(r))
+	}
+	return normalized
+}
+
+func isSkippable(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,16 @@
+package utils
+
+// Difference 返回在 a 中而不在 b 中的元素，保持 a 中的顺序
+func Difference(a []int, b []int) []int {
+	inB := make(map[int]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+	}
+	var diff []int
+	for _, v := range a {
+		if !inB[v] {
+			diff = append(diff, v)
+		}
+	}
+	return diff
+}
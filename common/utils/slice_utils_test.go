@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDifference(t *testing.T) {
+	if got := Difference([]int{4, 1, 3, 2}, []int{2, 4, 5}); !slices.Equal(got, []int{1, 3}) {
+		t.Errorf("Difference = %v, want [1 3]", got)
+	}
+	if got := Difference([]int{3, 1}, nil); !slices.Equal(got, []int{3, 1}) {
+		t.Errorf("Difference with empty b = %v, want [3 1]", got)
+	}
+	if got := Difference([]int{1, 2}, []int{2, 1, 3}); len(got) != 0 {
+		t.Errorf("Difference of subset = %v, want empty", got)
+	}
+	if got := Difference(nil, []int{1}); len(got) != 0 {
+		t.Errorf("Difference of empty a = %v, want empty", got)
+	}
+}
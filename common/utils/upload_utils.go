@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// mimeExtensions 允许上传的 MIME 类型对应的文件扩展名
+var mimeExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// extensionMimes 文件扩展名对应的 MIME 类型，用于下载时设置 Content-Type
+var extensionMimes = map[string]string{
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+}
+
+// DetectMimeType 根据文件内容的前 512 个字节判断 MIME 类型，不信任客户端上报的类型和文件名
+func DetectMimeType(data []byte) string {
+	mimeType := http.DetectContentType(data)
+	// DetectContentType 对部分类型会带上参数，例如 "text/plain; charset=utf-8"
+	for i, c := range mimeType {
+		if c == ';' {
+			return mimeType[:i]
+		}
+	}
+	return mimeType
+}
+
+// MimeExtension 获取 MIME 类型对应的扩展名，未知类型返回空字符串
+func MimeExtension(mimeType string) string {
+	return mimeExtensions[mimeType]
+}
+
+// ExtensionMime 获取扩展名对应的 MIME 类型，未知扩展名按二进制流处理
+func ExtensionMime(ext string) string {
+	if mimeType, ok := extensionMimes[ext]; ok {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// IsImageMime 判断 MIME 类型是否为可以生成缩略图的图片
+func IsImageMime(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/png" || mimeType == "image/gif"
+}
+
+// GenerateObjectKey 生成对象存储中的文件名，格式为 "目录/年/月/日/随机串扩展名"
+func GenerateObjectKey(dir string, ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%s%s", dir, time.Now().Format("2006/01/02"), hex.EncodeToString(buf), ext), nil
+}
+
+// Thumbnail 将图片按比例缩小到最长边不超过 size，编码为 JPEG 返回
+// 解码前先读取图片尺寸，像素数超过 maxPixels 时直接拒绝，避免恶意构造的大尺寸图片耗尽内存
+func Thumbnail(data []byte, size int, maxPixels int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("读取图片尺寸失败: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("图片尺寸不合法: %dx%d", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %w", err)
+	}
+
+	width, height := cfg.Width, cfg.Height
+	if width > size || height > size {
+		if width >= height {
+			height = max(height*size/width, 1)
+			width = size
+		} else {
+			width = max(width*size/height, 1)
+			height = size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	resizeArea(dst, src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("编码缩略图失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resizeArea 使用区域平均法缩放图片，目标图片的每个像素取原图对应区域内所有像素的平均值
+func resizeArea(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	for y := 0; y < db.Dy(); y++ {
+		y0 := sb.Min.Y + y*sb.Dy()/db.Dy()
+		y1 := max(sb.Min.Y+(y+1)*sb.Dy()/db.Dy(), y0+1)
+		for x := 0; x < db.Dx(); x++ {
+			x0 := sb.Min.X + x*sb.Dx()/db.Dx()
+			x1 := max(sb.Min.X+(x+1)*sb.Dx()/db.Dx(), x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// JPEG 不支持透明通道，颜色值为预乘透明度的结果，直接与白色背景按透明度混合
+			alpha := a / n
+			white := uint64(0xffff) - alpha
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((b/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+}
+
+// SignObjectKey 使用 HMAC-SHA256 为文件下载地址签名，签名内容为文件名与过期时间戳
+func SignObjectKey(secret string, key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyObjectSign 校验下载地址的签名是否正确且未过期
+func VerifyObjectSign(secret string, key string, expires int64, sign string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+	expected := SignObjectKey(secret, key, expires)
+	return hmac.Equal([]byte(expected), []byte(sign))
+}
@@ -18,13 +18,164 @@ type JwtConfig struct {
 
 // ArticleConfig 定义文章配置结构体
 type ArticleConfig struct {
-	KindMap             map[string]bool
-	UpdateLikesInterval time.Duration
+	KindMap              map[string]bool
+	SearchPageSize       int // 搜索结果默认每页条数
+	SearchMaxPageSize    int // 搜索结果每页最多的条数
+	SearchFragmentLength int // 搜索结果中高亮片段的长度（字符数）
+	MaxTags              int // 单篇文章最多的标签数
+	MaxTagLength         int // 单个标签的最大长度（字符数）
+	ExcerptLength        int // 首页文章摘要的最大长度（字符数）
+	// 点赞数据回写到 MySQL 的间隔在最小值与最大值之间根据待回写的文章数自动调整
+	LikeFlushMinInterval time.Duration
+	LikeFlushMaxInterval time.Duration
+	LikeFlushBatchSize   int           // 每批回写的点赞事件条数与文章数
+	LikeFlushLockTTL     time.Duration // 点赞回写锁的过期时间，每批回写前续期，也是等待锁的最长时间
+	// 完整文章缓存的过期时间为 CacheTTL 加上不超过 CacheTTLJitter 的随机时长，避免大量缓存同时过期
+	CacheTTL         time.Duration
+	CacheTTLJitter   time.Duration
+	NullCacheTTL     time.Duration // 文章不存在的结果的缓存时间
+	BloomBits        uint64        // 文章 ID 布隆过滤器的位数
+	BloomHashes      int           // 文章 ID 布隆过滤器的哈希函数个数
+	RebuildBatchSize int           // 由 MySQL 重建缓存时每批读取的文章数
+	// 文章修改后的缓存操作通过发件箱执行，CacheDeleteDelay 为延时双删中第二次删除的延时
+	CacheDeleteDelay    time.Duration
+	OutboxRelayInterval time.Duration // 发件箱任务的执行间隔
+	OutboxBatchSize     int           // 发件箱任务每批执行的操作数
+	OutboxMaxBackoff    time.Duration // 发件箱操作失败后重试间隔的上限
+	Hot                 HotConfig
+	Recommend           RecommendConfig
+	Syndication         SyndicationConfig
+	ImportMaxRows       int // 一次导入的最大行数
+	// 取消过期置顶的任务的执行间隔，置顶过期后最晚在一个间隔后移出置顶集合；读取列表时已过期的置顶不再排在前面
+	PinExpireInterval  time.Duration
+	PinExpireBatchSize int // 取消过期置顶的任务每批处理的文章数
+	// 每天的浏览次数与独立访客 HyperLogLog 在 Redis 中保留的时长，回写到 MySQL 后只用于补写当天的数据
+	ViewDailyTTL     time.Duration
+	ViewStatsMaxDays int // 浏览统计接口最多查询的天数
+	// 用户的收藏列表在 Redis 中的缓存时长，过期后由 MySQL 重新加载
+	FavoriteCacheTTL    time.Duration
+	FavoritePageSize    int // 收藏列表默认每页条数
+	FavoriteMaxPageSize int // 收藏列表每页最多的条数
+	LikerPageSize       int // 点赞用户列表默认每页条数
+	LikerMaxPageSize    int // 点赞用户列表每页最多的条数
+	AckPageSize         int // 已读报告中居民列表默认每页条数
+	AckMaxPageSize      int // 已读报告中居民列表每页最多的条数，导出全部未确认的居民不受限制
+}
+
+// HotConfig 定义热门文章排行配置结构体
+// 热度 = (LikeWeight*点赞数 + CommentWeight*评论数 + ViewWeight*浏览数 + 1) / (发布小时数 + AgeOffset)^Gravity，
+// Gravity 越大，旧文章的热度下降得越快
+type HotConfig struct {
+	LikeWeight    float64
+	CommentWeight float64
+	ViewWeight    float64
+	AgeOffset     float64 // 发布小时数的偏移，避免刚发布的文章热度过高
+	Gravity       float64
+	RecomputeSpec string // 重新计算全部文章热度的 cron 表达式，热度随时间衰减，需要定时重算
+	MaxSize       int    // 每个排行榜最多保留的文章数
+	PageSize      int    // 热门文章每页的默认条数
+	MaxPageSize   int    // 热门文章每页最多的条数
+}
+
+// RecommendConfig 定义猜你喜欢配置结构体
+// 推荐分 = SimilarWeight*与点赞过的文章的相似度之和 + KindWeight*类型偏好 + TagWeight*标签偏好之和，
+// 文章之间的相似度为共同点赞用户数 / sqrt(两篇文章点赞数之积)，由定时任务计算
+type RecommendConfig struct {
+	SimilarWeight  float64
+	KindWeight     float64
+	TagWeight      float64
+	RecomputeSpec  string        // 重新计算文章相似度的 cron 表达式
+	SimilarSize    int           // 每篇文章保留的相似文章数
+	MaxUserLikes   int           // 计算相似度时每个用户最多计入的点赞数，超过时只计入最新发布的文章，避免点赞很多的用户拖慢计算
+	SeedSize       int           // 作为兴趣来源的最近点赞文章数
+	InterestTags   int           // 从类型和标签列表中取候选文章时使用的偏好标签数
+	ListCandidates int           // 每个类型或标签列表中取出的候选文章数
+	HistorySize    int           // 每个用户保留的最近浏览文章数，已浏览的文章不再推荐
+	HistoryTTL     time.Duration // 浏览记录在用户不再浏览后保留的时长
+	PageSize       int           // 默认推荐的文章数
+	MaxPageSize    int           // 一次最多推荐的文章数
+}
+
+// SyndicationConfig 定义 RSS 与 Atom 订阅配置结构体
+type SyndicationConfig struct {
+	SiteURL     string // 站点对外的地址，用于生成订阅和文章的链接，为空时订阅不可用
+	Title       string
+	Description string
+	Size        int // 订阅中的文章数
+}
+
+// CommentConfig 定义评论配置结构体
+type CommentConfig struct {
+	MaxLength        int // 评论内容的最大长度（字符数）
+	PageSize         int // 评论列表默认每页条数
+	ReplyPreviewSize int // 顶级评论下预览的回复条数
+}
+
+// ModerationConfig 定义敏感词与内容审核配置结构体
+type ModerationConfig struct {
+	DictPath       string        // 敏感词词典文件，每行为 "敏感词 处理方式"
+	DefaultAction  string        // 词典中未写处理方式时使用的默认处理方式
+	ReloadInterval time.Duration // 定时从数据库重新加载敏感词的间隔，用于多实例间同步
+	PageSize       int           // 审核队列默认每页条数
+}
+
+// UploadConfig 定义文件上传配置结构体
+type UploadConfig struct {
+	Storage           string          // 存储方式：local 为本地文件系统，s3 为兼容 S3 协议的对象存储
+	LocalRoot         string          // 本地存储的根目录
+	S3                S3Config        // 对象存储配置，Storage 为 s3 时生效
+	BaseURL           string          // 文件下载地址前缀
+	SignSecret        string          // 下载地址签名密钥
+	SignExpire        time.Duration   // 附件下载地址的有效期
+	MaxAvatarSize     int64           // 头像大小上限（字节）
+	MaxAttachmentSize int64           // 附件大小上限（字节）
+	MaxImagePixels    int             // 允许解码的图片像素上限，防止解压炸弹
+	ThumbnailSize     int             // 缩略图最长边（像素）
+	AvatarTypes       map[string]bool // 头像允许的 MIME 类型
+	AttachmentTypes   map[string]bool // 附件允许的 MIME 类型
+}
+
+// S3Config 定义兼容 S3 协议的对象存储配置结构体
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// JobConfig 定义后台任务配置结构体
+type JobConfig struct {
+	LeaseTTL        time.Duration // 任务租约的有效期，持有租约的实例宕机后最多经过这么久由其他实例接管
+	AcquireInterval time.Duration // 未获得租约的实例重试获取租约的间隔
+	PollInterval    time.Duration // 调度器查询到期任务的间隔
+	PollBatchSize   int           // 调度器每次最多取出的到期任务数
+	PageSize        int           // 运行记录每页的默认条数
+}
+
+// AuditConfig 定义 Redis 与 MySQL 一致性检查配置结构体
+type AuditConfig struct {
+	Spec         string        // 定时检查的 cron 表达式
+	SampleSize   int           // 定时检查时每类对象随机抽样的个数，0 表示全量扫描
+	RepairFrom   string        // 定时检查修复时作为准确数据的一方（mysql 或 redis），为空时只报告
+	BatchSize    int           // 全量扫描时每批检查的用户数
+	RecheckDelay time.Duration // 发现不一致后等待多久重新检查
+}
+
+// FeedConfig 定义关注流配置结构体
+// 粉丝数不超过 PushMaxFans 的管理员发布文章时推送到粉丝的收件箱，超过时改为粉丝读取时从管理员的发件箱拉取
+type FeedConfig struct {
+	PushMaxFans     int
+	FanOutBatchSize int           // 推送时每批写入的收件箱个数
+	InboxSize       int           // 每个用户收件箱保留的文章数
+	OutboxSize      int           // 每个管理员发件箱保留的文章数
+	InboxTTL        time.Duration // 收件箱在用户不再读取后保留的时长，过期后下次读取时由发件箱重建
+	PageSize        int           // 关注流默认每页条数
 }
 
 // CodeConfig 定义验证码配置结构体
 type CodeConfig struct {
-	ExpireDuration time.Duration // 定时删除过期验证码的间隔时间
+	ExpireDuration time.Duration // 验证码的有效期，也是定时清理过期验证码的间隔
 }
 
 // RedisConfig 定义 Redis 配置结构体
@@ -41,12 +192,18 @@ type RabbitMQConfig struct {
 
 // Config 定义配置结构体
 type Config struct {
-	MySQL    MySQLConfig
-	Jwt      JwtConfig
-	Code     CodeConfig
-	Redis    RedisConfig
-	Article  ArticleConfig
-	RabbitMQ RabbitMQConfig
+	MySQL      MySQLConfig
+	Jwt        JwtConfig
+	Code       CodeConfig
+	Redis      RedisConfig
+	Article    ArticleConfig
+	Comment    CommentConfig
+	Moderation ModerationConfig
+	Upload     UploadConfig
+	Job        JobConfig
+	Audit      AuditConfig
+	Feed       FeedConfig
+	RabbitMQ   RabbitMQConfig
 }
 
 // GetConfig 获取配置实例
@@ -78,7 +235,132 @@ func GetConfig() Config {
 				"教育求助": true,
 				"其他":   true,
 			},
-			UpdateLikesInterval: time.Hour,
+			SearchPageSize:       10,
+			SearchMaxPageSize:    50,
+			SearchFragmentLength: 60,
+			MaxTags:              5,
+			MaxTagLength:         20,
+			ExcerptLength:        80,
+			LikeFlushMinInterval: time.Second * 10,
+			LikeFlushMaxInterval: time.Minute * 10,
+			LikeFlushBatchSize:   500,
+			LikeFlushLockTTL:     time.Second * 30,
+			CacheTTL:             time.Hour * 2,
+			CacheTTLJitter:       time.Minute * 20,
+			NullCacheTTL:         time.Minute,
+			BloomBits:            1 << 24,
+			BloomHashes:          7,
+			RebuildBatchSize:     500,
+			CacheDeleteDelay:     time.Second,
+			OutboxRelayInterval:  time.Second * 2,
+			OutboxBatchSize:      100,
+			OutboxMaxBackoff:     time.Minute * 5,
+			ViewDailyTTL:         time.Hour * 72,
+			ViewStatsMaxDays:     90,
+			FavoriteCacheTTL:     time.Hour * 24,
+			FavoritePageSize:     20,
+			FavoriteMaxPageSize:  50,
+			LikerPageSize:        20,
+			LikerMaxPageSize:     50,
+			AckPageSize:          20,
+			AckMaxPageSize:       100,
+			ImportMaxRows:        1000,
+			PinExpireInterval:    time.Minute,
+			PinExpireBatchSize:   100,
+			Hot: HotConfig{
+				LikeWeight:    1,
+				CommentWeight: 2,
+				ViewWeight:    0.1,
+				AgeOffset:     2,
+				Gravity:       1.5,
+				RecomputeSpec: "*/10 * * * *",
+				MaxSize:       1000,
+				PageSize:      20,
+				MaxPageSize:   50,
+			},
+			Recommend: RecommendConfig{
+				SimilarWeight:  1,
+				KindWeight:     0.3,
+				TagWeight:      0.5,
+				RecomputeSpec:  "15 * * * *",
+				SimilarSize:    20,
+				MaxUserLikes:   200,
+				SeedSize:       20,
+				InterestTags:   5,
+				ListCandidates: 20,
+				HistorySize:    200,
+				HistoryTTL:     time.Hour * 24 * 30,
+				PageSize:       10,
+				MaxPageSize:    50,
+			},
+			Syndication: SyndicationConfig{
+				SiteURL:     "",
+				Title:       "环翠楼",
+				Description: "环翠楼社区互助平台的最新文章",
+				Size:        20,
+			},
+		},
+		Comment: CommentConfig{
+			MaxLength:        500,
+			PageSize:         10,
+			ReplyPreviewSize: 3,
+		},
+		Moderation: ModerationConfig{
+			DictPath:       "configs/sensitive_words.txt",
+			DefaultAction:  "mask",
+			ReloadInterval: time.Minute * 5,
+			PageSize:       20,
+		},
+		Upload: UploadConfig{
+			Storage:   "local",
+			LocalRoot: "uploads",
+			S3: S3Config{
+				Endpoint:  "http://192.168.88.128:9000",
+				Region:    "us-east-1",
+				Bucket:    "huancuilou",
+				AccessKey: "minioadmin",
+				SecretKey: "minioadmin",
+			},
+			BaseURL:           "/upload/file",
+			SignSecret:        "huancuilou-upload",
+			SignExpire:        time.Hour,
+			MaxAvatarSize:     2 << 20,
+			MaxAttachmentSize: 10 << 20,
+			MaxImagePixels:    40000000,
+			ThumbnailSize:     200,
+			AvatarTypes: map[string]bool{
+				"image/jpeg": true,
+				"image/png":  true,
+				"image/gif":  true,
+			},
+			AttachmentTypes: map[string]bool{
+				"image/jpeg":      true,
+				"image/png":       true,
+				"image/gif":       true,
+				"image/webp":      true,
+				"application/pdf": true,
+			},
+		},
+		Job: JobConfig{
+			LeaseTTL:        time.Second * 15,
+			AcquireInterval: time.Second * 5,
+			PollInterval:    time.Second,
+			PollBatchSize:   100,
+			PageSize:        20,
+		},
+		Audit: AuditConfig{
+			Spec:         "0 4 * * *",
+			SampleSize:   200,
+			BatchSize:    500,
+			RecheckDelay: time.Second * 2,
+		},
+		Feed: FeedConfig{
+			PushMaxFans:     5000,
+			FanOutBatchSize: 500,
+			InboxSize:       1000,
+			OutboxSize:      200,
+			InboxTTL:        time.Hour * 24 * 7,
+			PageSize:        20,
 		},
 		RabbitMQ: RabbitMQConfig{
 			DSN:     "amqp://" + MQ_USER + ":" + MQ_PASSWORD + "@" + MQ_HOST + ":" + MQ_PORT + "/",
@@ -9,7 +9,10 @@ import (
 	"huancuilou/internal/article/article_service"
 	"huancuilou/response"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type ArticleController struct {
@@ -32,9 +35,19 @@ func (a *ArticleController) AddArticle(c *gin.Context) {
 		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddArticle err: 400:文章类型错误"))
 		return
 	}
+	if !utils.ValidateArticleFormat(article.Format) {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddArticle err: 400:文章格式错误"))
+		return
+	}
+	tags, err := utils.NormalizeTags(article.Tags)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddArticle err: 400:%w", err))
+		return
+	}
+	article.Tags = tags
 	managerID := c.MustGet("userID").(int)
 	if err := a.ArticleService.AddArticle(article, managerID); err != nil {
-		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddArticle err: 500: %w", err))
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddArticle err: %w", err))
 		return
 	}
 	c.JSON(http.StatusOK, response.SuccessWithoutData())
@@ -42,10 +55,11 @@ func (a *ArticleController) AddArticle(c *gin.Context) {
 
 func (a *ArticleController) GetAllArticle(c *gin.Context) {
 	kind := c.Query("kind")
+	userID := c.MustGet("userID").(int)
 
-	articles, err := a.ArticleService.GetAllArticleByKind(kind)
+	articles, err := a.ArticleService.GetAllArticleByKind(kind, userID)
 	if err != nil {
-		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAllArticle err: 500: %w", err))
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAllArticle err: %w", err))
 		return
 	}
 
@@ -60,10 +74,12 @@ func (a *ArticleController) GetArticle(c *gin.Context) {
 		return
 	}
 
-	article, err := a.ArticleService.GetArticle(articleID)
+	userID := c.MustGet("userID").(int)
+
+	article, err := a.ArticleService.GetArticle(articleID, userID)
 
 	if err != nil {
-		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetArticle err: 500: %w", err))
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetArticle err: %w", err))
 		return
 	}
 
@@ -79,11 +95,12 @@ func (a *ArticleController) AddLikes(c *gin.Context) {
 	}
 	userID := c.MustGet("userID").(int)
 
-	if err := a.ArticleService.AddLikes(articleID, userID); err != nil {
-		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddLikes err: 500: %w", err))
+	result, err := a.ArticleService.AddLikes(articleID, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddLikes err: %w", err))
 		return
 	}
-	c.JSON(http.StatusOK, response.SuccessWithoutData())
+	c.JSON(http.StatusOK, response.Success(result))
 }
 
 func (a *ArticleController) RemoveLikes(c *gin.Context) {
@@ -95,11 +112,172 @@ func (a *ArticleController) RemoveLikes(c *gin.Context) {
 	}
 	userID := c.MustGet("userID").(int)
 
-	if err := a.ArticleService.RemoveLikes(articleID, userID); err != nil {
-		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RemoveLikes err: 500: %w", err))
+	result, err := a.ArticleService.RemoveLikes(articleID, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RemoveLikes err: %w", err))
 		return
 	}
-	c.JSON(http.StatusOK, response.SuccessWithoutData())
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// RecommendArticles 猜你喜欢，size 为推荐的文章数
+func (a *ArticleController) RecommendArticles(c *gin.Context) {
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RecommendArticles err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	articles, err := a.ArticleService.RecommendArticles(userID, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RecommendArticles err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(articles))
+}
+
+// GetRSS 获取全部文章或某个类型的文章的 RSS 2.0 订阅
+func (a *ArticleController) GetRSS(c *gin.Context) {
+	a.getSyndicationFeed(c, article_model.SyndicationRSS)
+}
+
+// GetAtom 获取全部文章或某个类型的文章的 Atom 订阅
+func (a *ArticleController) GetAtom(c *gin.Context) {
+	a.getSyndicationFeed(c, article_model.SyndicationAtom)
+}
+
+// getSyndicationFeed 输出订阅，支持 If-None-Match 与 If-Modified-Since 条件请求，内容未变化时返回 304
+func (a *ArticleController) getSyndicationFeed(c *gin.Context, format string) {
+	kind := c.Query("kind")
+	if kind != "" && !utils.ValidateArticleKind(kind) {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.getSyndicationFeed err: 400:文章类型错误"))
+		return
+	}
+
+	feed, err := a.ArticleService.GetSyndicationFeed(format, kind)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.getSyndicationFeed err: %w", err))
+		return
+	}
+	c.Header("ETag", feed.ETag)
+	if !feed.LastModified.IsZero() {
+		c.Header("Last-Modified", feed.LastModified.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", "no-cache")
+	if notModified(c.GetHeader("If-None-Match"), c.GetHeader("If-Modified-Since"), feed.ETag, feed.LastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, feed.ContentType, feed.Body)
+}
+
+// notModified 判断条件请求的内容是否未变化，与 RFC 7232 一致，带有 If-None-Match 时只比较 ETag，忽略 If-Modified-Since
+func notModified(ifNoneMatch string, ifModifiedSince string, etag string, lastModified time.Time) bool {
+	if ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(since)
+}
+
+// etagMatches 判断 If-None-Match 中是否包含 etag，按弱比较处理 W/ 前缀
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLikers 分页获取点赞了文章的用户
+func (a *ArticleController) GetLikers(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetLikers err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetLikers err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetLikers err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	result, err := a.ArticleService.GetLikers(articleID, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetLikers err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// AddFavorite 收藏文章
+func (a *ArticleController) AddFavorite(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddFavorite err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	result, err := a.ArticleService.AddFavorite(articleID, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AddFavorite err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// RemoveFavorite 取消收藏文章
+func (a *ArticleController) RemoveFavorite(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RemoveFavorite err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	result, err := a.ArticleService.RemoveFavorite(articleID, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RemoveFavorite err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// GetFavorites 分页获取当前用户收藏的文章
+func (a *ArticleController) GetFavorites(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetFavorites err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetFavorites err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	result, err := a.ArticleService.GetFavorites(userID, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetFavorites err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
 }
 
 func (a *ArticleController) UpdateArticle(c *gin.Context) {
@@ -108,10 +286,410 @@ func (a *ArticleController) UpdateArticle(c *gin.Context) {
 		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.UpdateArticle err: 400:将json数据绑定到结构体失败:%w", err))
 		return
 	}
+	if !utils.ValidateArticleFormat(article.Format) {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.UpdateArticle err: 400:文章格式错误"))
+		return
+	}
+	if article.Tags != nil {
+		tags, err := utils.NormalizeTags(article.Tags)
+		if err != nil {
+			error_handler.HandleUserError(c, fmt.Errorf("ArticleController.UpdateArticle err: 400:%w", err))
+			return
+		}
+		article.Tags = tags
+	}
 
 	if err := a.ArticleService.UpdateArticle(article); err != nil {
-		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.UpdateArticle err: 500: %w", err))
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.UpdateArticle err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (a *ArticleController) GetAllArticleByTag(c *gin.Context) {
+	tag := strings.TrimSpace(c.Query("tag"))
+	if tag == "" {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAllArticleByTag err: 400: 标签不能为空"))
+		return
+	}
+
+	userID := c.MustGet("userID").(int)
+
+	articles, err := a.ArticleService.GetAllArticleByTag(tag, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAllArticleByTag err: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(articles))
+}
+
+func (a *ArticleController) AutocompleteTags(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("prefix"))
+	n, err := strconv.Atoi(c.DefaultQuery("n", "10"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AutocompleteTags err: 400: 将n转换为int失败:%w", err))
+		return
+	}
+
+	tags, err := a.ArticleService.AutocompleteTags(prefix, n)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AutocompleteTags err: 500: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(tags))
+}
+
+func (a *ArticleController) GetPopularTags(c *gin.Context) {
+	n, err := strconv.Atoi(c.DefaultQuery("n", "10"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetPopularTags err: 400: 将n转换为int失败:%w", err))
+		return
+	}
+
+	tags, err := a.ArticleService.GetPopularTags(n)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetPopularTags err: 500: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(tags))
+}
+
+func (a *ArticleController) SearchArticle(c *gin.Context) {
+	keyword := strings.TrimSpace(c.Query("keyword"))
+	if keyword == "" {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SearchArticle err: 400: 搜索关键词不能为空"))
+		return
+	}
+	kinds := c.QueryArray("kind")
+	for _, kind := range kinds {
+		if !utils.ValidateArticleKind(kind) {
+			error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SearchArticle err: 400:文章类型错误"))
+			return
+		}
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SearchArticle err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SearchArticle err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	result, err := a.ArticleService.SearchArticles(keyword, kinds, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SearchArticle err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// GetHotArticles 按热度分页获取热门文章，kind 为空时获取全站排行
+func (a *ArticleController) GetHotArticles(c *gin.Context) {
+	kind := c.Query("kind")
+	if kind != "" && !utils.ValidateArticleKind(kind) {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetHotArticles err: 400:文章类型错误"))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetHotArticles err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetHotArticles err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	userID := c.MustGet("userID").(int)
+
+	result, err := a.ArticleService.GetHotArticles(kind, page, size, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetHotArticles err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// GetViewStats 获取文章的浏览统计，days 为按天统计的天数
+func (a *ArticleController) GetViewStats(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetViewStats err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetViewStats err: 400: 将days转换为int失败:%w", err))
+		return
+	}
+
+	stats, err := a.ArticleService.GetViewStats(articleID, days)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetViewStats err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(stats))
+}
+
+// GetTopViewedArticles 获取最近一段时间浏览次数最多的文章
+func (a *ArticleController) GetTopViewedArticles(c *gin.Context) {
+	kind := c.Query("kind")
+	if kind != "" && !utils.ValidateArticleKind(kind) {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetTopViewedArticles err: 400:文章类型错误"))
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetTopViewedArticles err: 400: 将days转换为int失败:%w", err))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetTopViewedArticles err: 400: 将limit转换为int失败:%w", err))
+		return
+	}
+
+	ranks, err := a.ArticleService.GetTopViewedArticles(days, kind, limit)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetTopViewedArticles err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(ranks))
+}
+
+// RebuildLikes 以 MySQL 中的点赞关系为准重建缓存中的点赞数据
+func (a *ArticleController) RebuildLikes(c *gin.Context) {
+	if err := a.ArticleService.RebuildLikes(); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RebuildLikes err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+// RebuildCaches 由 MySQL 重建文章相关的全部缓存
+func (a *ArticleController) RebuildCaches(c *gin.Context) {
+	if err := a.ArticleService.RebuildCaches(); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.RebuildCaches err: %w", err))
 		return
 	}
 	c.JSON(http.StatusOK, response.SuccessWithoutData())
 }
+
+// FlushLikes 立即将点赞数据回写到 MySQL，返回回写的文章数
+func (a *ArticleController) FlushLikes(c *gin.Context) {
+	count, err := a.ArticleService.FlushLikes(0)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.FlushLikes err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(count))
+}
+
+// ExportArticles 以 JSON 或 CSV 文件导出全部文章或某个类型的文章
+func (a *ArticleController) ExportArticles(c *gin.Context) {
+	format := c.DefaultQuery("format", article_model.TransferJSON)
+	if format != article_model.TransferJSON && format != article_model.TransferCSV {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ExportArticles err: 400:不支持的格式%s", format))
+		return
+	}
+	kind := c.Query("kind")
+	if kind != "" && !utils.ValidateArticleKind(kind) {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ExportArticles err: 400:文章类型错误"))
+		return
+	}
+
+	records, err := a.ArticleService.ExportArticles(kind)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ExportArticles err: %w", err))
+		return
+	}
+	contentType := "application/json; charset=utf-8"
+	if format == article_model.TransferCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="articles-%s.%s"`, time.Now().Format("20060102150405"), format))
+	c.Status(http.StatusOK)
+	if err := article_service.WriteArticleRecords(c.Writer, format, records); err != nil {
+		// 响应头已经发出，只能中断连接
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// ImportArticles 从上传的 JSON 或 CSV 文件批量导入文章，dryRun 为 true 时只校验不写入
+// 没有指定管理员的行记为当前管理员发布，返回每一行的导入结果
+func (a *ArticleController) ImportArticles(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ImportArticles err: 400: 将dryRun转换为bool失败:%w", err))
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ImportArticles err: 400:读取上传文件失败:%w", err))
+		return
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(fileHeader.Filename), "."))
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ImportArticles err: 500: %w", err))
+		return
+	}
+	defer file.Close()
+
+	records, rowErrors, err := a.ArticleService.ReadArticleRecords(file, format)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ImportArticles err: %w", err))
+		return
+	}
+	report, err := a.ArticleService.ImportArticles(records, rowErrors, &article_model.ImportOptions{
+		DryRun:    dryRun,
+		ManagerID: c.MustGet("userID").(int),
+	})
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ImportArticles err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(report))
+}
+
+// PinArticle 置顶文章，可以设置置顶的过期时间以及过期后是否归档
+func (a *ArticleController) PinArticle(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.PinArticle err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	var pin *article_model.PinRequest
+	if err := c.BindJSON(&pin); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.PinArticle err: 400:将json数据绑定到结构体失败:%w", err))
+		return
+	}
+
+	if err := a.ArticleService.PinArticle(articleID, pin); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.PinArticle err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+// UnpinArticle 取消置顶
+func (a *ArticleController) UnpinArticle(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.UnpinArticle err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+
+	if err := a.ArticleService.UnpinArticle(articleID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.UnpinArticle err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+// SetRequireAck 设置文章是否需要居民确认已读
+func (a *ArticleController) SetRequireAck(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SetRequireAck err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	var request *article_model.RequireAckRequest
+	if err := c.BindJSON(&request); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SetRequireAck err: 400:将json数据绑定到结构体失败:%w", err))
+		return
+	}
+
+	if err := a.ArticleService.SetRequireAck(articleID, request.RequireAck); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.SetRequireAck err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+// AcknowledgeArticle 当前用户确认已读文章
+func (a *ArticleController) AcknowledgeArticle(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AcknowledgeArticle err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	result, err := a.ArticleService.AcknowledgeArticle(articleID, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.AcknowledgeArticle err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+// GetAckReport 获取文章的已读报告，status 为 acknowledged 或 outstanding，默认列出未确认的居民
+func (a *ArticleController) GetAckReport(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAckReport err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAckReport err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAckReport err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	report, err := a.ArticleService.GetAckReport(articleID, c.Query("status"), page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.GetAckReport err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(report))
+}
+
+// ExportOutstandingResidents 以 CSV 或 JSON 文件导出尚未确认文章的居民
+func (a *ArticleController) ExportOutstandingResidents(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ExportOutstandingResidents err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	format := c.DefaultQuery("format", article_model.TransferCSV)
+	if format != article_model.TransferJSON && format != article_model.TransferCSV {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ExportOutstandingResidents err: 400:不支持的格式%s", format))
+		return
+	}
+
+	residents, err := a.ArticleService.GetOutstandingResidents(articleID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ArticleController.ExportOutstandingResidents err: %w", err))
+		return
+	}
+	contentType := "application/json; charset=utf-8"
+	if format == article_model.TransferCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="article-%d-outstanding.%s"`, articleID, format))
+	c.Status(http.StatusOK)
+	if err := article_service.WriteAckResidents(c.Writer, format, residents); err != nil {
+		// 响应头已经发出，只能中断连接
+		c.Error(err)
+		c.Abort()
+	}
+}
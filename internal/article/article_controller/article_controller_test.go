@@ -0,0 +1,63 @@
+package article_controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := `"abc123"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc123"`, true},
+		{`W/"abc123"`, true},
+		{`"xyz", "abc123"`, true},
+		{` "xyz" , W/"abc123" `, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{`abc123`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	etag := `"abc123"`
+	lastModified := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	same := lastModified.Format(http.TimeFormat)
+	earlier := lastModified.Add(-time.Second).Format(http.TimeFormat)
+	later := lastModified.Add(time.Hour).Format(http.TimeFormat)
+
+	if !notModified("", same, etag, lastModified) {
+		t.Error("If-Modified-Since 等于 Last-Modified 时应返回 304")
+	}
+	if !notModified("", later, etag, lastModified) {
+		t.Error("If-Modified-Since 晚于 Last-Modified 时应返回 304")
+	}
+	if notModified("", earlier, etag, lastModified) {
+		t.Error("If-Modified-Since 早于 Last-Modified 时应返回内容")
+	}
+	if notModified("", "not a date", etag, lastModified) {
+		t.Error("无法解析的 If-Modified-Since 应被忽略")
+	}
+	if notModified("", same, etag, time.Time{}) {
+		t.Error("没有 Last-Modified 时应返回内容")
+	}
+	if notModified("", "", etag, lastModified) {
+		t.Error("没有条件请求头时应返回内容")
+	}
+	// 带有 If-None-Match 时以 ETag 为准
+	if notModified(`"xyz"`, later, etag, lastModified) {
+		t.Error("ETag 不匹配时即使 If-Modified-Since 较晚也应返回内容")
+	}
+	if !notModified(etag, earlier, etag, lastModified) {
+		t.Error("ETag 匹配时即使 If-Modified-Since 较早也应返回 304")
+	}
+}
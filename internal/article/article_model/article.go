@@ -6,10 +6,31 @@ type Article struct {
 	ID        int       `json:"id"`
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
+	Format    string    `json:"format"`
 	ManagerID int       `json:"managerID"`
 	CreateAt  time.Time `json:"createAt"`
-	Kind      string    `json:"kind"`
-	Like      int       `json:"like"`
+	// UpdateAt 最后一次修改正文或归档状态的时间，没有修改过时为空，只用于订阅的 Last-Modified，不写入缓存
+	UpdateAt *time.Time `json:"-"`
+	Kind     string     `json:"kind"`
+	Like     int        `json:"like"`
+	// View 浏览次数，UniqueView 为 HyperLogLog 估算的独立访客数
+	View       int      `json:"view"`
+	UniqueView int      `json:"uniqueView"`
+	Tags       []string `json:"tags" gorm:"-"`
+	Comment    int      `json:"comment" gorm:"-"`
+	Favorite   int      `json:"favorite" gorm:"-"`
+	LikedByMe  bool     `json:"likedByMe" gorm:"-"` // 当前用户是否已点赞
+	// PinPriority 置顶优先级，0 表示不置顶，数值越大越靠前；PinExpireAt 为置顶的过期时间，为空时一直置顶
+	PinPriority int        `json:"pinPriority"`
+	PinExpireAt *time.Time `json:"pinExpireAt"`
+	// ArchiveOnExpire 置顶过期后是否归档，Archived 为是否已归档，归档的文章不再出现在类型与标签列表中
+	ArchiveOnExpire bool `json:"archiveOnExpire"`
+	Archived        bool `json:"archived"`
+	// RequireAck 是否需要居民确认已读，AckedByMe 为当前用户是否已确认
+	RequireAck bool `json:"requireAck"`
+	AckedByMe  bool `json:"ackedByMe" gorm:"-"`
+	// ContentHTML 由正文渲染得到的 HTML，只用于展示
+	ContentHTML string `json:"contentHTML" gorm:"-"`
 }
 
 func (Article) TableName() string {
@@ -0,0 +1,53 @@
+package article_model
+
+import "time"
+
+// ArticleAck 用户确认已读需要确认的文章，(article_id, user_id) 上有唯一索引，由 ArticleRepository.EnsureAckIndex 在启动时创建
+type ArticleAck struct {
+	ID        int
+	ArticleID int
+	UserID    int
+	CreateAt  time.Time
+}
+
+func (ArticleAck) TableName() string {
+	return "article_ack"
+}
+
+// 已读报告中的居民状态
+const (
+	AckStatusAcknowledged = "acknowledged"
+	AckStatusOutstanding  = "outstanding"
+)
+
+// AckResult 确认已读的结果，AckAt 为第一次确认的时间
+type AckResult struct {
+	ArticleID int       `json:"articleID"`
+	AckAt     time.Time `json:"ackAt"`
+}
+
+// AckResident 已读报告中的一位居民，未确认时 AckAt 为空
+type AckResident struct {
+	UserID      int        `json:"userID"`
+	UserName    string     `json:"userName"`
+	PhoneNumber string     `json:"phoneNumber"`
+	AckAt       *time.Time `json:"ackAt"`
+}
+
+// AckReport 需要确认的文章的已读报告，Residents 为 Status 对应的一页居民
+type AckReport struct {
+	ArticleID    int            `json:"articleID"`
+	Title        string         `json:"title"`
+	Total        int64          `json:"total"` // 居民总数，不含管理员
+	Acknowledged int64          `json:"acknowledged"`
+	Outstanding  int64          `json:"outstanding"`
+	Status       string         `json:"status"`
+	Page         int            `json:"page"`
+	Size         int            `json:"size"`
+	Residents    []*AckResident `json:"residents"`
+}
+
+// RequireAckRequest 设置文章是否需要确认已读的参数
+type RequireAckRequest struct {
+	RequireAck bool `json:"requireAck"`
+}
@@ -0,0 +1,36 @@
+package article_model
+
+import "time"
+
+// ArticleFavorite 用户收藏文章的关系，article_id 与 user_id 上有唯一索引
+type ArticleFavorite struct {
+	ID        int
+	ArticleID int
+	UserID    int
+	CreateAt  time.Time
+}
+
+func (ArticleFavorite) TableName() string {
+	return "article_favorite"
+}
+
+// FavoriteResult 收藏或取消收藏后的结果
+type FavoriteResult struct {
+	Favorite  int  `json:"favorite"`  // 最新收藏数
+	Favorited bool `json:"favorited"` // 当前用户是否已收藏
+	Changed   bool `json:"changed"`   // 本次操作是否改变了收藏状态，重复收藏、重复取消收藏时为 false
+}
+
+// FavoriteArticle 收藏列表中的文章，FavoriteAt 为收藏时间
+type FavoriteArticle struct {
+	*BasicArticle
+	FavoriteAt time.Time `json:"favoriteAt"`
+}
+
+// FavoriteArticlePage 分页的收藏文章，Total 中包含已被删除的文章
+type FavoriteArticlePage struct {
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	Size     int                `json:"size"`
+	Articles []*FavoriteArticle `json:"articles"`
+}
@@ -0,0 +1,38 @@
+package article_model
+
+import "time"
+
+// ArticleLike 用户点赞文章的关系，article_id 与 user_id 上有唯一索引
+type ArticleLike struct {
+	ID        int
+	ArticleID int
+	UserID    int
+	CreateAt  time.Time
+}
+
+func (ArticleLike) TableName() string {
+	return "article_like"
+}
+
+// ArticleLiker 点赞了文章的用户
+type ArticleLiker struct {
+	UserID    int       `json:"userID"`
+	UserName  string    `json:"userName"`
+	AvatarUrl string    `json:"avatarUrl"`
+	LikeAt    time.Time `json:"likeAt"`
+}
+
+// ArticleLikerPage 分页的点赞用户
+type ArticleLikerPage struct {
+	Total  int64           `json:"total"`
+	Page   int             `json:"page"`
+	Size   int             `json:"size"`
+	Likers []*ArticleLiker `json:"likers"`
+}
+
+// LikeEvent 缓存中记录的一次点赞或取消点赞，Delta 为 1 表示点赞，-1 表示取消点赞
+type LikeEvent struct {
+	ArticleID int
+	UserID    int
+	Delta     int
+}
@@ -0,0 +1,27 @@
+package article_model
+
+import "time"
+
+// 缓存发件箱中的操作
+const (
+	OutboxSyncArticle  = "sync_article"  // 由 MySQL 同步基本文章哈希表、类型与标签列表，并删除完整文章缓存
+	OutboxDeleteFull   = "delete_full"   // 延时双删中的第二次删除，删除完整文章缓存
+	OutboxSyncFavorite = "sync_favorite" // 由 MySQL 重新统计基本文章哈希表中的收藏数
+	OutboxPublish      = "publish"       // 新文章发布后调用注册的发布处理方，例如推送到粉丝的关注流
+)
+
+// ArticleOutbox 文章缓存发件箱中的一条待执行操作
+// 与文章的修改在同一个事务中写入，事务提交后才会被执行，执行成功后删除，失败时推迟 AvailableAt 重试
+type ArticleOutbox struct {
+	ID          int
+	ArticleID   int
+	Action      string
+	Attempts    int
+	LastError   string
+	AvailableAt time.Time
+	CreateAt    time.Time
+}
+
+func (ArticleOutbox) TableName() string {
+	return "article_outbox"
+}
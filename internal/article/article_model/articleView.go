@@ -0,0 +1,34 @@
+package article_model
+
+import "time"
+
+// ArticleViewDaily 文章每天的浏览次数与独立访客数，独立访客数由 HyperLogLog 估算
+type ArticleViewDaily struct {
+	ID          int       `json:"-"`
+	ArticleID   int       `json:"articleID"`
+	Date        time.Time `json:"date" gorm:"type:date"`
+	Views       int       `json:"views"`
+	UniqueViews int       `json:"uniqueViews"`
+}
+
+func (ArticleViewDaily) TableName() string {
+	return "article_view_daily"
+}
+
+// ArticleViewStats 一篇文章的浏览统计，Daily 为统计区间内每天的数据
+type ArticleViewStats struct {
+	ArticleID  int                 `json:"articleID"`
+	Title      string              `json:"title"`
+	View       int                 `json:"view"`
+	UniqueView int                 `json:"uniqueView"`
+	Daily      []*ArticleViewDaily `json:"daily"`
+}
+
+// ArticleViewRank 统计区间内浏览次数最多的文章，UniqueViews 为每天独立访客数之和
+type ArticleViewRank struct {
+	ArticleID   int    `json:"articleID"`
+	Title       string `json:"title"`
+	Kind        string `json:"kind"`
+	Views       int    `json:"views"`
+	UniqueViews int    `json:"uniqueViews"`
+}
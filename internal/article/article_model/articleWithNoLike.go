@@ -6,7 +6,16 @@ type ArticleWithNoLike struct {
 	ID        int       `json:"id"`
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
+	Format    string    `json:"format"`
 	ManagerID int       `json:"managerID"`
 	CreateAt  time.Time `json:"createAt"`
 	Kind      string    `json:"kind"`
+	View      int       `json:"view"`
+	Tags      []string  `json:"tags"`
+	// 置顶与归档状态，见 Article
+	PinPriority     int        `json:"pinPriority"`
+	PinExpireAt     *time.Time `json:"pinExpireAt"`
+	ArchiveOnExpire bool       `json:"archiveOnExpire"`
+	Archived        bool       `json:"archived"`
+	RequireAck      bool       `json:"requireAck"`
 }
@@ -1,10 +1,24 @@
 package article_model
 
+import "time"
+
 type BasicArticle struct {
-	ID        int    `json:"id"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Kind      string `json:"kind"`
-	Like      int    `json:"like"`
-	ManagerID int    `json:"manager_id"`
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Kind      string    `json:"kind"`
+	Like      int       `json:"like"`
+	Comment   int       `json:"comment"`
+	View      int       `json:"view"`
+	Favorite  int       `json:"favorite"`
+	ManagerID int       `json:"manager_id"`
+	Tags      []string  `json:"tags"`
+	Cover     string    `json:"cover"`
+	CreateAt  time.Time `json:"createAt"`
+	LikedByMe bool      `json:"likedByMe"` // 当前用户是否已点赞，不写入缓存
+	// PinPriority 置顶优先级，0 表示不置顶；PinExpireAt 为置顶的过期时间，为空时一直置顶
+	PinPriority int        `json:"pinPriority"`
+	PinExpireAt *time.Time `json:"pinExpireAt"`
+	Archived    bool       `json:"archived"`   // 已归档的文章不在类型与标签列表中
+	RequireAck  bool       `json:"requireAck"` // 是否需要居民确认已读
 }
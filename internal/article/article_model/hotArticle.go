@@ -0,0 +1,22 @@
+package article_model
+
+// HotArticle 热门排行中的文章，Hot 为按时间衰减后的热度
+type HotArticle struct {
+	*BasicArticle
+	Hot float64 `json:"hot"`
+}
+
+// HotArticlePage 分页的热门文章
+type HotArticlePage struct {
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	Size     int           `json:"size"`
+	Articles []*HotArticle `json:"articles"`
+}
+
+// HotCounts 基本文章哈希表中计算热度所需的计数
+type HotCounts struct {
+	Like    int
+	Comment int
+	View    int
+}
@@ -0,0 +1,8 @@
+package article_model
+
+// LikeResult 点赞或取消点赞后的结果
+type LikeResult struct {
+	Like    int  `json:"like"`    // 最新点赞数
+	Liked   bool `json:"liked"`   // 当前用户是否已点赞
+	Changed bool `json:"changed"` // 本次操作是否改变了点赞状态，重复点赞、重复取消点赞时为 false
+}
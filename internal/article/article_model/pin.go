@@ -0,0 +1,16 @@
+package article_model
+
+import "time"
+
+// PinRequest 置顶文章的参数，ExpireAt 为空时一直置顶，直到取消置顶
+type PinRequest struct {
+	Priority        int        `json:"priority"`
+	ExpireAt        *time.Time `json:"expireAt"`
+	ArchiveOnExpire bool       `json:"archiveOnExpire"` // 置顶过期后归档，文章不再出现在列表中
+}
+
+// ExpiredPin 置顶已过期的文章
+type ExpiredPin struct {
+	ID              int
+	ArchiveOnExpire bool
+}
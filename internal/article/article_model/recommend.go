@@ -0,0 +1,21 @@
+package article_model
+
+// 推荐文章的理由
+const (
+	RecommendReasonCoLike   = "co_like"  // 与用户点赞过的文章被同一批用户点赞
+	RecommendReasonInterest = "interest" // 与用户点赞过的文章类型或标签相同
+	RecommendReasonHot      = "hot"      // 没有足够的点赞记录时推荐的热门文章
+)
+
+// SimilarArticle 相似文章，Score 为两篇文章点赞用户的余弦相似度
+type SimilarArticle struct {
+	ArticleID int
+	Score     float64
+}
+
+// RecommendedArticle 推荐给用户的文章
+type RecommendedArticle struct {
+	*BasicArticle
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
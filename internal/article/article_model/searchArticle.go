@@ -0,0 +1,24 @@
+package article_model
+
+import "time"
+
+// SearchArticle 搜索结果中的文章，Title 与 Content 为带高亮标记的片段
+type SearchArticle struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Format    string    `json:"format"`
+	Kind      string    `json:"kind"`
+	Like      int       `json:"like"`
+	ManagerID int       `json:"managerID"`
+	CreateAt  time.Time `json:"createAt"`
+	Score     float64   `json:"score"`
+}
+
+// SearchArticlePage 分页的搜索结果
+type SearchArticlePage struct {
+	Total    int64            `json:"total"`
+	Page     int              `json:"page"`
+	Size     int              `json:"size"`
+	Articles []*SearchArticle `json:"articles"`
+}
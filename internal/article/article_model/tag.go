@@ -0,0 +1,31 @@
+package article_model
+
+import "time"
+
+// Tag 文章标签
+type Tag struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name"`
+	CreateAt time.Time `json:"createAt"`
+}
+
+func (Tag) TableName() string {
+	return "tag"
+}
+
+// ArticleTag 文章与标签的多对多关联表
+type ArticleTag struct {
+	ID        int
+	ArticleID int
+	TagID     int
+}
+
+func (ArticleTag) TableName() string {
+	return "article_tag"
+}
+
+// PopularTag 热门标签及其关联的文章数
+type PopularTag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,47 @@
+package article_model
+
+import "time"
+
+// 导入导出的文件格式
+const (
+	TransferJSON = "json"
+	TransferCSV  = "csv"
+)
+
+// TransferColumns CSV 文件的表头，导入时按表头的名称匹配列，顺序不限
+var TransferColumns = []string{"id", "title", "kind", "format", "tags", "manager_id", "like", "create_at", "content"}
+
+// ArticleRecord 导入导出的一篇文章，导入时忽略 ID 与点赞数，点赞数只能由用户点赞产生
+type ArticleRecord struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Kind      string    `json:"kind"`
+	Format    string    `json:"format"`
+	Tags      []string  `json:"tags"`
+	ManagerID int       `json:"managerID"`
+	Like      int       `json:"like"`
+	CreateAt  time.Time `json:"createAt"`
+	Content   string    `json:"content"`
+}
+
+// ImportOptions 导入的参数
+type ImportOptions struct {
+	DryRun    bool // 只校验不写入
+	ManagerID int  // 没有指定管理员的行使用的管理员 ID
+}
+
+// ImportRowError 导入时某一行的错误，Row 从 1 开始，不含 CSV 表头
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Title string `json:"title"`
+	Error string `json:"error"`
+}
+
+// ImportReport 导入的结果，DryRun 时 Imported 为通过校验的行数
+type ImportReport struct {
+	DryRun   bool              `json:"dryRun"`
+	Total    int               `json:"total"`
+	Imported int               `json:"imported"`
+	IDs      []int             `json:"ids"`
+	Errors   []*ImportRowError `json:"errors"`
+}
@@ -2,12 +2,15 @@ package article_repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
 	"huancuilou/common/utils"
 	"huancuilou/internal/article/article_model"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,51 +20,66 @@ type ArticleCacheRepository struct {
 
 var prefix = "hcl:article"
 
-func NewArticleCacheRepository(client *redis.Client) *ArticleCacheRepository {
-	return &ArticleCacheRepository{client: client}
-}
+// likeEventsKey 点赞事件列表，按发生顺序保存尚未回写到 MySQL 的点赞与取消点赞，likeEventsProcessingKey 为正在回写的一批事件
+// likeFlushLockKey 为点赞回写锁，定时任务、手动回写与重建点赞数据互斥，保证各批事件按顺序写入，
+// 点赞数与浏览统计的处理中集合也不会被另一次回写删除
+var (
+	likeEventsKey           = prefix + ":like:events"
+	likeEventsProcessingKey = prefix + ":like:events:processing"
+	likeFlushLockKey        = prefix + ":like:flush:lock"
+)
 
-func (a *ArticleCacheRepository) AddBasicArticle(article *article_model.BasicArticle) error {
-	ctx := context.Background()
-	mapKey := fmt.Sprintf("%s:basic:map:%d", prefix, article.ID)
-	basicArticleMap := map[string]interface{}{
-		"id":         article.ID,
-		"title":      article.Title,
-		"content":    article.Content,
-		"kind":       article.Kind,
-		"like":       article.Like,
-		"manager_id": article.ManagerID,
-	}
-	if err := a.client.HSet(ctx, mapKey, basicArticleMap).Err(); err != nil {
-		return fmt.Errorf("ArticleCacheRepository.AddBasicArticle err: %w", err)
-	}
+// bloomKey 文章 ID 的布隆过滤器位图，bloomRebuildKey 为重建时临时写入的位图
+var (
+	bloomKey        = prefix + ":bloom"
+	bloomRebuildKey = prefix + ":bloom:rebuilding"
+)
 
-	listKey := fmt.Sprintf("%s:basic:list:%s", prefix, article.Kind)
-	if err := a.client.LPush(ctx, listKey, article.ID).Err(); err != nil {
-		return fmt.Errorf("ArticleCacheRepository.AddBasicArticle err: %w", err)
-	}
+// likeDirtyKey 点赞数有变化、尚未回写到 MySQL 的文章 ID 集合，likeDirtyProcessingKey 为正在回写的文章 ID 集合
+var (
+	likeDirtyKey           = prefix + ":like:dirty"
+	likeDirtyProcessingKey = prefix + ":like:dirty:processing"
+)
 
-	return nil
+// viewDirtyKey 浏览数有变化、尚未回写到 MySQL 的 "文章ID:日期" 集合，viewDirtyProcessingKey 为正在回写的集合
+var (
+	viewDirtyKey           = prefix + ":view:dirty"
+	viewDirtyProcessingKey = prefix + ":view:dirty:processing"
+)
+
+// hotKey 全站热门文章有序集合，各类型的热门文章保存在 hotKey:<类型> 中，分数为热度
+var hotKey = prefix + ":hot"
+
+// ErrArticleNotCached 基本文章不在缓存中，基本文章没有过期时间，缺失时说明文章不存在
+var ErrArticleNotCached = errors.New("文章不存在")
+
+func NewArticleCacheRepository(client *redis.Client) *ArticleCacheRepository {
+	return &ArticleCacheRepository{client: client}
 }
 
-func (a *ArticleCacheRepository) AddArticle(article *article_model.Article) error {
+// AddArticle 缓存完整文章，expiration 由调用方加上随机抖动，避免同时写入的文章同时过期
+func (a *ArticleCacheRepository) AddArticle(article *article_model.Article, expiration time.Duration) error {
 	ctx := context.Background()
 	key := fmt.Sprintf("%s:full:%d", prefix, article.ID)
 	articleMap := map[string]interface{}{
-		"id":         article.ID,
-		"title":      article.Title,
-		"content":    article.Content,
-		"kind":       article.Kind,
-		"like":       article.Like,
-		"manager_id": article.ManagerID,
-		"create_at":  article.CreateAt,
+		"id":            article.ID,
+		"title":         article.Title,
+		"content":       article.Content,
+		"format":        article.Format,
+		"kind":          article.Kind,
+		"like":          article.Like,
+		"manager_id":    article.ManagerID,
+		"create_at":     article.CreateAt,
+		"tags":          strings.Join(article.Tags, ","),
+		"pin_priority":  article.PinPriority,
+		"pin_expire_at": pinExpireUnix(article.PinExpireAt),
+		"archived":      article.Archived,
+		"require_ack":   article.RequireAck,
 	}
 	if err := a.client.HSet(ctx, key, articleMap).Err(); err != nil {
 		return fmt.Errorf("ArticleCacheRepository.AddArticle err: %w", err)
 	}
 
-	// 设置哈希表的过期时间，这里设置为 2 小时
-	expiration := 2 * time.Hour
 	if err := a.client.Expire(ctx, key, expiration).Err(); err != nil {
 		return fmt.Errorf("设置哈希表过期时间时出错: %w", err)
 	}
@@ -70,51 +88,135 @@ func (a *ArticleCacheRepository) AddArticle(article *article_model.Article) erro
 }
 
 func (a *ArticleCacheRepository) GetAllArticleByKind(kind string) ([]*article_model.BasicArticle, error) {
-	var articles []*article_model.BasicArticle
-	ctx := context.Background()
 	listKey := fmt.Sprintf("%s:basic:list:%s", prefix, kind)
+	return a.getBasicArticlesByList(listKey)
+}
+
+// GetAllArticleByTag 获取某个标签下的所有基本文章
+func (a *ArticleCacheRepository) GetAllArticleByTag(tag string) ([]*article_model.BasicArticle, error) {
+	listKey := fmt.Sprintf("%s:basic:tag:%s", prefix, tag)
+	return a.getBasicArticlesByList(listKey)
+}
+
+// getBasicArticlesByList 按列表中保存的文章 ID 依次读取基本文章哈希表
+func (a *ArticleCacheRepository) getBasicArticlesByList(listKey string) ([]*article_model.BasicArticle, error) {
+	ctx := context.Background()
 	articleIDs, err := a.client.LRange(ctx, listKey, 0, -1).Result()
 	if err != nil {
 		return nil, fmt.Errorf("获取列表时出错: %w", err)
 	}
-
+	ids := make([]int, 0, len(articleIDs))
 	for _, idStr := range articleIDs {
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
 			return nil, fmt.Errorf("转换ID时出错: %w", err)
 		}
-		mapKey := fmt.Sprintf("%s:basic:map:%d", prefix, id)
-		articleMap, err := a.client.HGetAll(ctx, mapKey).Result()
-		if err != nil {
-			return nil, fmt.Errorf("获取哈希表时出错: %w", err)
-		}
-		if len(articleMap) == 0 {
-			log.Printf("文章ID为: %d的基本文章在缓存中不存在", id)
-			continue
+		ids = append(ids, id)
+	}
+	articleMap, err := a.getBasicArticles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var articles []*article_model.BasicArticle
+	for _, id := range ids {
+		if article, ok := articleMap[id]; ok {
+			articles = append(articles, article)
 		}
+	}
+	return articles, nil
+}
 
-		like, err := strconv.Atoi(articleMap["like"])
-		if err != nil {
-			return nil, fmt.Errorf("转换点赞数时出错: %w", err)
+// getBasicArticles 批量读取基本文章哈希表，不在缓存中的文章不在结果中
+func (a *ArticleCacheRepository) getBasicArticles(ctx context.Context, ids []int) (map[int]*article_model.BasicArticle, error) {
+	pipe := a.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, fmt.Sprintf("%s:basic:map:%d", prefix, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("获取哈希表时出错: %w", err)
+	}
+	articles := make(map[int]*article_model.BasicArticle, len(ids))
+	for i, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			log.Printf("文章ID为: %d的基本文章在缓存中不存在", ids[i])
+			continue
 		}
-		managerID, err := strconv.Atoi(articleMap["manager_id"])
+		article, err := parseBasicArticle(ids[i], cmd.Val())
 		if err != nil {
-			return nil, fmt.Errorf("转换管理员id时出错: %w", err)
-		}
-		article := &article_model.BasicArticle{
-			ID:        id,
-			Title:     articleMap["title"],
-			Content:   articleMap["content"],
-			Kind:      articleMap["kind"],
-			Like:      like,
-			ManagerID: managerID,
+			return nil, err
 		}
-		articles = append(articles, article)
+		articles[ids[i]] = article
 	}
+	return articles, nil
+}
 
+// GetBasicArticlesByIDs 批量获取基本文章，不在缓存中的文章不在结果中
+func (a *ArticleCacheRepository) GetBasicArticlesByIDs(ids []int) (map[int]*article_model.BasicArticle, error) {
+	articles, err := a.getBasicArticles(context.Background(), ids)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetBasicArticlesByIDs err: %w", err)
+	}
 	return articles, nil
 }
 
+// parseBasicArticle 由基本文章哈希表的字段生成基本文章
+func parseBasicArticle(id int, articleMap map[string]string) (*article_model.BasicArticle, error) {
+	like, err := strconv.Atoi(articleMap["like"])
+	if err != nil {
+		return nil, fmt.Errorf("转换点赞数时出错: %w", err)
+	}
+	managerID, err := strconv.Atoi(articleMap["manager_id"])
+	if err != nil {
+		return nil, fmt.Errorf("转换管理员id时出错: %w", err)
+	}
+	// 评论数字段在文章收到第一条评论时才会写入，不存在时视为 0
+	comment, _ := strconv.Atoi(articleMap["comment"])
+	view, _ := strconv.Atoi(articleMap["view"])
+	favorite, _ := strconv.Atoi(articleMap["favorite"])
+	article := &article_model.BasicArticle{
+		ID:        id,
+		Title:     articleMap["title"],
+		Content:   articleMap["content"],
+		Kind:      articleMap["kind"],
+		Like:      like,
+		Comment:   comment,
+		View:      view,
+		Favorite:  favorite,
+		ManagerID: managerID,
+		Tags:      utils.SplitTags(articleMap["tags"]),
+		Cover:     articleMap["cover"],
+	}
+	// 发布时间保存为秒级时间戳，较早写入的哈希表中没有这个字段
+	if createAt, err := strconv.ParseInt(articleMap["create_at"], 10, 64); err == nil {
+		article.CreateAt = time.Unix(createAt, 0)
+	}
+	// 置顶与归档字段不存在时视为不置顶、未归档
+	article.PinPriority, _ = strconv.Atoi(articleMap["pin_priority"])
+	article.PinExpireAt = parsePinExpire(articleMap["pin_expire_at"])
+	article.Archived = articleMap["archived"] == "1"
+	article.RequireAck = articleMap["require_ack"] == "1"
+	return article, nil
+}
+
+// pinExpireUnix 将置顶过期时间转换为秒级时间戳，没有过期时间时为 0
+func pinExpireUnix(expireAt *time.Time) int64 {
+	if expireAt == nil {
+		return 0
+	}
+	return expireAt.Unix()
+}
+
+// parsePinExpire 解析哈希表中的置顶过期时间，字段不存在或为 0 时返回 nil
+func parsePinExpire(value string) *time.Time {
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || unix == 0 {
+		return nil
+	}
+	expireAt := time.Unix(unix, 0)
+	return &expireAt
+}
+
 func (a *ArticleCacheRepository) GetArticleByID(id int) (*article_model.Article, error) {
 	ctx := context.Background()
 	key := fmt.Sprintf("%s:full:%d", prefix, id)
@@ -145,18 +247,25 @@ func (a *ArticleCacheRepository) GetArticleByID(id int) (*article_model.Article,
 	}
 
 	article := &article_model.Article{
-		ID:        id,
-		Title:     articleMap["title"],
-		Content:   articleMap["content"],
-		Kind:      articleMap["kind"],
-		Like:      like,
-		ManagerID: managerID,
-		CreateAt:  createAt,
+		ID:          id,
+		Title:       articleMap["title"],
+		Content:     articleMap["content"],
+		Format:      articleMap["format"],
+		Kind:        articleMap["kind"],
+		Like:        like,
+		ManagerID:   managerID,
+		CreateAt:    createAt,
+		Tags:        utils.SplitTags(articleMap["tags"]),
+		PinExpireAt: parsePinExpire(articleMap["pin_expire_at"]),
+		Archived:    articleMap["archived"] == "1",
+		RequireAck:  articleMap["require_ack"] == "1",
 	}
+	article.PinPriority, _ = strconv.Atoi(articleMap["pin_priority"])
 
 	return article, nil
 }
 
+// GetBasicArticleByID 获取基本文章，不在缓存中时返回 nil
 func (a *ArticleCacheRepository) GetBasicArticleByID(id int) (*article_model.BasicArticle, error) {
 	ctx := context.Background()
 	key := fmt.Sprintf("%s:basic:map:%d", prefix, id)
@@ -165,176 +274,1454 @@ func (a *ArticleCacheRepository) GetBasicArticleByID(id int) (*article_model.Bas
 		return nil, err
 	}
 	if len(articleMap) == 0 {
-		return nil, fmt.Errorf("基本文章类型在缓存不存在")
+		return nil, nil
 	}
-	like, err := strconv.Atoi(articleMap["like"])
-	if err != nil {
-		return nil, err
+	return parseBasicArticle(id, articleMap)
+}
+
+// SetArticleNull 缓存文章不存在的结果，在 expiration 内不再查询 MySQL
+func (a *ArticleCacheRepository) SetArticleNull(id int, expiration time.Duration) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:null:%d", prefix, id)
+	if err := a.client.Set(ctx, key, 1, expiration).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.SetArticleNull err: %w", err)
 	}
-	managerID, err := strconv.Atoi(articleMap["manager_id"])
+	return nil
+}
+
+// IsArticleNull 判断是否缓存了文章不存在的结果
+func (a *ArticleCacheRepository) IsArticleNull(id int) (bool, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:null:%d", prefix, id)
+	n, err := a.client.Exists(ctx, key).Result()
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("ArticleCacheRepository.IsArticleNull err: %w", err)
 	}
-	return &article_model.BasicArticle{
-		ID:        id,
-		Title:     articleMap["title"],
-		Content:   articleMap["content"],
-		Kind:      articleMap["kind"],
-		Like:      like,
-		ManagerID: managerID,
-	}, nil
+	return n == 1, nil
 }
 
-func (a *ArticleCacheRepository) AddLikes(articleID int, userID int, i int) error {
+// DeleteArticleNull 删除文章不存在的缓存，文章创建后立即生效
+func (a *ArticleCacheRepository) DeleteArticleNull(id int) error {
 	ctx := context.Background()
-	articleLikeKey := fmt.Sprintf("%s:like:%d", prefix, articleID)
-	exists, err := a.client.SIsMember(ctx, articleLikeKey, userID).Result()
-	if err != nil {
-		return fmt.Errorf("判断用户点赞时出错，文章 ID: %d, 用户 ID: %d, 错误信息: %w", articleID, userID, err)
+	key := fmt.Sprintf("%s:null:%d", prefix, id)
+	if err := a.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.DeleteArticleNull err: %w", err)
+	}
+	return nil
+}
+
+// bloomCheckScript 判断 ARGV 中的位是否全部为 1
+// 位图不存在时（Redis 数据丢失或尚未构建）返回 1，宁可多查一次 MySQL 也不能把存在的文章判断为不存在
+var bloomCheckScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 1
+end
+for i = 1, #ARGV do
+    if redis.call('GETBIT', KEYS[1], ARGV[i]) == 0 then
+        return 0
+    end
+end
+return 1
+`)
+
+// BloomMightContain 判断元素是否可能在布隆过滤器中，offsets 为元素对应的位，返回 false 时元素一定不存在
+func (a *ArticleCacheRepository) BloomMightContain(offsets []uint64) (bool, error) {
+	ctx := context.Background()
+	args := make([]interface{}, len(offsets))
+	for i, offset := range offsets {
+		args[i] = offset
 	}
-	if exists {
-		return fmt.Errorf("用户已经点赞，无法重复点赞，文章 ID: %d, 用户 ID: %d", articleID, userID)
+	result, err := bloomCheckScript.Run(ctx, a.client, []string{bloomKey}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("ArticleCacheRepository.BloomMightContain err: %w", err)
 	}
+	return result == 1, nil
+}
 
-	basicKey := fmt.Sprintf("%s:basic:map:%d", prefix, articleID)
-	if err := a.client.HIncrBy(ctx, basicKey, "like", int64(i)).Err(); err != nil {
-		return fmt.Errorf("增加基本信息哈希表点赞数时出错，文章 ID: %d, 错误信息: %w", articleID, err)
+// BloomAdd 将元素加入布隆过滤器
+func (a *ArticleCacheRepository) BloomAdd(offsets []uint64) error {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	for _, offset := range offsets {
+		pipe.SetBit(ctx, bloomKey, int64(offset), 1)
 	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.BloomAdd err: %w", err)
+	}
+	return nil
+}
+
+// bloomSwapScript 用重建好的位图替换当前位图
+// 替换前先合并当前位图，重建期间新加入的元素不会丢失
+var bloomSwapScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 1 then
+    redis.call('BITOP', 'OR', KEYS[1], KEYS[1], KEYS[2])
+end
+redis.call('RENAME', KEYS[1], KEYS[2])
+return 1
+`)
 
-	fullKey := fmt.Sprintf("%s:full:%d", prefix, articleID)
-	if err := a.client.HIncrBy(ctx, fullKey, "like", int64(i)).Err(); err != nil {
-		// 尝试回滚基本信息哈希表中的点赞数
-		if rollbackErr := a.client.HIncrBy(ctx, basicKey, "like", int64(-i)).Err(); rollbackErr != nil {
-			log.Printf("回滚基本文章点赞数时出错，文章 ID: %d, 错误信息: %v", articleID, rollbackErr)
-			return fmt.Errorf("增加完整信息哈希表点赞数出错且回滚基本信息哈希表点赞数失败，文章 ID: %d, 原始错误: %w, 回滚错误: %v", articleID, err, rollbackErr)
+// RebuildBloom 用给定的元素重建布隆过滤器，每个元素对应 offsetsList 中的一组位
+// 位图先写入临时键再整体替换，重建过程中过滤器一直可用
+func (a *ArticleCacheRepository) RebuildBloom(offsetsList [][]uint64, bits uint64) error {
+	ctx := context.Background()
+	if err := a.client.Del(ctx, bloomRebuildKey).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RebuildBloom err: %w", err)
+	}
+	// 先写入最后一位，一次分配好整个位图，也保证没有元素时位图同样存在
+	if err := a.client.SetBit(ctx, bloomRebuildKey, int64(bits-1), 0).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RebuildBloom err: %w", err)
+	}
+	const batchSize = 1000
+	for start := 0; start < len(offsetsList); start += batchSize {
+		pipe := a.client.Pipeline()
+		for _, offsets := range offsetsList[start:min(start+batchSize, len(offsetsList))] {
+			for _, offset := range offsets {
+				pipe.SetBit(ctx, bloomRebuildKey, int64(offset), 1)
+			}
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("ArticleCacheRepository.RebuildBloom err: %w", err)
 		}
-		return fmt.Errorf("增加完整文章表点赞数时出错，文章 ID: %d, 错误信息: %w", articleID, err)
 	}
-	if err := a.UseLikeSort(articleID, userID, "addLike"); err != nil {
-		return err
+	if err := bloomSwapScript.Run(ctx, a.client, []string{bloomRebuildKey, bloomKey}).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RebuildBloom err: %w", err)
 	}
 	return nil
 }
 
-func (a *ArticleCacheRepository) RemoveLikes(articleID int, userID int, i int) error {
-	ctx := context.Background()
-	articleLikeKey := fmt.Sprintf("%s:like:%d", prefix, articleID)
-	exists, err := a.client.SIsMember(ctx, articleLikeKey, userID).Result()
+// likeScript 原子地修改用户点赞状态和文章点赞数
+// KEYS[1] 为点赞用户集合，KEYS[2] 为基本文章哈希表，KEYS[3] 为完整文章哈希表，KEYS[4] 为点赞事件列表，KEYS[5] 为点赞脏集合
+// ARGV[1] 为用户 ID，ARGV[2] 为 1（点赞）或 -1（取消点赞），ARGV[3] 为文章 ID
+// 基本文章哈希表不存在时返回 -1，避免给不存在的文章写入只有点赞数的残缺哈希表；完整文章哈希表只在未过期时才更新
+// 状态改变时在同一个脚本中追加点赞事件并把文章加入脏集合，保证两者与点赞集合一致，由 FlushLikes 回写到 MySQL
+// 返回 {点赞数, 状态是否改变}，重复点赞或重复取消点赞时状态不变，点赞数也不变
+var likeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 0 then
+    return {-1, 0}
+end
+local changed
+if tonumber(ARGV[2]) > 0 then
+    changed = redis.call('SADD', KEYS[1], ARGV[1])
+else
+    changed = redis.call('SREM', KEYS[1], ARGV[1])
+end
+if changed == 0 then
+    return {tonumber(redis.call('HGET', KEYS[2], 'like') or '0'), 0}
+end
+local like = redis.call('HINCRBY', KEYS[2], 'like', ARGV[2])
+if redis.call('EXISTS', KEYS[3]) == 1 then
+    redis.call('HINCRBY', KEYS[3], 'like', ARGV[2])
+end
+redis.call('RPUSH', KEYS[4], ARGV[3] .. ':' .. ARGV[1] .. ':' .. ARGV[2])
+redis.call('SADD', KEYS[5], ARGV[3])
+return {like, 1}
+`)
+
+// AddLikes 用户点赞文章，返回最新点赞数以及点赞状态是否改变，hotSize 为热门排行保留的文章数
+func (a *ArticleCacheRepository) AddLikes(articleID int, userID int, hotSize int) (int, bool, error) {
+	like, changed, err := a.changeLike(articleID, userID, 1, hotSize)
 	if err != nil {
-		return fmt.Errorf("判断用户点赞时出错，文章 ID: %d, 用户 ID: %d, 错误信息: %w", articleID, userID, err)
-	}
-	if !exists {
-		return fmt.Errorf("无法重复取消点赞，文章 ID: %d, 用户 ID: %d", articleID, userID)
+		return 0, false, fmt.Errorf("ArticleCacheRepository.AddLikes err: %w", err)
 	}
+	return like, changed, nil
+}
 
-	basicKey := fmt.Sprintf("%s:basic:map:%d", prefix, articleID)
-	if err := a.client.HIncrBy(ctx, basicKey, "like", int64(i)).Err(); err != nil {
-		return fmt.Errorf("取消基本信息哈希表点赞数时出错，文章 ID: %d, 错误信息: %w", articleID, err)
+// RemoveLikes 用户取消点赞文章，返回最新点赞数以及点赞状态是否改变，hotSize 为热门排行保留的文章数
+func (a *ArticleCacheRepository) RemoveLikes(articleID int, userID int, hotSize int) (int, bool, error) {
+	like, changed, err := a.changeLike(articleID, userID, -1, hotSize)
+	if err != nil {
+		return 0, false, fmt.Errorf("ArticleCacheRepository.RemoveLikes err: %w", err)
 	}
+	return like, changed, nil
+}
 
-	fullKey := fmt.Sprintf("%s:full:%d", prefix, articleID)
-	if err := a.client.HIncrBy(ctx, fullKey, "like", int64(i)).Err(); err != nil {
-		// 尝试回滚基本信息哈希表中的点赞数
-		if rollbackErr := a.client.HIncrBy(ctx, basicKey, "like", int64(-i)).Err(); rollbackErr != nil {
-			log.Printf("回滚基本文章点赞数时出错，文章 ID: %d, 错误信息: %v", articleID, rollbackErr)
-			return fmt.Errorf("取消完整信息哈希表点赞数出错且回滚基本信息哈希表点赞数失败，文章 ID: %d, 原始错误: %w, 回滚错误: %v", articleID, err, rollbackErr)
+func (a *ArticleCacheRepository) changeLike(articleID int, userID int, delta int, hotSize int) (int, bool, error) {
+	ctx := context.Background()
+	keys := []string{
+		fmt.Sprintf("%s:like:%d", prefix, articleID),
+		fmt.Sprintf("%s:basic:map:%d", prefix, articleID),
+		fmt.Sprintf("%s:full:%d", prefix, articleID),
+		likeEventsKey,
+		likeDirtyKey,
+	}
+	result, err := likeScript.Run(ctx, a.client, keys, userID, delta, articleID).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if result[0] < 0 {
+		return 0, false, ErrArticleNotCached
+	}
+	if result[1] == 1 {
+		// 热度由点赞数等计数算出，点赞已经成功，更新失败时等待定时重算
+		if err := a.refreshHotScore(ctx, articleID, hotSize); err != nil {
+			log.Printf("更新文章%d的热度失败: %v", articleID, err)
 		}
-		return fmt.Errorf("取消完整文章表点赞数时出错，文章 ID: %d, 错误信息: %w", articleID, err)
 	}
+	return int(result[0]), result[1] == 1, nil
+}
 
-	if err := a.UseLikeSort(articleID, userID, "removeLike"); err != nil {
-		return err
+// takeLikeEventsScript 将事件列表中最早的 ARGV[1] 条事件移到处理中列表，返回处理中列表的全部事件
+// 上一批回写失败时处理中列表不会被删除，直接返回这一批重新回写，不会取出新的事件
+var takeLikeEventsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 0 then
+    local values = redis.call('LRANGE', KEYS[1], 0, tonumber(ARGV[1]) - 1)
+    if #values == 0 then
+        return values
+    end
+    redis.call('RPUSH', KEYS[2], unpack(values))
+    redis.call('LTRIM', KEYS[1], #values, -1)
+end
+return redis.call('LRANGE', KEYS[2], 0, -1)
+`)
+
+// TakeLikeEvents 按发生顺序取出最早的 n 条点赞事件，取出与移出事件列表是原子的
+func (a *ArticleCacheRepository) TakeLikeEvents(n int) ([]*article_model.LikeEvent, error) {
+	ctx := context.Background()
+	values, err := takeLikeEventsScript.Run(ctx, a.client, []string{likeEventsKey, likeEventsProcessingKey}, n).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.TakeLikeEvents err: %w", err)
+	}
+	events := make([]*article_model.LikeEvent, 0, len(values))
+	for _, value := range values {
+		var event article_model.LikeEvent
+		if _, err := fmt.Sscanf(value, "%d:%d:%d", &event.ArticleID, &event.UserID, &event.Delta); err != nil {
+			// 格式错误的事件无法回写，跳过但仍计入这一批，随其他事件一起从处理中列表删除
+			log.Printf("解析点赞事件%q出错: %v", value, err)
+			events = append(events, nil)
+			continue
+		}
+		events = append(events, &event)
 	}
+	return events, nil
+}
 
+// FinishLikeEvents 一批事件回写成功后删除处理中列表
+func (a *ArticleCacheRepository) FinishLikeEvents() error {
+	ctx := context.Background()
+	if err := a.client.Del(ctx, likeEventsProcessingKey).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.FinishLikeEvents err: %w", err)
+	}
 	return nil
 }
 
-func (a *ArticleCacheRepository) UseLikeSort(articleID int, userID int, option string) error {
-	if option != "addLike" && option != "removeLike" {
-		return fmt.Errorf("option参数错误")
+// AcquireLikeFlushLock 获取回写点赞事件的锁，owner 标识持有者，锁被其他持有者占用时返回 false
+// 持有者已经持有锁时延长过期时间，回写每一批事件前调用以续期
+func (a *ArticleCacheRepository) AcquireLikeFlushLock(owner string, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+	ok, err := acquireLockScript.Run(ctx, a.client, []string{likeFlushLockKey}, owner, ttl.Milliseconds()).Bool()
+	if err != nil {
+		return false, fmt.Errorf("ArticleCacheRepository.AcquireLikeFlushLock err: %w", err)
 	}
+	return ok, nil
+}
 
+// ReleaseLikeFlushLock 释放回写点赞事件的锁，锁已过期并被其他持有者获取时不做处理
+func (a *ArticleCacheRepository) ReleaseLikeFlushLock(owner string) error {
 	ctx := context.Background()
-	key := fmt.Sprintf("%s:like:%d", prefix, articleID)
+	if err := releaseLockScript.Run(ctx, a.client, []string{likeFlushLockKey}, owner).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.ReleaseLikeFlushLock err: %w", err)
+	}
+	return nil
+}
+
+// acquireLockScript 锁不存在或由 ARGV[1] 持有时设置锁并把过期时间设为 ARGV[2] 毫秒，返回是否持有锁
+var acquireLockScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner and owner ~= ARGV[1] then
+    return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// releaseLockScript 锁由 ARGV[1] 持有时删除锁
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// resetLikesScript 用 MySQL 中的点赞关系重建一批文章的点赞集合和点赞数，再按发生顺序重放尚未回写的点赞事件
+// KEYS[1]、KEYS[2] 为点赞处理中列表与事件列表，之后每篇文章依次为点赞集合、基本文章哈希表、完整文章哈希表
+// ARGV 依次为每篇文章的 ID、MySQL 中的点赞用户数以及这些用户的 ID
+// 重放与重建在同一个脚本中完成，读取 MySQL 之后发生的点赞不会被旧数据覆盖
+var resetLikesScript = redis.NewScript(`
+local pending = {}
+for k = 1, 2 do
+    for _, event in ipairs(redis.call('LRANGE', KEYS[k], 0, -1)) do
+        local articleID, userID, delta = string.match(event, '^(%d+):(%d+):(-?%d+)$')
+        if articleID then
+            pending[articleID] = pending[articleID] or {}
+            table.insert(pending[articleID], {userID, tonumber(delta)})
+        end
+    end
+end
+local k, i = 3, 1
+while i <= #ARGV do
+    local articleID, n = ARGV[i], tonumber(ARGV[i + 1])
+    redis.call('DEL', KEYS[k])
+    for j = i + 2, i + 1 + n do
+        redis.call('SADD', KEYS[k], ARGV[j])
+    end
+    for _, event in ipairs(pending[articleID] or {}) do
+        if event[2] > 0 then
+            redis.call('SADD', KEYS[k], event[1])
+        else
+            redis.call('SREM', KEYS[k], event[1])
+        end
+    end
+    local like = redis.call('SCARD', KEYS[k])
+    if redis.call('EXISTS', KEYS[k + 1]) == 1 then
+        redis.call('HSET', KEYS[k + 1], 'like', like)
+    end
+    if redis.call('EXISTS', KEYS[k + 2]) == 1 then
+        redis.call('HSET', KEYS[k + 2], 'like', like)
+    end
+    k = k + 3
+    i = i + 2 + n
+end
+return 1
+`)
 
-	if option == "addLike" {
-		return a.client.SAdd(ctx, key, userID).Err()
+// ResetLikes 将一批文章的点赞集合和点赞数重置为 MySQL 中的点赞用户，并重放尚未回写的点赞事件
+// 调用方需要持有点赞回写锁，保证读取 MySQL 之后没有事件被回写并移出事件列表
+func (a *ArticleCacheRepository) ResetLikes(articleIDs []int, userIDs map[int][]int) error {
+	if len(articleIDs) == 0 {
+		return nil
 	}
+	ctx := context.Background()
+	keys := make([]string, 0, len(articleIDs)*3+2)
+	keys = append(keys, likeEventsProcessingKey, likeEventsKey)
+	var args []interface{}
+	for _, articleID := range articleIDs {
+		keys = append(keys,
+			fmt.Sprintf("%s:like:%d", prefix, articleID),
+			fmt.Sprintf("%s:basic:map:%d", prefix, articleID),
+			fmt.Sprintf("%s:full:%d", prefix, articleID),
+		)
+		args = append(args, articleID, len(userIDs[articleID]))
+		for _, userID := range userIDs[articleID] {
+			args = append(args, userID)
+		}
+	}
+	if err := resetLikesScript.Run(ctx, a.client, keys, args...).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.ResetLikes err: %w", err)
+	}
+	return nil
+}
 
-	return a.client.SRem(ctx, key, userID).Err()
+// refillLikesScript 点赞集合为空时用 MySQL 中的点赞关系重新填充，KEYS[1] 为点赞集合，KEYS[2]、KEYS[3] 为点赞脏集合与处理中集合，
+// ARGV 为点赞用户 ID；文章在脏集合中时还有点赞事件没有回写，MySQL 中的数据已经过时，不做填充，返回是否已填充
+var refillLikesScript = redis.NewScript(`
+if redis.call('SCARD', KEYS[1]) > 0 or redis.call('SISMEMBER', KEYS[2], ARGV[1]) == 1 or
+    redis.call('SISMEMBER', KEYS[3], ARGV[1]) == 1 then
+    return 0
+end
+for i = 2, #ARGV do
+    redis.call('SADD', KEYS[1], ARGV[i])
+end
+return 1
+`)
+
+// RefillLikes 点赞集合为空（例如 Redis 数据丢失）时用 MySQL 中的点赞用户填充，文章有未回写的点赞变化时不填充
+func (a *ArticleCacheRepository) RefillLikes(articleID int, userIDs []int) (bool, error) {
+	ctx := context.Background()
+	keys := []string{fmt.Sprintf("%s:like:%d", prefix, articleID), likeDirtyKey, likeDirtyProcessingKey}
+	args := make([]interface{}, 0, len(userIDs)+1)
+	args = append(args, articleID)
+	for _, userID := range userIDs {
+		args = append(args, userID)
+	}
+	refilled, err := refillLikesScript.Run(ctx, a.client, keys, args...).Bool()
+	if err != nil {
+		return false, fmt.Errorf("ArticleCacheRepository.RefillLikes err: %w", err)
+	}
+	return refilled, nil
 }
 
-// GetAllArticlesFromHash 获取哈希表中的所有文章信息
-func (a *ArticleCacheRepository) GetAllArticlesFromHash() ([][]int, error) {
-	var cursor uint64
-	var results [][]int
+// takeDirtyScript 将脏集合中的文章 ID 合并到处理中集合并清空脏集合，返回处理中集合的全部文章 ID
+// 上一轮回写失败时处理中集合不会被删除，这一轮会与新的脏文章一起重新回写
+var takeDirtyScript = redis.NewScript(`
+redis.call('SUNIONSTORE', KEYS[2], KEYS[2], KEYS[1])
+redis.call('DEL', KEYS[1])
+return redis.call('SMEMBERS', KEYS[2])
+`)
+
+// TakeDirtyArticles 取出点赞数有变化、需要回写的文章 ID
+func (a *ArticleCacheRepository) TakeDirtyArticles() ([]int, error) {
 	ctx := context.Background()
-	for {
-		keys, nextCursor, err := a.client.Scan(ctx, cursor, "hcl:article:basic:map:*", 0).Result()
-		log.Printf("扫描结果: %v", keys)
+	values, err := takeDirtyScript.Run(ctx, a.client, []string{likeDirtyKey, likeDirtyProcessingKey}).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.TakeDirtyArticles err: %w", err)
+	}
+	ids := make([]int, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.Atoi(value)
 		if err != nil {
-			return nil, err
+			log.Printf("转换文章 ID 出错: %v", err)
+			continue
 		}
-		for _, key := range keys {
-			// 从键中提取文章 ID
-			idStr := key[len("hcl:article:basic:map:"):]
-			id, err := strconv.Atoi(idStr)
-			if err != nil {
-				log.Printf("转换文章 ID 出错: %v", err)
-				continue
-			}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
 
-			likeStr, err := a.client.HGet(ctx, key, "like").Result()
-			if err != nil {
-				log.Printf("获取文章 %d 点赞数出错: %v", id, err)
-				continue
-			}
-			like, err := strconv.Atoi(likeStr)
-			if err != nil {
-				log.Printf("转换文章 %d 点赞数出错: %v", id, err)
-				continue
-			}
+// FinishDirtyArticles 回写成功后清空处理中集合
+func (a *ArticleCacheRepository) FinishDirtyArticles() error {
+	ctx := context.Background()
+	if err := a.client.Del(ctx, likeDirtyProcessingKey).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.FinishDirtyArticles err: %w", err)
+	}
+	return nil
+}
+
+// viewScript 记录一次浏览：增加基本文章哈希表中的浏览数，将用户加入文章总的和当天的独立访客 HyperLogLog，
+// 增加当天的浏览次数，并把 "文章ID:日期" 加入浏览脏集合
+// KEYS[1] 为基本文章哈希表，KEYS[2] 为总的 HyperLogLog，KEYS[3] 为当天的 HyperLogLog，KEYS[4] 为当天的浏览次数，KEYS[5] 为浏览脏集合
+// ARGV[1] 为用户 ID，ARGV[2] 为当天数据的过期秒数，ARGV[3] 为 "文章ID:日期"
+// 基本文章哈希表不存在时返回 {-1, 0}，否则返回 {浏览数, 独立访客数}
+var viewScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return {-1, 0}
+end
+local view = redis.call('HINCRBY', KEYS[1], 'view', 1)
+redis.call('PFADD', KEYS[2], ARGV[1])
+redis.call('PFADD', KEYS[3], ARGV[1])
+redis.call('INCR', KEYS[4])
+redis.call('EXPIRE', KEYS[3], ARGV[2])
+redis.call('EXPIRE', KEYS[4], ARGV[2])
+redis.call('SADD', KEYS[5], ARGV[3])
+return {view, redis.call('PFCOUNT', KEYS[2])}
+`)
+
+// viewDailyKeys 返回文章某天的独立访客 HyperLogLog 与浏览次数的键，day 的格式为 20060102
+func viewDailyKeys(articleID int, day string) (string, string) {
+	return fmt.Sprintf("%s:view:uv:%d:%s", prefix, articleID, day), fmt.Sprintf("%s:view:pv:%d:%s", prefix, articleID, day)
+}
+
+// RecordView 记录用户浏览文章，返回文章的浏览数与独立访客数，dailyTTL 为当天数据的保留时长
+func (a *ArticleCacheRepository) RecordView(articleID int, userID int, now time.Time, dailyTTL time.Duration) (int, int, error) {
+	ctx := context.Background()
+	day := now.Format("20060102")
+	dailyUVKey, dailyPVKey := viewDailyKeys(articleID, day)
+	keys := []string{
+		fmt.Sprintf("%s:basic:map:%d", prefix, articleID),
+		fmt.Sprintf("%s:view:uv:%d", prefix, articleID),
+		dailyUVKey,
+		dailyPVKey,
+		viewDirtyKey,
+	}
+	result, err := viewScript.Run(ctx, a.client, keys, userID, int(dailyTTL.Seconds()), fmt.Sprintf("%d:%s", articleID, day)).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("ArticleCacheRepository.RecordView err: %w", err)
+	}
+	if result[0] < 0 {
+		return 0, 0, ErrArticleNotCached
+	}
+	return int(result[0]), int(result[1]), nil
+}
 
-			results = append(results, []int{id, like})
+// TakeDirtyViews 取出浏览数有变化、需要回写的 "文章ID:日期"，上一轮回写失败的会一起取出
+func (a *ArticleCacheRepository) TakeDirtyViews() ([]*article_model.ArticleViewDaily, error) {
+	ctx := context.Background()
+	values, err := takeDirtyScript.Run(ctx, a.client, []string{viewDirtyKey, viewDirtyProcessingKey}).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.TakeDirtyViews err: %w", err)
+	}
+	stats := make([]*article_model.ArticleViewDaily, 0, len(values))
+	for _, value := range values {
+		idStr, day, _ := strings.Cut(value, ":")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Printf("转换文章 ID 出错: %v", err)
+			continue
+		}
+		date, err := time.ParseInLocation("20060102", day, time.Local)
+		if err != nil {
+			log.Printf("解析浏览日期出错: %v", err)
+			continue
 		}
+		stats = append(stats, &article_model.ArticleViewDaily{ArticleID: id, Date: date})
+	}
+	return stats, nil
+}
 
-		cursor = nextCursor
-		if cursor == 0 {
-			break
+// FinishDirtyViews 浏览数回写成功后清空处理中集合
+func (a *ArticleCacheRepository) FinishDirtyViews() error {
+	ctx := context.Background()
+	if err := a.client.Del(ctx, viewDirtyProcessingKey).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.FinishDirtyViews err: %w", err)
+	}
+	return nil
+}
+
+// FillViewDaily 读取每条统计对应日期的浏览次数与独立访客数，当天数据已过期时保持为 0
+func (a *ArticleCacheRepository) FillViewDaily(stats []*article_model.ArticleViewDaily) error {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	pvCmds := make([]*redis.StringCmd, len(stats))
+	uvCmds := make([]*redis.IntCmd, len(stats))
+	for i, stat := range stats {
+		uvKey, pvKey := viewDailyKeys(stat.ArticleID, stat.Date.Format("20060102"))
+		pvCmds[i] = pipe.Get(ctx, pvKey)
+		uvCmds[i] = pipe.PFCount(ctx, uvKey)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("ArticleCacheRepository.FillViewDaily err: %w", err)
+	}
+	for i, stat := range stats {
+		stat.Views, _ = pvCmds[i].Int()
+		stat.UniqueViews = int(uvCmds[i].Val())
+	}
+	return nil
+}
+
+// GetViewTotals 批量获取文章的浏览数与独立访客数，基本文章不在缓存中的文章会被跳过
+func (a *ArticleCacheRepository) GetViewTotals(articleIDs []int) (map[int]int, map[int]int, error) {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	viewCmds := make([]*redis.StringCmd, len(articleIDs))
+	uvCmds := make([]*redis.IntCmd, len(articleIDs))
+	for i, id := range articleIDs {
+		viewCmds[i] = pipe.HGet(ctx, fmt.Sprintf("%s:basic:map:%d", prefix, id), "view")
+		uvCmds[i] = pipe.PFCount(ctx, fmt.Sprintf("%s:view:uv:%d", prefix, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, nil, fmt.Errorf("ArticleCacheRepository.GetViewTotals err: %w", err)
+	}
+	views := make(map[int]int, len(articleIDs))
+	uniqueViews := make(map[int]int, len(articleIDs))
+	for i, id := range articleIDs {
+		view, err := viewCmds[i].Int()
+		if err != nil {
+			continue
 		}
+		views[id] = view
+		uniqueViews[id] = int(uvCmds[i].Val())
+	}
+	return views, uniqueViews, nil
+}
 
+// GetLikeCounts 批量获取文章的点赞数，基本文章不在缓存中的文章会被跳过
+func (a *ArticleCacheRepository) GetLikeCounts(articleIDs []int) (map[int]int, error) {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(articleIDs))
+	for i, id := range articleIDs {
+		cmds[i] = pipe.HGet(ctx, fmt.Sprintf("%s:basic:map:%d", prefix, id), "like")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetLikeCounts err: %w", err)
+	}
+
+	likes := make(map[int]int, len(articleIDs))
+	for i, cmd := range cmds {
+		like, err := cmd.Int()
+		if err != nil {
+			if err != redis.Nil {
+				log.Printf("获取文章 %d 点赞数出错: %v", articleIDs[i], err)
+			}
+			continue
+		}
+		likes[articleIDs[i]] = like
 	}
-	return results, nil
+	return likes, nil
 }
 
-func (a *ArticleCacheRepository) DeleteArticleForUpdate(id int) error {
+// AutocompleteTags 按前缀从标签词典中补全标签
+// 词典中所有成员的分值都为 0，利用 ZRANGEBYLEX 做字典序前缀查询
+func (a *ArticleCacheRepository) AutocompleteTags(tagPrefix string, n int) ([]string, error) {
 	ctx := context.Background()
-	fullKey := fmt.Sprintf("%s:full:%d", prefix, id)
-	if err := a.client.Del(ctx, fullKey).Err(); err != nil {
-		return fmt.Errorf("删除完整文章时出错，文章 ID: %d, 错误信息: %w", id, err)
+	tags, err := a.client.ZRangeByLex(ctx, fmt.Sprintf("%s:tag:dict", prefix), &redis.ZRangeBy{
+		Min:   "[" + tagPrefix,
+		Max:   "[" + tagPrefix + "\xff",
+		Count: int64(n),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.AutocompleteTags err: %w", err)
 	}
+	return tags, nil
+}
 
-	basicKey := fmt.Sprintf("%s:basic:map:%d", prefix, id)
-	if err := a.client.Del(ctx, basicKey).Err(); err != nil {
-		return fmt.Errorf("删除基本文章时出错，文章 ID: %d, 错误信息: %w", id, err)
+// GetPopularTags 获取关联文章数最多的前 n 个标签
+func (a *ArticleCacheRepository) GetPopularTags(n int) ([]*article_model.PopularTag, error) {
+	ctx := context.Background()
+	results, err := a.client.ZRevRangeByScoreWithScores(ctx, fmt.Sprintf("%s:tag:popular", prefix), &redis.ZRangeBy{
+		Min:   "1",
+		Max:   "+inf",
+		Count: int64(n),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetPopularTags err: %w", err)
 	}
+	tags := make([]*article_model.PopularTag, 0, len(results))
+	for _, result := range results {
+		tags = append(tags, &article_model.PopularTag{
+			Name:  result.Member.(string),
+			Count: int(result.Score),
+		})
+	}
+	return tags, nil
+}
 
+// IncrCommentCount 调整基本文章哈希表中的评论数，哈希表不存在时不做处理，避免写入残缺的文章，hotSize 为热门排行保留的文章数
+func (a *ArticleCacheRepository) IncrCommentCount(articleID int, delta int, hotSize int) error {
+	ctx := context.Background()
+	script := `
+    if redis.call('EXISTS', KEYS[1]) == 0 then
+        return 0
+    end
+    redis.call('HINCRBY', KEYS[1], 'comment', ARGV[1])
+    return 1
+    `
+	basicKey := fmt.Sprintf("%s:basic:map:%d", prefix, articleID)
+	if err := a.client.Eval(ctx, script, []string{basicKey}, delta).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.IncrCommentCount err: %w", err)
+	}
+	if err := a.refreshHotScore(ctx, articleID, hotSize); err != nil {
+		log.Printf("更新文章%d的热度失败: %v", articleID, err)
+	}
 	return nil
 }
 
-func (a *ArticleCacheRepository) AddBasicArticleWithoutAddList(article *article_model.Article) error {
+// GetCommentCount 从基本文章哈希表中获取评论数
+func (a *ArticleCacheRepository) GetCommentCount(articleID int) (int, error) {
 	ctx := context.Background()
-	key := fmt.Sprintf("%s:basic:map:%d", prefix, article.ID)
-	basicArticleMap := map[string]interface{}{
-		"id":         article.ID,
-		"title":      article.Title,
-		"content":    utils.Substring(article.Content, 5),
-		"kind":       article.Kind,
-		"like":       article.Like,
-		"manager_id": article.ManagerID,
-	}
-	if err := a.client.HSet(ctx, key, basicArticleMap).Err(); err != nil {
-		return fmt.Errorf("添加基本文章时出错，文章 ID: %d, 错误信息: %w", article.ID, err)
+	basicKey := fmt.Sprintf("%s:basic:map:%d", prefix, articleID)
+	countStr, err := a.client.HGet(ctx, basicKey, "comment").Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ArticleCacheRepository.GetCommentCount err: %w", err)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return 0, fmt.Errorf("ArticleCacheRepository.GetCommentCount err: %w", err)
+	}
+	return count, nil
+}
+
+// GetFavoriteCount 从基本文章哈希表中获取收藏数
+func (a *ArticleCacheRepository) GetFavoriteCount(articleID int) (int, error) {
+	ctx := context.Background()
+	basicKey := fmt.Sprintf("%s:basic:map:%d", prefix, articleID)
+	countStr, err := a.client.HGet(ctx, basicKey, "favorite").Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ArticleCacheRepository.GetFavoriteCount err: %w", err)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return 0, fmt.Errorf("ArticleCacheRepository.GetFavoriteCount err: %w", err)
+	}
+	return count, nil
+}
+
+// basicArticleMap 将基本文章转换为写入哈希表的字段
+func basicArticleMap(article *article_model.BasicArticle) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            article.ID,
+		"title":         article.Title,
+		"content":       article.Content,
+		"kind":          article.Kind,
+		"like":          article.Like,
+		"manager_id":    article.ManagerID,
+		"tags":          strings.Join(article.Tags, ","),
+		"comment":       article.Comment,
+		"cover":         article.Cover,
+		"view":          article.View,
+		"favorite":      article.Favorite,
+		"create_at":     article.CreateAt.Unix(),
+		"pin_priority":  article.PinPriority,
+		"pin_expire_at": pinExpireUnix(article.PinExpireAt),
+		"archived":      article.Archived,
+		"require_ack":   article.RequireAck,
+	}
+}
+
+// addBasicArticleIfAbsentScript 只在哈希表不存在时写入，ARGV 为交替的字段名与值
+var addBasicArticleIfAbsentScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+    return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV))
+return 1
+`)
+
+// AddBasicArticleIfAbsent 基本文章不在缓存中时写入，已存在时不覆盖，避免覆盖并发写入的点赞数
+func (a *ArticleCacheRepository) AddBasicArticleIfAbsent(article *article_model.BasicArticle) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:basic:map:%d", prefix, article.ID)
+	fields := basicArticleMap(article)
+	args := make([]interface{}, 0, len(fields)*2)
+	for field, value := range fields {
+		args = append(args, field, value)
+	}
+	if err := addBasicArticleIfAbsentScript.Run(ctx, a.client, []string{key}, args...).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.AddBasicArticleIfAbsent err: %w", err)
+	}
+	return nil
+}
+
+// GetLikedByUser 批量判断用户是否点赞了文章，每篇文章的点赞集合各执行一次 SMISMEMBER，在同一个管道中发送
+func (a *ArticleCacheRepository) GetLikedByUser(articleIDs []int, userID int) (map[int]bool, error) {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	cmds := make([]*redis.BoolSliceCmd, len(articleIDs))
+	for i, id := range articleIDs {
+		cmds[i] = pipe.SMIsMember(ctx, fmt.Sprintf("%s:like:%d", prefix, id), userID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetLikedByUser err: %w", err)
+	}
+	liked := make(map[int]bool, len(articleIDs))
+	for i, cmd := range cmds {
+		liked[articleIDs[i]] = cmd.Val()[0]
+	}
+	return liked, nil
+}
+
+// CountLikeUsers 获取点赞用户集合的大小，集合不存在时为 0
+func (a *ArticleCacheRepository) CountLikeUsers(articleID int) (int, error) {
+	ctx := context.Background()
+	count, err := a.client.SCard(ctx, fmt.Sprintf("%s:like:%d", prefix, articleID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("ArticleCacheRepository.CountLikeUsers err: %w", err)
+	}
+	return int(count), nil
+}
+
+// CountBasicLists 获取给定类型中基本文章列表存在的个数
+func (a *ArticleCacheRepository) CountBasicLists(kinds []string) (int, error) {
+	ctx := context.Background()
+	keys := make([]string, len(kinds))
+	for i, kind := range kinds {
+		keys[i] = fmt.Sprintf("%s:basic:list:%s", prefix, kind)
+	}
+	count, err := a.client.Exists(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("ArticleCacheRepository.CountBasicLists err: %w", err)
+	}
+	return int(count), nil
+}
+
+// rebuildingSuffix 重建时基本文章列表先写入带这个后缀的临时键，全部写完后再替换
+const rebuildingSuffix = ":rebuilding"
+
+// ClearRebuildingLists 删除上一次重建遗留的临时列表
+func (a *ArticleCacheRepository) ClearRebuildingLists() error {
+	ctx := context.Background()
+	for _, pattern := range []string{prefix + ":basic:list:*" + rebuildingSuffix, prefix + ":basic:tag:*" + rebuildingSuffix} {
+		iter := a.client.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			if err := a.client.Del(ctx, iter.Val()).Err(); err != nil {
+				return fmt.Errorf("ArticleCacheRepository.ClearRebuildingLists err: %w", err)
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("ArticleCacheRepository.ClearRebuildingLists err: %w", err)
+		}
+	}
+	return nil
+}
+
+// RebuildBasicArticles 覆盖写入一批基本文章的哈希表，并将未归档的文章加入类型与标签的临时列表
+// 文章需要按 ID 升序传入，与 SyncBasicArticle 一样从列表头部插入，列表中新文章在前
+func (a *ArticleCacheRepository) RebuildBasicArticles(articles []*article_model.BasicArticle) error {
+	ctx := context.Background()
+	pipe := a.client.TxPipeline()
+	for _, article := range articles {
+		mapKey := fmt.Sprintf("%s:basic:map:%d", prefix, article.ID)
+		pipe.Del(ctx, mapKey)
+		pipe.HSet(ctx, mapKey, basicArticleMap(article))
+		if article.Archived {
+			continue
+		}
+		pipe.HSet(ctx, mapKey, "listed", 1)
+		pipe.LPush(ctx, fmt.Sprintf("%s:basic:list:%s%s", prefix, article.Kind, rebuildingSuffix), article.ID)
+		for _, tag := range article.Tags {
+			pipe.LPush(ctx, fmt.Sprintf("%s:basic:tag:%s%s", prefix, tag, rebuildingSuffix), article.ID)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RebuildBasicArticles err: %w", err)
+	}
+	return nil
+}
+
+// swapListScript 用临时列表替换正式列表
+// 重建期间新发布的文章（ID 大于 ARGV[1]）只写入了正式列表，替换前按原顺序补到临时列表头部
+var swapListScript = redis.NewScript(`
+local items = redis.call('LRANGE', KEYS[2], 0, -1)
+for i = #items, 1, -1 do
+    if tonumber(items[i]) > tonumber(ARGV[1]) then
+        redis.call('LPUSH', KEYS[1], items[i])
+    end
+end
+if redis.call('EXISTS', KEYS[1]) == 1 then
+    redis.call('RENAME', KEYS[1], KEYS[2])
+else
+    redis.call('DEL', KEYS[2])
+end
+return 1
+`)
+
+// SwapRebuiltLists 用重建好的临时列表替换类型与标签的基本文章列表，maxID 为重建时读取到的最大文章 ID
+func (a *ArticleCacheRepository) SwapRebuiltLists(kinds []string, tags []string, maxID int) error {
+	ctx := context.Background()
+	listKeys := make([]string, 0, len(kinds)+len(tags))
+	for _, kind := range kinds {
+		listKeys = append(listKeys, fmt.Sprintf("%s:basic:list:%s", prefix, kind))
+	}
+	for _, tag := range tags {
+		listKeys = append(listKeys, fmt.Sprintf("%s:basic:tag:%s", prefix, tag))
+	}
+	for _, listKey := range listKeys {
+		if err := swapListScript.Run(ctx, a.client, []string{listKey + rebuildingSuffix, listKey}, maxID).Err(); err != nil {
+			return fmt.Errorf("ArticleCacheRepository.SwapRebuiltLists err: %w", err)
+		}
+	}
+	return nil
+}
+
+// ResetTagStats 用各标签的文章数重置标签词典与热门标签
+func (a *ArticleCacheRepository) ResetTagStats(counts map[string]int) error {
+	ctx := context.Background()
+	dictKey := fmt.Sprintf("%s:tag:dict", prefix)
+	popularKey := fmt.Sprintf("%s:tag:popular", prefix)
+	pipe := a.client.TxPipeline()
+	pipe.Del(ctx, dictKey, popularKey)
+	for tag, count := range counts {
+		pipe.ZAdd(ctx, dictKey, redis.Z{Score: 0, Member: tag})
+		pipe.ZAdd(ctx, popularKey, redis.Z{Score: float64(count), Member: tag})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.ResetTagStats err: %w", err)
+	}
+	return nil
+}
+
+// DeleteFullArticle 删除完整文章缓存
+func (a *ArticleCacheRepository) DeleteFullArticle(id int) error {
+	ctx := context.Background()
+	if err := a.client.Del(ctx, fmt.Sprintf("%s:full:%d", prefix, id)).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.DeleteFullArticle err: %w", err)
+	}
+	return nil
+}
+
+// SyncBasicArticle 用 MySQL 中的文章同步基本文章哈希表、类型列表、标签列表与置顶文章集合
+// 哈希表中的 listed 字段标记文章是否已经加入列表，tags 字段为上次同步的标签，据此只调整有变化的标签；
+// 点赞数、评论数、浏览数与收藏数由缓存维护，哈希表中已有时不覆盖。使用 WATCH 保证并发同步同一篇文章时计数不会重复调整，
+// 重复执行同一次同步结果不变
+func (a *ArticleCacheRepository) SyncBasicArticle(article *article_model.BasicArticle) error {
+	ctx := context.Background()
+	mapKey := fmt.Sprintf("%s:basic:map:%d", prefix, article.ID)
+	sync := func(tx *redis.Tx) error {
+		values, err := tx.HMGet(ctx, mapKey, "listed", "tags").Result()
+		if err != nil {
+			return err
+		}
+		listed := values[0] == "1"
+		listKey := fmt.Sprintf("%s:basic:list:%s", prefix, article.Kind)
+		oldTags := map[string]bool{}
+		if listed {
+			if tags, ok := values[1].(string); ok {
+				for _, tag := range utils.SplitTags(tags) {
+					oldTags[tag] = true
+				}
+			}
+		}
+		// 归档的文章移出类型与标签列表，视为没有标签；取消归档时与新文章一样重新加入列表
+		newTags := map[string]bool{}
+		if !article.Archived {
+			for _, tag := range article.Tags {
+				newTags[tag] = true
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			fields := basicArticleMap(article)
+			pipe.HSetNX(ctx, mapKey, "like", fields["like"])
+			pipe.HSetNX(ctx, mapKey, "comment", fields["comment"])
+			pipe.HSetNX(ctx, mapKey, "view", fields["view"])
+			pipe.HSetNX(ctx, mapKey, "favorite", fields["favorite"])
+			delete(fields, "like")
+			delete(fields, "comment")
+			delete(fields, "view")
+			delete(fields, "favorite")
+			if article.Archived {
+				fields["listed"] = 0
+				pipe.LRem(ctx, listKey, 0, article.ID)
+			} else {
+				fields["listed"] = 1
+				if !listed {
+					pipe.LRem(ctx, listKey, 0, article.ID)
+					pipe.LPush(ctx, listKey, article.ID)
+				}
+			}
+			pipe.HSet(ctx, mapKey, fields)
+			if article.PinPriority > 0 && !article.Archived {
+				pipe.ZAdd(ctx, pinnedKey(article.Kind), redis.Z{Score: float64(article.PinPriority), Member: article.ID})
+			} else {
+				pipe.ZRem(ctx, pinnedKey(article.Kind), article.ID)
+			}
+			for tag := range newTags {
+				if oldTags[tag] {
+					continue
+				}
+				tagKey := fmt.Sprintf("%s:basic:tag:%s", prefix, tag)
+				pipe.LRem(ctx, tagKey, 0, article.ID)
+				pipe.LPush(ctx, tagKey, article.ID)
+				pipe.ZAdd(ctx, fmt.Sprintf("%s:tag:dict", prefix), redis.Z{Score: 0, Member: tag})
+				pipe.ZIncrBy(ctx, fmt.Sprintf("%s:tag:popular", prefix), 1, tag)
+			}
+			for tag := range oldTags {
+				if newTags[tag] {
+					continue
+				}
+				pipe.LRem(ctx, fmt.Sprintf("%s:basic:tag:%s", prefix, tag), 0, article.ID)
+				pipe.ZIncrBy(ctx, fmt.Sprintf("%s:tag:popular", prefix), -1, tag)
+			}
+			return nil
+		})
+		return err
+	}
+
+	// 点赞会修改同一个哈希表，WATCH 冲突时重试几次，仍然失败时由发件箱稍后重试
+	var err error
+	for i := 0; i < 3; i++ {
+		if err = a.client.Watch(ctx, sync, mapKey); !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ArticleCacheRepository.SyncBasicArticle err: %w", err)
+	}
+	return nil
+}
+
+// hotKindKey 返回某个类型的热门文章有序集合，kind 为空时返回全站的有序集合
+func hotKindKey(kind string) string {
+	if kind == "" {
+		return hotKey
+	}
+	return hotKey + ":" + kind
+}
+
+// refreshHotScore 按基本文章哈希表中的计数重新计算文章热度，写入全站和所属类型的热门文章有序集合，归档的文章移出排行
+// 写入后在同一个管道中把有序集合裁剪到热度最高的 hotSize 篇，与 RebuildHotRanking 保持一致，排行不会在两次重算之间无限增长
+// 哈希表不存在或缺少发布时间时不处理，由定时重算补上
+func (a *ArticleCacheRepository) refreshHotScore(ctx context.Context, articleID int, hotSize int) error {
+	mapKey := fmt.Sprintf("%s:basic:map:%d", prefix, articleID)
+	values, err := a.client.HMGet(ctx, mapKey, "kind", "create_at", "like", "comment", "view", "archived").Result()
+	if err != nil {
+		return err
+	}
+	kind, _ := values[0].(string)
+	createAtStr, _ := values[1].(string)
+	createAt, err := strconv.ParseInt(createAtStr, 10, 64)
+	if kind == "" || err != nil {
+		return nil
+	}
+	if archived, _ := values[5].(string); archived == "1" {
+		// 归档的文章移出热门排行
+		pipe := a.client.Pipeline()
+		pipe.ZRem(ctx, hotKindKey(""), articleID)
+		pipe.ZRem(ctx, hotKindKey(kind), articleID)
+		_, err = pipe.Exec(ctx)
+		return err
+	}
+	counts := make([]int, 3)
+	for i, value := range values[2:5] {
+		if str, ok := value.(string); ok {
+			counts[i], _ = strconv.Atoi(str)
+		}
+	}
+	score := utils.HotScore(counts[0], counts[1], counts[2], time.Since(time.Unix(createAt, 0)))
+	pipe := a.client.Pipeline()
+	for _, key := range []string{hotKindKey(""), hotKindKey(kind)} {
+		pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: articleID})
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-hotSize-1))
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RefreshHotScore 按缓存中的计数重新计算一篇文章的热度，hotSize 为热门排行保留的文章数
+func (a *ArticleCacheRepository) RefreshHotScore(articleID int, hotSize int) error {
+	if err := a.refreshHotScore(context.Background(), articleID, hotSize); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RefreshHotScore err: %w", err)
+	}
+	return nil
+}
+
+// GetHotCounts 批量获取基本文章哈希表中的点赞数、评论数与浏览数，不在缓存中的文章不在结果中
+func (a *ArticleCacheRepository) GetHotCounts(articleIDs []int) (map[int]*article_model.HotCounts, error) {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(articleIDs))
+	for i, id := range articleIDs {
+		cmds[i] = pipe.HMGet(ctx, fmt.Sprintf("%s:basic:map:%d", prefix, id), "like", "comment", "view")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetHotCounts err: %w", err)
+	}
+	counts := make(map[int]*article_model.HotCounts, len(articleIDs))
+	for i, cmd := range cmds {
+		values := cmd.Val()
+		like, ok := values[0].(string)
+		if !ok {
+			continue
+		}
+		hotCounts := &article_model.HotCounts{}
+		hotCounts.Like, _ = strconv.Atoi(like)
+		if comment, ok := values[1].(string); ok {
+			hotCounts.Comment, _ = strconv.Atoi(comment)
+		}
+		if view, ok := values[2].(string); ok {
+			hotCounts.View, _ = strconv.Atoi(view)
+		}
+		counts[articleIDs[i]] = hotCounts
+	}
+	return counts, nil
+}
+
+// RebuildHotRanking 用重新计算的热度替换全站和各类型的热门文章有序集合，每个有序集合只保留热度最高的 size 篇
+// 新的有序集合先写入临时键再整体替换，重算期间读取不受影响
+func (a *ArticleCacheRepository) RebuildHotRanking(articles []*article_model.HotArticle, kinds []string, size int) error {
+	ctx := context.Background()
+	members := map[string][]redis.Z{hotKindKey(""): nil}
+	for _, kind := range kinds {
+		members[hotKindKey(kind)] = nil
+	}
+	for _, article := range articles {
+		z := redis.Z{Score: article.Hot, Member: article.ID}
+		members[hotKindKey("")] = append(members[hotKindKey("")], z)
+		members[hotKindKey(article.Kind)] = append(members[hotKindKey(article.Kind)], z)
+	}
+
+	pipe := a.client.TxPipeline()
+	for key, zs := range members {
+		if len(zs) == 0 {
+			pipe.Del(ctx, key)
+			continue
+		}
+		tmpKey := key + rebuildingSuffix
+		pipe.Del(ctx, tmpKey)
+		pipe.ZAdd(ctx, tmpKey, zs...)
+		pipe.ZRemRangeByRank(ctx, tmpKey, 0, int64(-size-1))
+		pipe.Rename(ctx, tmpKey, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RebuildHotRanking err: %w", err)
+	}
+	return nil
+}
+
+// GetHotArticles 按热度从高到低分页获取热门文章，kind 为空时获取全站排行，返回排行中的文章总数
+func (a *ArticleCacheRepository) GetHotArticles(kind string, offset int, limit int) ([]*article_model.HotArticle, int64, error) {
+	ctx := context.Background()
+	key := hotKindKey(kind)
+	total, err := a.client.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("ArticleCacheRepository.GetHotArticles err: %w", err)
+	}
+	results, err := a.client.ZRevRangeWithScores(ctx, key, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("ArticleCacheRepository.GetHotArticles err: %w", err)
+	}
+	ids := make([]int, 0, len(results))
+	for _, result := range results {
+		id, err := strconv.Atoi(result.Member.(string))
+		if err != nil {
+			return nil, 0, fmt.Errorf("ArticleCacheRepository.GetHotArticles err: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	basicArticles, err := a.getBasicArticles(ctx, ids)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ArticleCacheRepository.GetHotArticles err: %w", err)
+	}
+	articles := make([]*article_model.HotArticle, 0, len(results))
+	for i, result := range results {
+		if basicArticle, ok := basicArticles[ids[i]]; ok {
+			articles = append(articles, &article_model.HotArticle{BasicArticle: basicArticle, Hot: result.Score})
+		}
+	}
+	return articles, total, nil
+}
+
+// favoriteKey 返回用户收藏列表的有序集合，成员为文章 ID，分数为收藏时间的秒级时间戳
+func favoriteKey(userID int) string {
+	return fmt.Sprintf("%s:favorite:user:%d", prefix, userID)
+}
+
+// favoriteKeys 返回用户收藏列表的有序集合、没有收藏时的空标记、以及收藏列表的版本号
+// 空标记存在时表示用户没有收藏，不必查询 MySQL；每次收藏或取消收藏都会增加版本号，
+// 由 MySQL 加载收藏列表期间版本号发生变化时不写入缓存，避免用加载前读到的旧列表覆盖新的收藏
+func favoriteKeys(userID int) []string {
+	key := favoriteKey(userID)
+	return []string{key, key + ":empty", key + ":version"}
+}
+
+// setFavoriteCountScript 只在基本文章哈希表存在时写入收藏数，避免写入残缺的文章
+var setFavoriteCountScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 0
+end
+redis.call('HSET', KEYS[1], 'favorite', ARGV[1])
+return 1
+`)
+
+// SetFavoriteCount 设置基本文章哈希表中的收藏数，哈希表不存在时不做处理
+func (a *ArticleCacheRepository) SetFavoriteCount(articleID int, count int) error {
+	ctx := context.Background()
+	basicKey := fmt.Sprintf("%s:basic:map:%d", prefix, articleID)
+	if err := setFavoriteCountScript.Run(ctx, a.client, []string{basicKey}, count).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.SetFavoriteCount err: %w", err)
+	}
+	return nil
+}
+
+// changeFavoriteScript 增加收藏列表的版本号，只在收藏列表已缓存时修改，避免只含最近几次收藏的残缺列表被当作完整列表
+// KEYS 为 favoriteKeys，ARGV[1] 为文章 ID，ARGV[2] 为收藏时间，为空时表示取消收藏，ARGV[3] 为缓存时长的秒数
+// 已缓存为没有收藏的用户收藏时删除空标记并创建收藏列表，取消最后一个收藏时写入空标记
+var changeFavoriteScript = redis.NewScript(`
+redis.call('INCR', KEYS[3])
+redis.call('EXPIRE', KEYS[3], ARGV[3])
+if ARGV[2] ~= '' and redis.call('EXISTS', KEYS[2]) == 1 then
+    redis.call('DEL', KEYS[2])
+    redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+    redis.call('EXPIRE', KEYS[1], ARGV[3])
+    return 1
+end
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 0
+end
+if ARGV[2] == '' then
+    redis.call('ZREM', KEYS[1], ARGV[1])
+    if redis.call('EXISTS', KEYS[1]) == 0 then
+        redis.call('SET', KEYS[2], 1, 'EX', ARGV[3])
+    end
+else
+    redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+end
+return 1
+`)
+
+// AddFavorite 把文章加入已缓存的收藏列表
+func (a *ArticleCacheRepository) AddFavorite(userID int, articleID int, favoriteAt time.Time, expiration time.Duration) error {
+	ctx := context.Background()
+	if err := changeFavoriteScript.Run(ctx, a.client, favoriteKeys(userID), articleID, favoriteAt.Unix(), int64(expiration.Seconds())).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.AddFavorite err: %w", err)
+	}
+	return nil
+}
+
+// RemoveFavorite 把文章移出已缓存的收藏列表
+func (a *ArticleCacheRepository) RemoveFavorite(userID int, articleID int, expiration time.Duration) error {
+	ctx := context.Background()
+	if err := changeFavoriteScript.Run(ctx, a.client, favoriteKeys(userID), articleID, "", int64(expiration.Seconds())).Err(); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RemoveFavorite err: %w", err)
+	}
+	return nil
+}
+
+// DeleteFavorites 删除用户的收藏列表缓存并增加版本号，下次读取时由 MySQL 重新加载
+func (a *ArticleCacheRepository) DeleteFavorites(userID int, expiration time.Duration) error {
+	ctx := context.Background()
+	keys := favoriteKeys(userID)
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys[0], keys[1])
+		pipe.Incr(ctx, keys[2])
+		pipe.Expire(ctx, keys[2], expiration)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ArticleCacheRepository.DeleteFavorites err: %w", err)
+	}
+	return nil
+}
+
+// GetFavoriteVersion 获取收藏列表的版本号，由 MySQL 加载收藏列表前调用，传给 LoadFavorites
+func (a *ArticleCacheRepository) GetFavoriteVersion(userID int) (string, error) {
+	ctx := context.Background()
+	version, err := a.client.Get(ctx, favoriteKeys(userID)[2]).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("ArticleCacheRepository.GetFavoriteVersion err: %w", err)
+	}
+	return version, nil
+}
+
+// loadFavoritesScript 版本号仍为 ARGV[1] 时重建收藏列表，没有收藏时写入空标记，返回是否已写入
+// KEYS 为 favoriteKeys，ARGV[2] 为缓存时长的秒数，之后依次为收藏时间与文章 ID
+var loadFavoritesScript = redis.NewScript(`
+if (redis.call('GET', KEYS[3]) or '') ~= ARGV[1] then
+    return 0
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+if #ARGV > 2 then
+    for i = 3, #ARGV, 2 do
+        redis.call('ZADD', KEYS[1], ARGV[i], ARGV[i + 1])
+    end
+    redis.call('EXPIRE', KEYS[1], ARGV[2])
+else
+    redis.call('SET', KEYS[2], 1, 'EX', ARGV[2])
+end
+return 1
+`)
+
+// LoadFavorites 用 MySQL 中的收藏关系重建用户的收藏列表缓存，没有收藏时写入空标记
+// version 为加载前由 GetFavoriteVersion 获取的版本号，加载期间有新的收藏或取消收藏时不写入，返回是否已写入
+func (a *ArticleCacheRepository) LoadFavorites(userID int, favorites []*article_model.ArticleFavorite, version string, expiration time.Duration) (bool, error) {
+	ctx := context.Background()
+	args := make([]interface{}, 0, len(favorites)*2+2)
+	args = append(args, version, int64(expiration.Seconds()))
+	for _, favorite := range favorites {
+		args = append(args, favorite.CreateAt.Unix(), favorite.ArticleID)
+	}
+	loaded, err := loadFavoritesScript.Run(ctx, a.client, favoriteKeys(userID), args...).Bool()
+	if err != nil {
+		return false, fmt.Errorf("ArticleCacheRepository.LoadFavorites err: %w", err)
+	}
+	return loaded, nil
+}
+
+// GetFavorites 按收藏时间从新到旧分页获取收藏列表，返回文章 ID 与收藏时间以及收藏总数
+// 收藏列表未缓存时 cached 为 false，已缓存为没有收藏时 cached 为 true，总数为 0
+func (a *ArticleCacheRepository) GetFavorites(userID int, offset int, limit int) (favorites []*article_model.ArticleFavorite, total int64, cached bool, err error) {
+	ctx := context.Background()
+	keys := favoriteKeys(userID)
+	pipe := a.client.Pipeline()
+	existsCmd := pipe.Exists(ctx, keys[0], keys[1])
+	totalCmd := pipe.ZCard(ctx, keys[0])
+	rangeCmd := pipe.ZRevRangeWithScores(ctx, keys[0], int64(offset), int64(offset+limit-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, 0, false, fmt.Errorf("ArticleCacheRepository.GetFavorites err: %w", err)
+	}
+	if existsCmd.Val() == 0 {
+		return nil, 0, false, nil
+	}
+	for _, z := range rangeCmd.Val() {
+		articleID, err := strconv.Atoi(z.Member.(string))
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("ArticleCacheRepository.GetFavorites err: %w", err)
+		}
+		favorites = append(favorites, &article_model.ArticleFavorite{
+			ArticleID: articleID,
+			UserID:    userID,
+			CreateAt:  time.Unix(int64(z.Score), 0),
+		})
+	}
+	return favorites, totalCmd.Val(), true, nil
+}
+
+// similarKey 返回文章的相似文章有序集合，分数为相似度
+func similarKey(articleID int) string {
+	return fmt.Sprintf("%s:similar:%d", prefix, articleID)
+}
+
+// readKey 返回用户最近浏览的文章有序集合，分数为浏览时间的秒级时间戳
+func readKey(userID int) string {
+	return fmt.Sprintf("%s:read:%d", prefix, userID)
+}
+
+// AddReadHistory 记录用户浏览过文章，只保留最近浏览的 size 篇，用户 ttl 内没有浏览时整个记录过期
+func (a *ArticleCacheRepository) AddReadHistory(userID int, articleID int, now time.Time, size int, ttl time.Duration) error {
+	ctx := context.Background()
+	key := readKey(userID)
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.Unix()), Member: articleID})
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-size-1))
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ArticleCacheRepository.AddReadHistory err: %w", err)
+	}
+	return nil
+}
+
+// GetReadArticles 批量判断用户最近是否浏览过文章
+func (a *ArticleCacheRepository) GetReadArticles(userID int, articleIDs []int) (map[int]bool, error) {
+	read := make(map[int]bool, len(articleIDs))
+	if len(articleIDs) == 0 {
+		return read, nil
+	}
+	ctx := context.Background()
+	members := make([]string, len(articleIDs))
+	for i, id := range articleIDs {
+		members[i] = strconv.Itoa(id)
+	}
+	// 不在有序集合中的成员分数为 0，浏览时间不会为 0
+	scores, err := a.client.ZMScore(ctx, readKey(userID), members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetReadArticles err: %w", err)
+	}
+	for i, score := range scores {
+		read[articleIDs[i]] = score > 0
+	}
+	return read, nil
+}
+
+// GetLikeSets 批量获取文章的点赞用户，没有点赞的文章不在结果中
+func (a *ArticleCacheRepository) GetLikeSets(articleIDs []int) (map[int][]int, error) {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(articleIDs))
+	for i, id := range articleIDs {
+		cmds[i] = pipe.SMembers(ctx, fmt.Sprintf("%s:like:%d", prefix, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetLikeSets err: %w", err)
+	}
+	sets := make(map[int][]int, len(articleIDs))
+	for i, cmd := range cmds {
+		for _, member := range cmd.Val() {
+			userID, err := strconv.Atoi(member)
+			if err != nil {
+				return nil, fmt.Errorf("ArticleCacheRepository.GetLikeSets err: %w", err)
+			}
+			sets[articleIDs[i]] = append(sets[articleIDs[i]], userID)
+		}
+	}
+	return sets, nil
+}
+
+// SaveSimilarArticles 覆盖 articleIDs 中每篇文章的相似文章，没有相似文章的文章删除原有数据
+func (a *ArticleCacheRepository) SaveSimilarArticles(articleIDs []int, similar map[int][]*article_model.SimilarArticle) error {
+	ctx := context.Background()
+	_, err := a.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range articleIDs {
+			key := similarKey(id)
+			pipe.Del(ctx, key)
+			if len(similar[id]) == 0 {
+				continue
+			}
+			members := make([]redis.Z, len(similar[id]))
+			for i, article := range similar[id] {
+				members[i] = redis.Z{Score: article.Score, Member: article.ArticleID}
+			}
+			pipe.ZAdd(ctx, key, members...)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ArticleCacheRepository.SaveSimilarArticles err: %w", err)
+	}
+	return nil
+}
+
+// GetSimilarArticles 批量获取文章的相似文章，按相似度从高到低排列
+func (a *ArticleCacheRepository) GetSimilarArticles(articleIDs []int) (map[int][]*article_model.SimilarArticle, error) {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	cmds := make([]*redis.ZSliceCmd, len(articleIDs))
+	for i, id := range articleIDs {
+		cmds[i] = pipe.ZRevRangeWithScores(ctx, similarKey(id), 0, -1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetSimilarArticles err: %w", err)
+	}
+	similar := make(map[int][]*article_model.SimilarArticle, len(articleIDs))
+	for i, cmd := range cmds {
+		for _, z := range cmd.Val() {
+			id, err := strconv.Atoi(z.Member.(string))
+			if err != nil {
+				return nil, fmt.Errorf("ArticleCacheRepository.GetSimilarArticles err: %w", err)
+			}
+			similar[articleIDs[i]] = append(similar[articleIDs[i]], &article_model.SimilarArticle{ArticleID: id, Score: z.Score})
+		}
+	}
+	return similar, nil
+}
+
+// GetListArticleIDs 获取类型列表与标签列表中最新的 n 篇文章的 ID，按列表顺序排列，可能重复
+func (a *ArticleCacheRepository) GetListArticleIDs(kinds []string, tags []string, n int) ([]int, error) {
+	ctx := context.Background()
+	pipe := a.client.Pipeline()
+	var cmds []*redis.StringSliceCmd
+	for _, kind := range kinds {
+		cmds = append(cmds, pipe.LRange(ctx, fmt.Sprintf("%s:basic:list:%s", prefix, kind), 0, int64(n-1)))
+	}
+	for _, tag := range tags {
+		cmds = append(cmds, pipe.LRange(ctx, fmt.Sprintf("%s:basic:tag:%s", prefix, tag), 0, int64(n-1)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetListArticleIDs err: %w", err)
+	}
+	var ids []int
+	for _, cmd := range cmds {
+		for _, member := range cmd.Val() {
+			id, err := strconv.Atoi(member)
+			if err != nil {
+				return nil, fmt.Errorf("ArticleCacheRepository.GetListArticleIDs err: %w", err)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// pinnedKey 返回某个类型的置顶文章有序集合，成员为文章 ID，分数为置顶优先级
+func pinnedKey(kind string) string {
+	return fmt.Sprintf("%s:pinned:%s", prefix, kind)
+}
+
+// GetPinnedArticles 获取某个类型在 now 时仍然有效的置顶文章，按优先级从高到低、同优先级新文章在前排列
+// 已过期但还没有被定时任务取消的置顶不在结果中
+func (a *ArticleCacheRepository) GetPinnedArticles(kind string, now time.Time) ([]*article_model.BasicArticle, error) {
+	ctx := context.Background()
+	members, err := a.client.ZRange(ctx, pinnedKey(kind), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetPinnedArticles err: %w", err)
+	}
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.Atoi(member)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleCacheRepository.GetPinnedArticles err: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	basicArticles, err := a.getBasicArticles(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleCacheRepository.GetPinnedArticles err: %w", err)
+	}
+	articles := make([]*article_model.BasicArticle, 0, len(basicArticles))
+	for _, article := range basicArticles {
+		if article.PinPriority <= 0 || article.Archived || (article.PinExpireAt != nil && !article.PinExpireAt.After(now)) {
+			continue
+		}
+		articles = append(articles, article)
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		if articles[i].PinPriority != articles[j].PinPriority {
+			return articles[i].PinPriority > articles[j].PinPriority
+		}
+		return articles[i].ID > articles[j].ID
+	})
+	return articles, nil
+}
+
+// RebuildPinned 用重建时读取到的置顶文章替换各类型的置顶文章集合
+func (a *ArticleCacheRepository) RebuildPinned(articles []*article_model.BasicArticle, kinds []string) error {
+	ctx := context.Background()
+	pipe := a.client.TxPipeline()
+	for _, kind := range kinds {
+		pipe.Del(ctx, pinnedKey(kind))
+	}
+	for _, article := range articles {
+		pipe.ZAdd(ctx, pinnedKey(article.Kind), redis.Z{Score: float64(article.PinPriority), Member: article.ID})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("ArticleCacheRepository.RebuildPinned err: %w", err)
 	}
 	return nil
 }
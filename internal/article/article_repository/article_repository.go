@@ -4,7 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"huancuilou/internal/article/article_model"
+	"huancuilou/internal/comment/comment_model"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type ArticleRepository struct {
@@ -34,53 +40,690 @@ func (a *ArticleRepository) GetArticleByID(id int) (*article_model.ArticleWithNo
 		}
 		return nil, result.Error
 	}
+	tags, err := a.GetTagsByArticleID(id)
+	if err != nil {
+		return nil, err
+	}
 	return &article_model.ArticleWithNoLike{
-		ID:        article.ID,
-		Title:     article.Title,
-		Content:   article.Content,
-		Kind:      article.Kind,
-		ManagerID: article.ManagerID,
-		CreateAt:  article.CreateAt,
+		ID:              article.ID,
+		Title:           article.Title,
+		Content:         article.Content,
+		Format:          article.Format,
+		Kind:            article.Kind,
+		ManagerID:       article.ManagerID,
+		CreateAt:        article.CreateAt,
+		View:            article.View,
+		Tags:            tags,
+		PinPriority:     article.PinPriority,
+		PinExpireAt:     article.PinExpireAt,
+		ArchiveOnExpire: article.ArchiveOnExpire,
+		Archived:        article.Archived,
+		RequireAck:      article.RequireAck,
 	}, nil
 }
 
-func (a *ArticleRepository) WriteLikesToMySQL(tx *gorm.DB, slice []int) error {
-	result := tx.Model(&article_model.Article{}).Where("id = ?", slice[0]).Update("like", slice[1])
+// BatchUpdateLikes 批量更新文章点赞数，每 batchSize 篇文章合并为一条 UPDATE ... CASE 语句
+func (a *ArticleRepository) BatchUpdateLikes(tx *gorm.DB, likes map[int]int, batchSize int) error {
+	if err := batchUpdateCase(tx, "`like`", likes, batchSize, false); err != nil {
+		return fmt.Errorf("ArticleRepository.BatchUpdateLikes err: %w", err)
+	}
+	return nil
+}
+
+// BatchUpdateViews 批量更新文章浏览次数与独立访客数
+// 独立访客数只增不减，Redis 中的 HyperLogLog 丢失后重新计数时不会覆盖 MySQL 中更大的值
+func (a *ArticleRepository) BatchUpdateViews(tx *gorm.DB, views map[int]int, uniqueViews map[int]int, batchSize int) error {
+	if err := batchUpdateCase(tx, "view", views, batchSize, false); err != nil {
+		return fmt.Errorf("ArticleRepository.BatchUpdateViews err: %w", err)
+	}
+	if err := batchUpdateCase(tx, "unique_view", uniqueViews, batchSize, true); err != nil {
+		return fmt.Errorf("ArticleRepository.BatchUpdateViews err: %w", err)
+	}
+	return nil
+}
+
+// batchUpdateCase 将 values 中每篇文章的值写入 column，每 batchSize 篇文章合并为一条 UPDATE ... CASE 语句
+// keepMax 为 true 时只在新值更大时更新
+func batchUpdateCase(tx *gorm.DB, column string, values map[int]int, batchSize int, keepMax bool) error {
+	ids := make([]int, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		var builder strings.Builder
+		args := make([]interface{}, 0, len(batch)*2+1)
+		builder.WriteString("CASE id")
+		for _, id := range batch {
+			builder.WriteString(" WHEN ? THEN ?")
+			args = append(args, id, values[id])
+		}
+		builder.WriteString(" END")
+		expr := builder.String()
+		if keepMax {
+			expr = "GREATEST(" + column + ", " + expr + ")"
+		}
+		args = append(args, batch)
+		if err := tx.Exec("UPDATE article SET "+column+" = "+expr+" WHERE id IN ?", args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *ArticleRepository) UpdateArticle(tx *gorm.DB, article *article_model.Article) error {
+	updates := map[string]interface{}{
+		"title":     article.Title,
+		"content":   article.Content,
+		"update_at": time.Now(),
+	}
+	// 未指定正文格式时保留原有格式
+	if article.Format != "" {
+		updates["format"] = article.Format
+	}
+	result := tx.Model(&article_model.Article{}).Where("id = ?", article.ID).Updates(updates)
 	if result.Error != nil {
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		// 内容没有变化时 MySQL 也返回 0 行，再确认一次文章是否存在
+		var count int64
+		if err := tx.Model(&article_model.Article{}).Where("id = ?", article.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("未找到要更新的文章记录，ID: %d", article.ID)
+		}
+	}
 	return nil
 }
 
-func (a *ArticleRepository) UpdateArticle(article *article_model.Article) (*article_model.Article, error) {
-	newArticle := article_model.Article{}
+// UpdatePin 设置文章的置顶状态，priority 为 0 时取消置顶；置顶的文章同时取消归档
+func (a *ArticleRepository) UpdatePin(tx *gorm.DB, articleID int, pin *article_model.PinRequest) error {
+	updates := map[string]interface{}{
+		"pin_priority":      pin.Priority,
+		"pin_expire_at":     pin.ExpireAt,
+		"archive_on_expire": pin.ArchiveOnExpire,
+	}
+	if pin.Priority > 0 {
+		// 置顶可能取消归档，文章重新出现在订阅中
+		updates["archived"] = false
+		updates["update_at"] = time.Now()
+	}
+	var count int64
+	if err := tx.Model(&article_model.Article{}).Where("id = ?", articleID).Count(&count).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.UpdatePin err: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("未找到要置顶的文章记录，ID: %d", articleID)
+	}
+	if err := tx.Model(&article_model.Article{}).Where("id = ?", articleID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.UpdatePin err: %w", err)
+	}
+	return nil
+}
 
-	result := a.DB.Model(article).Where("id =?", article.ID).Updates(map[string]interface{}{
-		"title":   article.Title,
-		"content": article.Content,
-		"like":    article.Like,
-	})
+// GetExpiredPins 获取置顶过期时间不晚于 now 的至多 limit 篇文章
+func (a *ArticleRepository) GetExpiredPins(now time.Time, limit int) ([]*article_model.ExpiredPin, error) {
+	var pins []*article_model.ExpiredPin
+	if err := a.DB.Model(&article_model.Article{}).Select("id", "archive_on_expire").
+		Where("pin_priority > 0 AND pin_expire_at IS NOT NULL AND pin_expire_at <= ?", now).
+		Order("id").Limit(limit).Scan(&pins).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetExpiredPins err: %w", err)
+	}
+	return pins, nil
+}
 
+// ExpirePins 取消置顶过期的文章，设置了过期后归档的文章同时归档并更新修改时间，archive_on_expire 保留到下一次置顶时覆盖
+// 条件中再次检查过期时间，并发修改过置顶的文章不会被误取消，返回实际取消置顶的文章数
+func (a *ArticleRepository) ExpirePins(tx *gorm.DB, articleIDs []int, now time.Time) (int, error) {
+	result := tx.Model(&article_model.Article{}).
+		Where("id IN ? AND pin_priority > 0 AND pin_expire_at <= ?", articleIDs, now).
+		Updates(map[string]interface{}{
+			"archived":      gorm.Expr("archived OR archive_on_expire"),
+			"pin_priority":  0,
+			"pin_expire_at": nil,
+			"update_at":     gorm.Expr("IF(archive_on_expire, ?, update_at)", now),
+		})
 	if result.Error != nil {
-		return nil, result.Error
+		return 0, fmt.Errorf("ArticleRepository.ExpirePins err: %w", result.Error)
 	}
+	return int(result.RowsAffected), nil
+}
 
-	if result.RowsAffected == 0 {
-		return nil, fmt.Errorf("未找到要更新的文章记录，ID: %d", article.ID)
+// SetRequireAck 设置文章是否需要居民确认已读
+func (a *ArticleRepository) SetRequireAck(tx *gorm.DB, articleID int, requireAck bool) error {
+	var count int64
+	if err := tx.Model(&article_model.Article{}).Where("id = ?", articleID).Count(&count).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.SetRequireAck err: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("未找到要修改的文章记录，ID: %d", articleID)
+	}
+	if err := tx.Model(&article_model.Article{}).Where("id = ?", articleID).Update("require_ack", requireAck).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.SetRequireAck err: %w", err)
 	}
+	return nil
+}
 
-	result = a.DB.First(&newArticle, article.ID)
+// EnsureSearchIndex 确保文章表上存在基于 ngram 分词的全文索引
+// MySQL 的 FULLTEXT 索引会在 AddArticle/UpdateArticle 写入时由数据库自动维护，这里只负责首次创建
+func (a *ArticleRepository) EnsureSearchIndex() error {
+	indexes := map[string]string{
+		"ft_article_title":         "title",
+		"ft_article_title_content": "title, content",
+	}
+	for name, columns := range indexes {
+		var count int64
+		result := a.DB.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+			article_model.Article{}.TableName(), name).Scan(&count)
+		if result.Error != nil {
+			return fmt.Errorf("ArticleRepository.EnsureSearchIndex err: %w", result.Error)
+		}
+		if count > 0 {
+			continue
+		}
+		sql := fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT INDEX %s (%s) WITH PARSER ngram", article_model.Article{}.TableName(), name, columns)
+		if err := a.DB.Exec(sql).Error; err != nil {
+			return fmt.Errorf("ArticleRepository.EnsureSearchIndex err: %w", err)
+		}
+	}
+	return nil
+}
+
+// EnsureUpdateAtColumn 确保文章表上存在 update_at 列，修改正文、归档或取消归档时更新，作为订阅的 Last-Modified
+// 已有的文章 update_at 为空，视为没有修改过
+func (a *ArticleRepository) EnsureUpdateAtColumn() error {
+	var count int64
+	result := a.DB.Raw("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+		article_model.Article{}.TableName(), "update_at").Scan(&count)
 	if result.Error != nil {
-		return nil, result.Error
+		return fmt.Errorf("ArticleRepository.EnsureUpdateAtColumn err: %w", result.Error)
+	}
+	if count > 0 {
+		return nil
+	}
+	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN update_at DATETIME NULL DEFAULT NULL", article_model.Article{}.TableName())
+	if err := a.DB.Exec(sql).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.EnsureUpdateAtColumn err: %w", err)
 	}
+	return nil
+}
 
-	return &article_model.Article{
-		ID:        newArticle.ID,
-		Title:     newArticle.Title,
-		Content:   newArticle.Content,
-		Kind:      newArticle.Kind,
-		Like:      newArticle.Like,
-		ManagerID: newArticle.ManagerID,
-		CreateAt:  newArticle.CreateAt,
-	}, nil
+// EnsureAckIndex 确保确认已读表上存在 (article_id, user_id) 唯一索引，AddAck 依赖它忽略重复确认
+// 创建索引前先删除同一用户对同一文章的重复记录，只保留最早的一条
+func (a *ArticleRepository) EnsureAckIndex() error {
+	const name = "uk_article_ack_article_user"
+	table := article_model.ArticleAck{}.TableName()
+	var count int64
+	result := a.DB.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+		table, name).Scan(&count)
+	if result.Error != nil {
+		return fmt.Errorf("ArticleRepository.EnsureAckIndex err: %w", result.Error)
+	}
+	if count > 0 {
+		return nil
+	}
+	dedup := fmt.Sprintf("DELETE a1 FROM %[1]s AS a1 JOIN %[1]s AS a2 ON a1.article_id = a2.article_id AND a1.user_id = a2.user_id AND a1.id > a2.id", table)
+	if err := a.DB.Exec(dedup).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.EnsureAckIndex err: %w", err)
+	}
+	sql := fmt.Sprintf("ALTER TABLE %s ADD UNIQUE INDEX %s (article_id, user_id)", table, name)
+	if err := a.DB.Exec(sql).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.EnsureAckIndex err: %w", err)
+	}
+	return nil
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符，关键词中的 % 与 _ 按字面匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchArticles 按关键词全文检索文章，标题命中的权重是正文的两倍
+// 关键词不足一个 ngram 分词长度（两个字）时全文索引无法命中，退化为 LIKE 查询；已归档的文章不会被搜索到
+func (a *ArticleRepository) SearchArticles(keyword string, kinds []string, offset int, limit int) ([]*article_model.SearchArticle, int64, error) {
+	var articles []*article_model.SearchArticle
+	var total int64
+
+	if utf8.RuneCountInString(keyword) < 2 {
+		pattern := "%" + likeEscaper.Replace(keyword) + "%"
+		query := a.DB.Model(&article_model.Article{}).Where("archived = 0 AND (title LIKE ? OR content LIKE ?)", pattern, pattern)
+		if len(kinds) > 0 {
+			query = query.Where("kind IN ?", kinds)
+		}
+		if err := query.Count(&total).Error; err != nil {
+			return nil, 0, fmt.Errorf("ArticleRepository.SearchArticles err: %w", err)
+		}
+		// LIKE 查询没有相关度，score 固定为 0
+		if err := query.Select("id, title, content, format, kind, `like`, manager_id, create_at, 0 AS score").
+			Order("id DESC").Offset(offset).Limit(limit).Scan(&articles).Error; err != nil {
+			return nil, 0, fmt.Errorf("ArticleRepository.SearchArticles err: %w", err)
+		}
+		return articles, total, nil
+	}
+
+	match := "MATCH(title, content) AGAINST(? IN NATURAL LANGUAGE MODE)"
+	where := match + " AND archived = 0"
+	args := []interface{}{keyword}
+	if len(kinds) > 0 {
+		where += " AND kind IN ?"
+		args = append(args, kinds)
+	}
+
+	if err := a.DB.Raw("SELECT COUNT(*) FROM article WHERE "+where, args...).Scan(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("ArticleRepository.SearchArticles err: %w", err)
+	}
+	if total == 0 {
+		return articles, 0, nil
+	}
+
+	sql := "SELECT id, title, content, format, kind, `like`, manager_id, create_at, " +
+		"MATCH(title) AGAINST(? IN NATURAL LANGUAGE MODE) * 2 + " + match + " AS score " +
+		"FROM article WHERE " + where + " ORDER BY score DESC, id DESC LIMIT ? OFFSET ?"
+	queryArgs := append([]interface{}{keyword, keyword}, args...)
+	queryArgs = append(queryArgs, limit, offset)
+	if err := a.DB.Raw(sql, queryArgs...).Scan(&articles).Error; err != nil {
+		return nil, 0, fmt.Errorf("ArticleRepository.SearchArticles err: %w", err)
+	}
+	return articles, total, nil
+}
+
+// AddTags 为文章添加标签，标签不存在时先创建
+func (a *ArticleRepository) AddTags(tx *gorm.DB, articleID int, names []string) error {
+	for _, name := range names {
+		var tag article_model.Tag
+		result := tx.Where(article_model.Tag{Name: name}).Attrs(article_model.Tag{CreateAt: time.Now()}).FirstOrCreate(&tag)
+		if result.Error != nil {
+			return fmt.Errorf("ArticleRepository.AddTags err: %w", result.Error)
+		}
+		if err := tx.Create(&article_model.ArticleTag{ArticleID: articleID, TagID: tag.ID}).Error; err != nil {
+			return fmt.Errorf("ArticleRepository.AddTags err: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplaceTags 用新的标签集合替换文章原有的标签
+func (a *ArticleRepository) ReplaceTags(tx *gorm.DB, articleID int, names []string) error {
+	if err := tx.Where("article_id = ?", articleID).Delete(&article_model.ArticleTag{}).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.ReplaceTags err: %w", err)
+	}
+	return a.AddTags(tx, articleID, names)
+}
+
+// GetTagsByArticleID 获取文章的所有标签名
+func (a *ArticleRepository) GetTagsByArticleID(articleID int) ([]string, error) {
+	tags := []string{}
+	result := a.DB.Table("article_tag").Select("tag.name").
+		Joins("JOIN tag ON tag.id = article_tag.tag_id").
+		Where("article_tag.article_id = ?", articleID).
+		Order("article_tag.id").Scan(&tags)
+	if result.Error != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetTagsByArticleID err: %w", result.Error)
+	}
+	return tags, nil
+}
+
+// AddLikeRecords 批量写入点赞关系，已存在的关系忽略，重复回写同一批事件时结果不变
+func (a *ArticleRepository) AddLikeRecords(tx *gorm.DB, likes []*article_model.ArticleLike) error {
+	if len(likes) == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&likes).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.AddLikeRecords err: %w", err)
+	}
+	return nil
+}
+
+// RemoveLikeRecords 批量删除点赞关系
+func (a *ArticleRepository) RemoveLikeRecords(tx *gorm.DB, likes []*article_model.ArticleLike) error {
+	if len(likes) == 0 {
+		return nil
+	}
+	pairs := make([][]interface{}, 0, len(likes))
+	for _, like := range likes {
+		pairs = append(pairs, []interface{}{like.ArticleID, like.UserID})
+	}
+	if err := tx.Where("(article_id, user_id) IN ?", pairs).Delete(&article_model.ArticleLike{}).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.RemoveLikeRecords err: %w", err)
+	}
+	return nil
+}
+
+// GetLikers 按点赞时间从新到旧分页获取点赞了文章的用户以及点赞总数
+func (a *ArticleRepository) GetLikers(articleID int, offset int, limit int) ([]*article_model.ArticleLiker, int64, error) {
+	var total int64
+	if err := a.DB.Model(&article_model.ArticleLike{}).Where("article_id = ?", articleID).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("ArticleRepository.GetLikers err: %w", err)
+	}
+	var likers []*article_model.ArticleLiker
+	result := a.DB.Table("article_like AS l").
+		Select("l.user_id, u.username AS user_name, u.avatar_url, l.create_at AS like_at").
+		Joins("JOIN `user` AS u ON u.id = l.user_id").
+		Where("l.article_id = ?", articleID).
+		Order("l.create_at DESC, l.id DESC").
+		Offset(offset).Limit(limit).
+		Scan(&likers)
+	if result.Error != nil {
+		return nil, 0, fmt.Errorf("ArticleRepository.GetLikers err: %w", result.Error)
+	}
+	return likers, total, nil
+}
+
+// GetArticleIDsByManager 按发布时间从新到旧获取管理员最近发布的 limit 篇未归档文章的 ID
+func (a *ArticleRepository) GetArticleIDsByManager(managerID int, limit int) ([]int, error) {
+	var ids []int
+	if err := a.DB.Model(&article_model.Article{}).Where("manager_id = ? AND archived = 0", managerID).
+		Order("id DESC").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetArticleIDsByManager err: %w", err)
+	}
+	return ids, nil
+}
+
+// GetLikedArticleIDs 按点赞时间从新到旧获取用户最近点赞的 limit 篇文章的 ID
+func (a *ArticleRepository) GetLikedArticleIDs(userID int, limit int) ([]int, error) {
+	var ids []int
+	if err := a.DB.Model(&article_model.ArticleLike{}).Where("user_id = ?", userID).
+		Order("create_at DESC, id DESC").Limit(limit).Pluck("article_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetLikedArticleIDs err: %w", err)
+	}
+	return ids, nil
+}
+
+// GetAllArticleIDs 获取所有文章的 ID
+func (a *ArticleRepository) GetAllArticleIDs() ([]int, error) {
+	var ids []int
+	if err := a.DB.Model(&article_model.Article{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetAllArticleIDs err: %w", err)
+	}
+	return ids, nil
+}
+
+// GetArticlesAfter 按 ID 升序分批获取文章，afterID 为上一批最后一篇文章的 ID
+func (a *ArticleRepository) GetArticlesAfter(afterID int, limit int) ([]*article_model.Article, error) {
+	var articles []*article_model.Article
+	if err := a.DB.Where("id > ?", afterID).Order("id").Limit(limit).Find(&articles).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetArticlesAfter err: %w", err)
+	}
+	return articles, nil
+}
+
+// GetHotSourcesAfter 按 ID 升序分批获取计算热度需要的字段，不读取正文，afterID 为上一批最后一篇文章的 ID
+func (a *ArticleRepository) GetHotSourcesAfter(afterID int, limit int) ([]*article_model.Article, error) {
+	var articles []*article_model.Article
+	if err := a.DB.Select("id, kind, `like`, create_at, archived").
+		Where("id > ?", afterID).Order("id").Limit(limit).Find(&articles).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetHotSourcesAfter err: %w", err)
+	}
+	return articles, nil
+}
+
+// GetLatestCreateAt 获取最新一篇文章的发布时间，没有文章时返回零值
+func (a *ArticleRepository) GetLatestCreateAt() (time.Time, error) {
+	var latest []time.Time
+	if err := a.DB.Model(&article_model.Article{}).Order("create_at DESC").Limit(1).Pluck("create_at", &latest).Error; err != nil {
+		return time.Time{}, fmt.Errorf("ArticleRepository.GetLatestCreateAt err: %w", err)
+	}
+	if len(latest) == 0 {
+		return time.Time{}, nil
+	}
+	return latest[0], nil
+}
+
+// GetLastModified 获取这些类型的文章（含已归档的文章）最后一次发布或修改的时间，没有文章时返回零值
+// 文章只会归档不会删除，订阅中文章的增加、修改与移出都会推后这个时间
+func (a *ArticleRepository) GetLastModified(kinds []string) (time.Time, error) {
+	var lastModified *time.Time
+	if err := a.DB.Model(&article_model.Article{}).Where("kind IN ?", kinds).
+		Select("MAX(GREATEST(create_at, COALESCE(update_at, create_at)))").Scan(&lastModified).Error; err != nil {
+		return time.Time{}, fmt.Errorf("ArticleRepository.GetLastModified err: %w", err)
+	}
+	if lastModified == nil {
+		return time.Time{}, nil
+	}
+	return *lastModified, nil
+}
+
+// IsManager 判断用户是否存在并且是管理员
+func (a *ArticleRepository) IsManager(userID int) (bool, error) {
+	var count int64
+	if err := a.DB.Table("`user`").Where("id = ? AND is_manager = 1", userID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("ArticleRepository.IsManager err: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetTagsByArticleIDs 批量获取文章的标签名，键为文章 ID
+func (a *ArticleRepository) GetTagsByArticleIDs(articleIDs []int) (map[int][]string, error) {
+	var rows []struct {
+		ArticleID int
+		Name      string
+	}
+	result := a.DB.Table("article_tag").Select("article_tag.article_id, tag.name").
+		Joins("JOIN tag ON tag.id = article_tag.tag_id").
+		Where("article_tag.article_id IN ?", articleIDs).
+		Order("article_tag.id").Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetTagsByArticleIDs err: %w", result.Error)
+	}
+	tags := make(map[int][]string, len(articleIDs))
+	for _, row := range rows {
+		tags[row.ArticleID] = append(tags[row.ArticleID], row.Name)
+	}
+	return tags, nil
+}
+
+// GetLikeUserIDsByArticleIDs 批量获取点赞了文章的用户 ID，键为文章 ID
+func (a *ArticleRepository) GetLikeUserIDsByArticleIDs(articleIDs []int) (map[int][]int, error) {
+	var likes []*article_model.ArticleLike
+	if err := a.DB.Where("article_id IN ?", articleIDs).Find(&likes).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetLikeUserIDsByArticleIDs err: %w", err)
+	}
+	userIDs := make(map[int][]int, len(articleIDs))
+	for _, like := range likes {
+		userIDs[like.ArticleID] = append(userIDs[like.ArticleID], like.UserID)
+	}
+	return userIDs, nil
+}
+
+// GetCommentCounts 批量获取文章的可见评论数（含回复），键为文章 ID，与缓存中评论数的计数口径一致
+func (a *ArticleRepository) GetCommentCounts(articleIDs []int) (map[int]int, error) {
+	var rows []struct {
+		ArticleID int
+		Count     int
+	}
+	result := a.DB.Model(&comment_model.Comment{}).Select("article_id, COUNT(*) AS count").
+		Where("article_id IN ? AND status = ?", articleIDs, comment_model.StatusNormal).
+		Group("article_id").Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetCommentCounts err: %w", result.Error)
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.ArticleID] = row.Count
+	}
+	return counts, nil
+}
+
+// AddFavorite 写入收藏关系，返回是否新增，已收藏时不做处理
+func (a *ArticleRepository) AddFavorite(tx *gorm.DB, favorite *article_model.ArticleFavorite) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(favorite)
+	if result.Error != nil {
+		return false, fmt.Errorf("ArticleRepository.AddFavorite err: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RemoveFavorite 删除收藏关系，返回是否删除了记录
+func (a *ArticleRepository) RemoveFavorite(tx *gorm.DB, articleID int, userID int) (bool, error) {
+	result := tx.Where("article_id = ? AND user_id = ?", articleID, userID).Delete(&article_model.ArticleFavorite{})
+	if result.Error != nil {
+		return false, fmt.Errorf("ArticleRepository.RemoveFavorite err: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetFavoriteCounts 批量获取文章的收藏数，键为文章 ID，没有收藏的文章不在结果中
+func (a *ArticleRepository) GetFavoriteCounts(articleIDs []int) (map[int]int, error) {
+	var rows []struct {
+		ArticleID int
+		Count     int
+	}
+	result := a.DB.Model(&article_model.ArticleFavorite{}).Select("article_id, COUNT(*) AS count").
+		Where("article_id IN ?", articleIDs).Group("article_id").Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetFavoriteCounts err: %w", result.Error)
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.ArticleID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetFavoritesByUserID 获取用户的全部收藏关系，按收藏时间从新到旧排列
+func (a *ArticleRepository) GetFavoritesByUserID(userID int) ([]*article_model.ArticleFavorite, error) {
+	var favorites []*article_model.ArticleFavorite
+	if err := a.DB.Where("user_id = ?", userID).Order("create_at DESC, id DESC").Find(&favorites).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetFavoritesByUserID err: %w", err)
+	}
+	return favorites, nil
+}
+
+// AddOutboxEvents 在事务中写入缓存发件箱，事务回滚时发件箱中的操作也不会存在
+func (a *ArticleRepository) AddOutboxEvents(tx *gorm.DB, events []*article_model.ArticleOutbox) error {
+	if err := tx.Create(&events).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.AddOutboxEvents err: %w", err)
+	}
+	return nil
+}
+
+// GetDueOutboxEvents 获取已到执行时间的发件箱操作，按写入顺序返回
+func (a *ArticleRepository) GetDueOutboxEvents(now time.Time, limit int) ([]*article_model.ArticleOutbox, error) {
+	var events []*article_model.ArticleOutbox
+	if err := a.DB.Where("available_at <= ?", now).Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetDueOutboxEvents err: %w", err)
+	}
+	return events, nil
+}
+
+// DeleteOutboxEvent 删除执行成功的发件箱操作
+func (a *ArticleRepository) DeleteOutboxEvent(id int) error {
+	if err := a.DB.Delete(&article_model.ArticleOutbox{}, id).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.DeleteOutboxEvent err: %w", err)
+	}
+	return nil
+}
+
+// DeferOutboxEvent 记录发件箱操作的失败次数与原因，并推迟到 availableAt 再执行
+func (a *ArticleRepository) DeferOutboxEvent(id int, attempts int, lastError string, availableAt time.Time) error {
+	if err := a.DB.Model(&article_model.ArticleOutbox{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":     attempts,
+		"last_error":   lastError,
+		"available_at": availableAt,
+	}).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.DeferOutboxEvent err: %w", err)
+	}
+	return nil
+}
+
+// SaveViewDaily 写入文章每天的浏览统计，同一篇文章同一天已有记录时覆盖
+func (a *ArticleRepository) SaveViewDaily(tx *gorm.DB, stats []*article_model.ArticleViewDaily) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "article_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"views", "unique_views"}),
+	}).Create(&stats).Error; err != nil {
+		return fmt.Errorf("ArticleRepository.SaveViewDaily err: %w", err)
+	}
+	return nil
+}
+
+// GetViewDaily 获取文章从 from 起每天的浏览统计，按日期升序
+func (a *ArticleRepository) GetViewDaily(articleID int, from time.Time) ([]*article_model.ArticleViewDaily, error) {
+	var stats []*article_model.ArticleViewDaily
+	if err := a.DB.Where("article_id = ? AND date >= ?", articleID, from).Order("date ASC").Find(&stats).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetViewDaily err: %w", err)
+	}
+	return stats, nil
+}
+
+// GetTopViewed 获取从 from 起浏览次数最多的 limit 篇文章，kind 为空时不限类型
+func (a *ArticleRepository) GetTopViewed(from time.Time, kind string, limit int) ([]*article_model.ArticleViewRank, error) {
+	var ranks []*article_model.ArticleViewRank
+	query := a.DB.Table("article_view_daily").
+		Select("article.id AS article_id, article.title, article.kind, SUM(article_view_daily.views) AS views, "+
+			"SUM(article_view_daily.unique_views) AS unique_views").
+		Joins("JOIN article ON article.id = article_view_daily.article_id").
+		Where("article_view_daily.date >= ?", from)
+	if kind != "" {
+		query = query.Where("article.kind = ?", kind)
+	}
+	if err := query.Group("article.id, article.title, article.kind").Order("views DESC").Limit(limit).
+		Scan(&ranks).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetTopViewed err: %w", err)
+	}
+	return ranks, nil
+}
+
+// AddAck 写入确认已读记录，已确认过时由 (article_id, user_id) 唯一索引忽略，返回是否新写入了记录
+func (a *ArticleRepository) AddAck(ack *article_model.ArticleAck) (bool, error) {
+	result := a.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(ack)
+	if result.Error != nil {
+		return false, fmt.Errorf("ArticleRepository.AddAck err: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetAck 获取用户对文章的确认已读记录，没有确认时返回 nil
+func (a *ArticleRepository) GetAck(articleID int, userID int) (*article_model.ArticleAck, error) {
+	var acks []*article_model.ArticleAck
+	if err := a.DB.Where("article_id = ? AND user_id = ?", articleID, userID).Limit(1).Find(&acks).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetAck err: %w", err)
+	}
+	if len(acks) == 0 {
+		return nil, nil
+	}
+	return acks[0], nil
+}
+
+// CountAckResidents 统计居民（不含管理员）总数与其中已确认文章的人数
+func (a *ArticleRepository) CountAckResidents(articleID int) (total int64, acknowledged int64, err error) {
+	if err := a.DB.Table("`user`").Where("is_manager = 0").Count(&total).Error; err != nil {
+		return 0, 0, fmt.Errorf("ArticleRepository.CountAckResidents err: %w", err)
+	}
+	if err := a.DB.Table("article_ack AS a").
+		Joins("JOIN `user` AS u ON u.id = a.user_id").
+		Where("a.article_id = ? AND u.is_manager = 0", articleID).
+		Count(&acknowledged).Error; err != nil {
+		return 0, 0, fmt.Errorf("ArticleRepository.CountAckResidents err: %w", err)
+	}
+	return total, acknowledged, nil
+}
+
+// GetAckResidents 分页获取已确认或未确认文章的居民，limit 为 -1 时获取全部
+// 已确认的居民按确认时间从新到旧排列，未确认的居民按用户 ID 排列
+func (a *ArticleRepository) GetAckResidents(articleID int, status string, offset int, limit int) ([]*article_model.AckResident, error) {
+	var query *gorm.DB
+	if status == article_model.AckStatusAcknowledged {
+		query = a.DB.Table("article_ack AS a").
+			Select("u.id AS user_id, u.username AS user_name, u.phone_number, a.create_at AS ack_at").
+			Joins("JOIN `user` AS u ON u.id = a.user_id").
+			Where("a.article_id = ? AND u.is_manager = 0", articleID).
+			Order("a.create_at DESC, a.id DESC")
+	} else {
+		query = a.DB.Table("`user` AS u").
+			Select("u.id AS user_id, u.username AS user_name, u.phone_number").
+			Joins("LEFT JOIN article_ack AS a ON a.user_id = u.id AND a.article_id = ?", articleID).
+			Where("u.is_manager = 0 AND a.id IS NULL").
+			Order("u.id")
+	}
+	var residents []*article_model.AckResident
+	if err := query.Offset(offset).Limit(limit).Scan(&residents).Error; err != nil {
+		return nil, fmt.Errorf("ArticleRepository.GetAckResidents err: %w", err)
+	}
+	return residents, nil
 }
@@ -0,0 +1,137 @@
+package article_service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/internal/article/article_model"
+	"io"
+	"strconv"
+	"time"
+)
+
+// SetRequireAck 设置文章是否需要居民确认已读，取消后已有的确认记录保留，再次设置时仍然有效
+func (a *ArticleService) SetRequireAck(articleID int, requireAck bool) error {
+	err := a.updateArticleState(articleID, func(tx *gorm.DB) error {
+		return a.articleRepository.SetRequireAck(tx, articleID, requireAck)
+	})
+	if err != nil {
+		return fmt.Errorf("ArticleService.SetRequireAck err: %w", err)
+	}
+	return nil
+}
+
+// AcknowledgeArticle 用户确认已读需要确认的文章，重复确认时返回第一次确认的时间
+func (a *ArticleService) AcknowledgeArticle(articleID int, userID int) (*article_model.AckResult, error) {
+	if _, err := a.checkRequireAck(articleID); err != nil {
+		return nil, fmt.Errorf("ArticleService.AcknowledgeArticle err: %w", err)
+	}
+	ack := &article_model.ArticleAck{ArticleID: articleID, UserID: userID, CreateAt: time.Now()}
+	added, err := a.articleRepository.AddAck(ack)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.AcknowledgeArticle err: 500: %w", err)
+	}
+	if !added {
+		existing, err := a.articleRepository.GetAck(articleID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.AcknowledgeArticle err: 500: %w", err)
+		}
+		if existing != nil {
+			ack = existing
+		}
+	}
+	return &article_model.AckResult{ArticleID: articleID, AckAt: ack.CreateAt}, nil
+}
+
+// GetAckReport 获取文章的已读报告：居民总数、已确认与未确认的人数，以及 status 对应的一页居民
+func (a *ArticleService) GetAckReport(articleID int, status string, page int, size int) (*article_model.AckReport, error) {
+	if status == "" {
+		status = article_model.AckStatusOutstanding
+	}
+	if status != article_model.AckStatusAcknowledged && status != article_model.AckStatusOutstanding {
+		return nil, fmt.Errorf("ArticleService.GetAckReport err: 400:不支持的状态%s", status)
+	}
+	basicArticle, err := a.checkRequireAck(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAckReport err: %w", err)
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = a.config.Article.AckPageSize
+	}
+	size = min(size, a.config.Article.AckMaxPageSize)
+
+	total, acknowledged, err := a.articleRepository.CountAckResidents(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAckReport err: 500: %w", err)
+	}
+	residents, err := a.articleRepository.GetAckResidents(articleID, status, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAckReport err: 500: %w", err)
+	}
+	return &article_model.AckReport{
+		ArticleID:    articleID,
+		Title:        basicArticle.Title,
+		Total:        total,
+		Acknowledged: acknowledged,
+		Outstanding:  max(total-acknowledged, 0),
+		Status:       status,
+		Page:         page,
+		Size:         size,
+		Residents:    residents,
+	}, nil
+}
+
+// GetOutstandingResidents 获取全部尚未确认文章的居民，用于导出后线下通知
+func (a *ArticleService) GetOutstandingResidents(articleID int) ([]*article_model.AckResident, error) {
+	if _, err := a.checkRequireAck(articleID); err != nil {
+		return nil, fmt.Errorf("ArticleService.GetOutstandingResidents err: %w", err)
+	}
+	residents, err := a.articleRepository.GetAckResidents(articleID, article_model.AckStatusOutstanding, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetOutstandingResidents err: 500: %w", err)
+	}
+	return residents, nil
+}
+
+// checkRequireAck 检查文章存在并且需要确认已读，返回文章的基本信息
+func (a *ArticleService) checkRequireAck(articleID int) (*article_model.BasicArticle, error) {
+	basicArticle, err := a.getBasicArticle(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("500: %w", err)
+	}
+	if basicArticle == nil {
+		return nil, fmt.Errorf("400:文章不存在")
+	}
+	if !basicArticle.RequireAck {
+		return nil, fmt.Errorf("400:文章不需要确认已读")
+	}
+	return basicArticle, nil
+}
+
+// WriteAckResidents 按 format 把居民列表写入 w，CSV 的第一行为表头
+func WriteAckResidents(w io.Writer, format string, residents []*article_model.AckResident) error {
+	switch format {
+	case article_model.TransferJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(residents)
+	case article_model.TransferCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"user_id", "username", "phone_number"}); err != nil {
+			return err
+		}
+		for _, resident := range residents {
+			if err := writer.Write([]string{strconv.Itoa(resident.UserID), resident.UserName, resident.PhoneNumber}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("不支持的格式%s", format)
+	}
+}
@@ -1,34 +1,93 @@
 package article_service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
 	"huancuilou/common/utils"
+	"huancuilou/configs"
 	"huancuilou/internal/article/article_model"
 	"huancuilou/internal/article/article_repository"
+	"huancuilou/internal/job/job_service"
+	"huancuilou/internal/moderation/moderation_model"
+	"huancuilou/internal/moderation/moderation_service"
 	"log"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
+// LikeFlushJob 点赞回写任务的名称，由调度器在持有租约的一个实例上运行
+const LikeFlushJob = "article_like_flush"
+
+// OutboxRelayJob 缓存发件箱任务的名称
+const OutboxRelayJob = "article_cache_outbox"
+
+// HotRecomputeJob 重新计算文章热度任务的名称
+const HotRecomputeJob = "article_hot_recompute"
+
+// PublishHandler 新文章发布后的处理方，由其他模块注册，通过缓存发件箱调用，失败时会重试，需要保证幂等
+type PublishHandler func(article *article_model.BasicArticle) error
+
 type ArticleService struct {
 	articleRepository      *article_repository.ArticleRepository
 	articleCacheRepository *article_repository.ArticleCacheRepository
+	moderationService      *moderation_service.ModerationService
+	jobService             *job_service.JobService
+	likeFlushInterval      atomic.Int64 // 点赞回写的当前间隔，随回写量自动调整
+	loadGroup              singleflight.Group
+	publishHandlers        []PublishHandler
+	config                 *configs.Config
 }
 
-func NewArticleService(articleRepository *article_repository.ArticleRepository, articleCacheRepository *article_repository.ArticleCacheRepository) *ArticleService {
-	return &ArticleService{
+func NewArticleService(articleRepository *article_repository.ArticleRepository, articleCacheRepository *article_repository.ArticleCacheRepository,
+	moderationService *moderation_service.ModerationService, jobService *job_service.JobService, config *configs.Config) *ArticleService {
+	articleService := &ArticleService{
 		articleRepository:      articleRepository,
 		articleCacheRepository: articleCacheRepository,
+		moderationService:      moderationService,
+		jobService:             jobService,
+		config:                 config,
 	}
+	articleService.likeFlushInterval.Store(int64(config.Article.LikeFlushMinInterval))
+	return articleService
+}
+
+// RegisterPublishHandler 注册新文章发布后的处理方，需要在处理请求前注册
+func (a *ArticleService) RegisterPublishHandler(handler PublishHandler) {
+	a.publishHandlers = append(a.publishHandlers, handler)
 }
 
 func (a *ArticleService) AddArticle(article *article_model.Article, managerID int) error {
-	article.ManagerID = managerID
 	article.CreateAt = time.Now()
+	return a.addArticle(article, managerID, true)
+}
+
+// addArticle 写入文章并通过发件箱写入缓存，发布时间由调用方设置
+// publish 为 false 时不调用发布处理方，导入的旧文章不会推送到粉丝的关注流
+func (a *ArticleService) addArticle(article *article_model.Article, managerID int, publish bool) error {
+	article.ManagerID = managerID
 	article.Like = 0
+	article.Archived = false
+	if article.Format == "" {
+		article.Format = utils.ArticleFormatPlain
+	}
+	if err := validatePin(article.PinPriority, article.PinExpireAt, time.Now()); err != nil {
+		return fmt.Errorf("ArticleService.AddArticle err: %w", err)
+	}
+
+	checkResult, err := a.filterArticle(article)
+	if err != nil {
+		return fmt.Errorf("ArticleService.AddArticle err: %w", err)
+	}
 
 	tx := a.articleRepository.DB.Begin()
 	if tx.Error != nil {
-		return fmt.Errorf("ArticleService.AddArticle err: %w", tx.Error)
+		return fmt.Errorf("ArticleService.AddArticle err: 500: %w", tx.Error)
 	}
 	defer func() {
 		if r := recover(); r != nil {
@@ -39,179 +98,1473 @@ func (a *ArticleService) AddArticle(article *article_model.Article, managerID in
 
 	if err := a.articleRepository.AddArticle(tx, article); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ArticleService.AddArticle err: %w", err)
+		return fmt.Errorf("ArticleService.AddArticle err: 500: %w", err)
+	}
+
+	if err := a.articleRepository.AddTags(tx, article.ID, article.Tags); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ArticleService.AddArticle err: 500: %w", err)
 	}
 
-	basicArticle := &article_model.BasicArticle{
-		ID:        article.ID,
-		Title:     article.Title,
-		Content:   utils.Substring(article.Content, 5),
-		Kind:      article.Kind,
-		ManagerID: managerID,
-		Like:      0,
+	if checkResult.Action == moderation_model.ActionReview {
+		if err := a.moderationService.Submit(tx, moderation_model.ContentArticle, article.ID, managerID,
+			article.Title+"\n"+article.Content, checkResult.Words); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("ArticleService.AddArticle err: 500: %w", err)
+		}
 	}
 
-	if err := a.articleCacheRepository.AddBasicArticle(basicArticle); err != nil {
+	// 提交前加入布隆过滤器，事务回滚只会多出一个误判，不会把存在的文章判断为不存在
+	if err := a.articleCacheRepository.BloomAdd(a.bloomOffsets(article.ID)); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ArticleService.AddArticle err: %w", err)
+		return fmt.Errorf("ArticleService.AddArticle err: 500: %w", err)
 	}
 
-	go func() {
-		if err := a.articleCacheRepository.AddArticle(article); err != nil {
-			log.Printf("缓存文章添加失败: %v", err)
-		}
-	}()
+	// 缓存在事务提交后由发件箱写入，事务回滚时首页不会出现不存在的文章，也不会推送给粉丝
+	events := a.newOutboxEvents(article.ID, false)
+	if publish {
+		events = append(events, &article_model.ArticleOutbox{
+			ArticleID:   article.ID,
+			Action:      article_model.OutboxPublish,
+			AvailableAt: article.CreateAt,
+			CreateAt:    article.CreateAt,
+		})
+	}
+	if err := a.articleRepository.AddOutboxEvents(tx, events); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ArticleService.AddArticle err: 500: %w", err)
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("事务提交失败: %v", err)
-		return fmt.Errorf("ArticleService.AddArticle err: %w", err)
+		return fmt.Errorf("ArticleService.AddArticle err: 500: %w", err)
 	}
 
+	a.relayOutboxEvents(events)
 	return nil
 }
 
-func (a *ArticleService) GetAllArticleByKind(kind string) ([]*article_model.BasicArticle, error) {
-	articles, err := a.articleCacheRepository.GetAllArticleByKind(kind)
+// GetAllArticleByKind 获取某个类型的所有基本文章，并标记 userID 是否已点赞
+// 置顶的文章按优先级排在最前面，其余文章按发布时间从新到旧排列
+func (a *ArticleService) GetAllArticleByKind(kind string, userID int) ([]*article_model.BasicArticle, error) {
+	listed, err := a.articleCacheRepository.GetAllArticleByKind(kind)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAllArticleByKind err: 500: %w", err)
+	}
+	articles, err := a.articleCacheRepository.GetPinnedArticles(kind, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAllArticleByKind err: 500: %w", err)
+	}
+	pinned := make(map[int]bool, len(articles))
+	for _, article := range articles {
+		pinned[article.ID] = true
+	}
+	for _, article := range listed {
+		if !pinned[article.ID] {
+			articles = append(articles, article)
+		}
+	}
+	if err := a.fillLikedByMe(articles, userID); err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAllArticleByKind err: 500: %w", err)
+	}
+	return articles, nil
+}
+
+// GetBasicArticles 按 articleIDs 的顺序获取基本文章，并标记 userID 是否已点赞
+// 不在缓存中的文章由 MySQL 加载，已被删除的文章不在结果中
+func (a *ArticleService) GetBasicArticles(articleIDs []int, userID int) ([]*article_model.BasicArticle, error) {
+	cached, err := a.articleCacheRepository.GetBasicArticlesByIDs(articleIDs)
 	if err != nil {
-		return nil, fmt.Errorf("ArticleService.GetAllArticleByKind err: %w", err)
+		return nil, fmt.Errorf("ArticleService.GetBasicArticles err: %w", err)
+	}
+	articles := make([]*article_model.BasicArticle, 0, len(articleIDs))
+	for _, id := range articleIDs {
+		article, ok := cached[id]
+		if !ok {
+			if article, err = a.getBasicArticle(id); err != nil {
+				return nil, fmt.Errorf("ArticleService.GetBasicArticles err: %w", err)
+			}
+			if article == nil {
+				continue
+			}
+		}
+		articles = append(articles, article)
+	}
+	if err := a.fillLikedByMe(articles, userID); err != nil {
+		return nil, fmt.Errorf("ArticleService.GetBasicArticles err: %w", err)
 	}
 	return articles, nil
 }
 
-func (a *ArticleService) GetArticle(id int) (*article_model.Article, error) {
+// fillLikedByMe 批量标记 userID 是否点赞了列表中的文章
+func (a *ArticleService) fillLikedByMe(articles []*article_model.BasicArticle, userID int) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	articleIDs := make([]int, len(articles))
+	for i, article := range articles {
+		articleIDs[i] = article.ID
+	}
+	liked, err := a.articleCacheRepository.GetLikedByUser(articleIDs, userID)
+	if err != nil {
+		return err
+	}
+	for _, article := range articles {
+		article.LikedByMe = liked[article.ID]
+	}
+	return nil
+}
+
+// GetHotArticles 按热度分页获取热门文章，kind 为空时获取全站排行，并标记 userID 是否已点赞
+func (a *ArticleService) GetHotArticles(kind string, page int, size int, userID int) (*article_model.HotArticlePage, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = a.config.Article.Hot.PageSize
+	}
+	size = min(size, a.config.Article.Hot.MaxPageSize)
+	articles, total, err := a.articleCacheRepository.GetHotArticles(kind, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetHotArticles err: 500: %w", err)
+	}
+	basicArticles := make([]*article_model.BasicArticle, len(articles))
+	for i, article := range articles {
+		basicArticles[i] = article.BasicArticle
+	}
+	if err := a.fillLikedByMe(basicArticles, userID); err != nil {
+		return nil, fmt.Errorf("ArticleService.GetHotArticles err: 500: %w", err)
+	}
+	return &article_model.HotArticlePage{
+		Total:    total,
+		Page:     page,
+		Size:     size,
+		Articles: articles,
+	}, nil
+}
+
+// RecomputeHotJob 调度器中的热度重算任务
+func (a *ArticleService) RecomputeHotJob(ctx context.Context, jc *job_service.JobContext) error {
+	return a.RecomputeHotArticles()
+}
+
+// RecomputeHotArticles 重新计算全部文章的热度并替换热门排行
+// 热度随发布时间衰减，点赞和评论只会更新被操作的文章，其余文章的热度由定时重算更新；
+// 计数以缓存为准，基本文章不在缓存中时使用 MySQL 中的点赞数与评论数
+func (a *ArticleService) RecomputeHotArticles() error {
+	now := time.Now()
+	var hotArticles []*article_model.HotArticle
+	maxID := 0
+	for {
+		articles, err := a.articleRepository.GetHotSourcesAfter(maxID, a.config.Article.RebuildBatchSize)
+		if err != nil {
+			return fmt.Errorf("ArticleService.RecomputeHotArticles err: %w", err)
+		}
+		if len(articles) == 0 {
+			break
+		}
+		articleIDs := make([]int, len(articles))
+		for i, article := range articles {
+			articleIDs[i] = article.ID
+		}
+		counts, err := a.articleCacheRepository.GetHotCounts(articleIDs)
+		if err != nil {
+			return fmt.Errorf("ArticleService.RecomputeHotArticles err: %w", err)
+		}
+		comments, err := a.articleRepository.GetCommentCounts(articleIDs)
+		if err != nil {
+			return fmt.Errorf("ArticleService.RecomputeHotArticles err: %w", err)
+		}
+		for _, article := range articles {
+			if article.Archived {
+				continue
+			}
+			hotCounts, ok := counts[article.ID]
+			if !ok {
+				hotCounts = &article_model.HotCounts{Like: article.Like, Comment: comments[article.ID]}
+			}
+			hotArticles = append(hotArticles, &article_model.HotArticle{
+				BasicArticle: &article_model.BasicArticle{ID: article.ID, Kind: article.Kind},
+				Hot:          utils.HotScore(hotCounts.Like, hotCounts.Comment, hotCounts.View, now.Sub(article.CreateAt)),
+			})
+		}
+		maxID = articles[len(articles)-1].ID
+	}
+
+	kinds := make([]string, 0, len(a.config.Article.KindMap))
+	for kind := range a.config.Article.KindMap {
+		kinds = append(kinds, kind)
+	}
+	if err := a.articleCacheRepository.RebuildHotRanking(hotArticles, kinds, a.config.Article.Hot.MaxSize); err != nil {
+		return fmt.Errorf("ArticleService.RecomputeHotArticles err: %w", err)
+	}
+	return nil
+}
+
+// GetArticle 获取完整文章，并记录 userID 的一次浏览
+func (a *ArticleService) GetArticle(id int, userID int) (*article_model.Article, error) {
+	// 布隆过滤器判断不存在的文章一定不存在，枚举文章 ID 的请求不会打到 MySQL
+	mightExist, err := a.articleCacheRepository.BloomMightContain(a.bloomOffsets(id))
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetArticle err: 500: %w", err)
+	}
+	if !mightExist {
+		return nil, fmt.Errorf("ArticleService.GetArticle err: 400:文章不存在")
+	}
+
 	article, err := a.articleCacheRepository.GetArticleByID(id)
 	if err != nil {
-		return nil, fmt.Errorf("ArticleService.GetArticle err: %w", err)
+		return nil, fmt.Errorf("ArticleService.GetArticle err: 500: %w", err)
 	}
 	if article == nil {
+		article, err = a.loadArticle(id)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.GetArticle err: 500: %w", err)
+		}
+		if article == nil {
+			return nil, fmt.Errorf("ArticleService.GetArticle err: 400:文章不存在")
+		}
+	}
+
+	comment, err := a.articleCacheRepository.GetCommentCount(id)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetArticle err: 500: %w", err)
+	}
+	article.Comment = comment
+	favorite, err := a.articleCacheRepository.GetFavoriteCount(id)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetArticle err: 500: %w", err)
+	}
+	article.Favorite = favorite
+	liked, err := a.articleCacheRepository.GetLikedByUser([]int{id}, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetArticle err: 500: %w", err)
+	}
+	article.LikedByMe = liked[id]
+	if article.RequireAck {
+		ack, err := a.articleRepository.GetAck(id, userID)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.GetArticle err: 500: %w", err)
+		}
+		article.AckedByMe = ack != nil
+	}
+	article.ContentHTML = utils.RenderArticleHTML(article.Content, article.Format)
+
+	// 浏览统计和浏览记录只是附加信息，记录失败时照常返回文章
+	now := time.Now()
+	view, uniqueView, err := a.articleCacheRepository.RecordView(id, userID, now, a.config.Article.ViewDailyTTL)
+	if err != nil {
+		log.Printf("记录文章%d的浏览失败: %v", id, err)
+	} else {
+		article.View = view
+		article.UniqueView = uniqueView
+	}
+	recommend := a.config.Article.Recommend
+	if err := a.articleCacheRepository.AddReadHistory(userID, id, now, recommend.HistorySize, recommend.HistoryTTL); err != nil {
+		log.Printf("记录用户%d的浏览记录失败: %v", userID, err)
+	}
+	return article, nil
+}
+
+// loadArticle 缓存未命中时从 MySQL 加载文章并写入缓存，文章不存在时返回 nil
+// 同一篇文章的并发请求合并为一次查询，只有一个请求查询 MySQL 并写入缓存；不存在的文章短时间缓存空结果
+func (a *ArticleService) loadArticle(id int) (*article_model.Article, error) {
+	isNull, err := a.articleCacheRepository.IsArticleNull(id)
+	if err != nil {
+		return nil, err
+	}
+	if isNull {
+		return nil, nil
+	}
+
+	value, err, _ := a.loadGroup.Do(strconv.Itoa(id), func() (interface{}, error) {
 		log.Printf("从缓存中获取文章失败, 从数据库中获取文章")
 		articleWithNoLike, err := a.articleRepository.GetArticleByID(id)
 		if err != nil {
-			return nil, fmt.Errorf("ArticleService.GetArticle err: %w", err)
+			return nil, err
 		}
 		if articleWithNoLike == nil {
-			return nil, fmt.Errorf("ArticleService.GetArticle err: 文章不存在")
+			if err := a.articleCacheRepository.SetArticleNull(id, a.config.Article.NullCacheTTL); err != nil {
+				log.Printf("缓存不存在的文章失败: %v", err)
+			}
+			return nil, nil
 		}
-		basicArticle, err := a.articleCacheRepository.GetBasicArticleByID(id)
+		basicArticle, err := a.getBasicArticle(id)
 		if err != nil {
-			return nil, fmt.Errorf("ArticleService.GetArticle err: %w", err)
+			return nil, err
 		}
-		article = &article_model.Article{
-			ID:        articleWithNoLike.ID,
-			Title:     articleWithNoLike.Title,
-			Content:   articleWithNoLike.Content,
-			Kind:      articleWithNoLike.Kind,
-			ManagerID: articleWithNoLike.ManagerID,
-			CreateAt:  articleWithNoLike.CreateAt,
-			Like:      basicArticle.Like,
+		if basicArticle == nil {
+			return nil, nil
+		}
+		article := &article_model.Article{
+			ID:              articleWithNoLike.ID,
+			Title:           articleWithNoLike.Title,
+			Content:         articleWithNoLike.Content,
+			Format:          articleWithNoLike.Format,
+			Kind:            articleWithNoLike.Kind,
+			ManagerID:       articleWithNoLike.ManagerID,
+			CreateAt:        articleWithNoLike.CreateAt,
+			Like:            basicArticle.Like,
+			Tags:            articleWithNoLike.Tags,
+			PinPriority:     articleWithNoLike.PinPriority,
+			PinExpireAt:     articleWithNoLike.PinExpireAt,
+			ArchiveOnExpire: articleWithNoLike.ArchiveOnExpire,
+			Archived:        articleWithNoLike.Archived,
+			RequireAck:      articleWithNoLike.RequireAck,
+		}
+		if err := a.articleCacheRepository.AddArticle(article, a.articleCacheTTL()); err != nil {
+			log.Printf("缓存文章添加失败: %v", err)
 		}
-		go func() {
-			log.Printf("向缓存中添加文章")
-			if err := a.articleCacheRepository.AddArticle(article); err != nil {
-				log.Printf("缓存文章添加失败: %v", err)
-			}
-		}()
 		return article, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return article, nil
+	shared, _ := value.(*article_model.Article)
+	if shared == nil {
+		return nil, nil
+	}
+	// 合并的请求共用同一个结果，复制一份再交给调用方修改
+	article := *shared
+	return &article, nil
 }
 
-// PeriodicUpdateLikes 周期性更新文章点赞数据到 MySQL
-func (a *ArticleService) PeriodicUpdateLikes(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	for {
-		select {
-		case <-ticker.C:
-			// 从 Redis 获取文章点赞数据
-			results, err := a.articleCacheRepository.GetAllArticlesFromHash()
-			if err != nil || len(results) == 0 {
-				log.Printf("从 Redis 获取文章点赞数据出错: %v", err)
-				continue
-			}
-			log.Printf("从 Redis 获取文章点赞数据")
+// getBasicArticle 获取基本文章，缓存中不存在时由 MySQL 加载并写回缓存，文章不存在时返回 nil
+// Redis 数据丢失或更新文章删除缓存后，读取和点赞都不会因为缺少基本文章而失败
+func (a *ArticleService) getBasicArticle(id int) (*article_model.BasicArticle, error) {
+	basicArticle, err := a.articleCacheRepository.GetBasicArticleByID(id)
+	if err != nil || basicArticle != nil {
+		return basicArticle, err
+	}
 
-			// 将点赞数据回写到 MySQL
-			if err := a.updateLikesInTransaction(results); err != nil {
-				log.Printf("更新点赞数据到 MySQL 出错: %v", err)
-				continue
+	value, err, _ := a.loadGroup.Do("basic:"+strconv.Itoa(id), func() (interface{}, error) {
+		log.Printf("基本文章%d不在缓存中, 从数据库中加载", id)
+		basicArticle, err := a.loadBasicArticle(id)
+		if err != nil || basicArticle == nil {
+			return nil, err
+		}
+		if err := a.articleCacheRepository.AddBasicArticleIfAbsent(basicArticle); err != nil {
+			return nil, err
+		}
+		return basicArticle, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	shared, _ := value.(*article_model.BasicArticle)
+	if shared == nil {
+		return nil, nil
+	}
+	copied := *shared
+	return &copied, nil
+}
+
+// loadBasicArticle 由 MySQL 生成基本文章，文章不存在时返回 nil
+// 点赞集合仍在缓存中时以集合为准，否则取点赞关系表中的点赞数
+func (a *ArticleService) loadBasicArticle(id int) (*article_model.BasicArticle, error) {
+	articleWithNoLike, err := a.articleRepository.GetArticleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if articleWithNoLike == nil {
+		return nil, nil
+	}
+	like, err := a.articleCacheRepository.CountLikeUsers(id)
+	if err != nil {
+		return nil, err
+	}
+	if like == 0 {
+		// 点赞集合为空时可能是 Redis 数据丢失，以 MySQL 中的点赞关系为准并写回点赞集合，
+		// 否则之后的点赞会在空集合上计数，点赞状态与点赞数都会出错
+		likeUserIDs, err := a.articleRepository.GetLikeUserIDsByArticleIDs([]int{id})
+		if err != nil {
+			return nil, err
+		}
+		like = len(likeUserIDs[id])
+		if like > 0 {
+			if _, err := a.articleCacheRepository.RefillLikes(id, likeUserIDs[id]); err != nil {
+				return nil, err
 			}
-			log.Printf("更新点赞数据到 MySQL 成功")
 		}
 	}
+	comments, err := a.articleRepository.GetCommentCounts([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	favorites, err := a.articleRepository.GetFavoriteCounts([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	return a.toBasicArticle(&article_model.Article{
+		ID:          articleWithNoLike.ID,
+		Title:       articleWithNoLike.Title,
+		Content:     articleWithNoLike.Content,
+		Format:      articleWithNoLike.Format,
+		Kind:        articleWithNoLike.Kind,
+		ManagerID:   articleWithNoLike.ManagerID,
+		CreateAt:    articleWithNoLike.CreateAt,
+		View:        articleWithNoLike.View,
+		Like:        like,
+		Comment:     comments[id],
+		Favorite:    favorites[id],
+		Tags:        articleWithNoLike.Tags,
+		PinPriority: articleWithNoLike.PinPriority,
+		PinExpireAt: articleWithNoLike.PinExpireAt,
+		Archived:    articleWithNoLike.Archived,
+		RequireAck:  articleWithNoLike.RequireAck,
+	}), nil
 }
 
-// updateLikesInTransaction 在事务中更新点赞数据到 MySQL
-func (a *ArticleService) updateLikesInTransaction(results [][]int) error {
-	tx := a.articleRepository.DB.Begin()
-	if tx.Error != nil {
-		return tx.Error
+// WarmUpCaches 服务启动时检查文章缓存，所有类型的基本文章列表都不存在时（例如 Redis 数据丢失）由 MySQL 重建
+func (a *ArticleService) WarmUpCaches() error {
+	kinds := make([]string, 0, len(a.config.Article.KindMap))
+	for kind := range a.config.Article.KindMap {
+		kinds = append(kinds, kind)
+	}
+	count, err := a.articleCacheRepository.CountBasicLists(kinds)
+	if err != nil {
+		return fmt.Errorf("ArticleService.WarmUpCaches err: %w", err)
+	}
+	if count > 0 {
+		return a.RebuildBloomFilter()
 	}
+	if err := a.RebuildCaches(); err != nil {
+		return fmt.Errorf("ArticleService.WarmUpCaches err: %w", err)
+	}
+	return nil
+}
 
-	for _, result := range results {
+// RebuildCaches 由 MySQL 重建文章缓存：基本文章哈希表、类型与标签的基本文章列表、标签统计、点赞集合与点赞数以及布隆过滤器
+// 列表先写入临时键再整体替换，重建期间读取不受影响；点赞数以点赞关系表为准，并同步回文章表
+// 回写与逐批重建期间持有点赞回写锁，重建期间发生的点赞由 ResetLikes 重放，不会被 MySQL 中的旧数据覆盖
+func (a *ArticleService) RebuildCaches() error {
+	if err := a.articleCacheRepository.ClearRebuildingLists(); err != nil {
+		return fmt.Errorf("ArticleService.RebuildCaches err: 500: %w", err)
+	}
 
-		err := a.articleRepository.WriteLikesToMySQL(tx, result)
-		if err != nil {
-			tx.Rollback()
+	kinds := make(map[string]bool, len(a.config.Article.KindMap))
+	for kind := range a.config.Article.KindMap {
+		kinds[kind] = true
+	}
+	tagCounts := make(map[string]int)
+	var pinned []*article_model.BasicArticle
+	maxID, total := 0, 0
+	err := a.withLikeFlushLock(func(renew func() error) error {
+		// 先回写缓存中尚未持久化的点赞，Redis 数据仍在时重建不会丢失点赞
+		if _, err := a.flushLikes(0, renew); err != nil {
 			return err
 		}
+		for {
+			if err := renew(); err != nil {
+				return err
+			}
+			articles, err := a.articleRepository.GetArticlesAfter(maxID, a.config.Article.RebuildBatchSize)
+			if err != nil {
+				return err
+			}
+			if len(articles) == 0 {
+				return nil
+			}
+			batchPinned, err := a.rebuildArticleBatch(articles, kinds, tagCounts)
+			if err != nil {
+				return err
+			}
+			pinned = append(pinned, batchPinned...)
+			maxID = articles[len(articles)-1].ID
+			total += len(articles)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("ArticleService.RebuildCaches err: 500: %w", err)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return err
+	kindList := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		kindList = append(kindList, kind)
 	}
-
+	tagList := make([]string, 0, len(tagCounts))
+	for tag := range tagCounts {
+		tagList = append(tagList, tag)
+	}
+	if err := a.articleCacheRepository.SwapRebuiltLists(kindList, tagList, maxID); err != nil {
+		return fmt.Errorf("ArticleService.RebuildCaches err: 500: %w", err)
+	}
+	if err := a.articleCacheRepository.RebuildPinned(pinned, kindList); err != nil {
+		return fmt.Errorf("ArticleService.RebuildCaches err: 500: %w", err)
+	}
+	if err := a.articleCacheRepository.ResetTagStats(tagCounts); err != nil {
+		return fmt.Errorf("ArticleService.RebuildCaches err: 500: %w", err)
+	}
+	if err := a.RebuildBloomFilter(); err != nil {
+		return fmt.Errorf("ArticleService.RebuildCaches err: 500: %w", err)
+	}
+	if err := a.RecomputeHotArticles(); err != nil {
+		return fmt.Errorf("ArticleService.RebuildCaches err: 500: %w", err)
+	}
+	log.Printf("由数据库重建文章缓存完成，文章数: %d", total)
 	return nil
 }
 
-func (a *ArticleService) AddLikes(articleID int, userID int) error {
-	err := a.articleCacheRepository.AddLikes(articleID, userID, 1)
+// rebuildArticleBatch 重建一批文章的基本文章与点赞数据，统计出现的类型与未归档文章的标签，返回其中置顶的文章
+func (a *ArticleService) rebuildArticleBatch(articles []*article_model.Article, kinds map[string]bool, tagCounts map[string]int) ([]*article_model.BasicArticle, error) {
+	articleIDs := make([]int, len(articles))
+	for i, article := range articles {
+		articleIDs[i] = article.ID
+	}
+	tags, err := a.articleRepository.GetTagsByArticleIDs(articleIDs)
+	if err != nil {
+		return nil, err
+	}
+	likeUserIDs, err := a.articleRepository.GetLikeUserIDsByArticleIDs(articleIDs)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := a.articleRepository.GetCommentCounts(articleIDs)
+	if err != nil {
+		return nil, err
+	}
+	favorites, err := a.articleRepository.GetFavoriteCounts(articleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var pinned []*article_model.BasicArticle
+	basicArticles := make([]*article_model.BasicArticle, len(articles))
+	likes := make(map[int]int, len(articles))
+	for i, article := range articles {
+		article.Tags = tags[article.ID]
+		article.Like = len(likeUserIDs[article.ID])
+		article.Comment = comments[article.ID]
+		article.Favorite = favorites[article.ID]
+		basicArticles[i] = a.toBasicArticle(article)
+		likes[article.ID] = article.Like
+		kinds[article.Kind] = true
+		if article.Archived {
+			continue
+		}
+		for _, tag := range article.Tags {
+			tagCounts[tag]++
+		}
+		if article.PinPriority > 0 {
+			pinned = append(pinned, basicArticles[i])
+		}
+	}
+	if err := a.articleCacheRepository.RebuildBasicArticles(basicArticles); err != nil {
+		return nil, err
+	}
+	if err := a.articleCacheRepository.ResetLikes(articleIDs, likeUserIDs); err != nil {
+		return nil, err
+	}
+	if err := a.articleRepository.BatchUpdateLikes(a.articleRepository.DB, likes, a.config.Article.RebuildBatchSize); err != nil {
+		return nil, err
+	}
+	return pinned, nil
+}
+
+// articleCacheTTL 返回完整文章缓存的过期时间，加上随机抖动使同时写入的缓存分散过期
+func (a *ArticleService) articleCacheTTL() time.Duration {
+	ttl := a.config.Article.CacheTTL
+	if jitter := a.config.Article.CacheTTLJitter; jitter > 0 {
+		ttl += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	return ttl
+}
+
+// bloomOffsets 计算文章 ID 在布隆过滤器中对应的位
+func (a *ArticleService) bloomOffsets(id int) []uint64 {
+	return utils.BloomOffsets(strconv.Itoa(id), a.config.Article.BloomHashes, a.config.Article.BloomBits)
+}
+
+// RebuildBloomFilter 用 MySQL 中所有文章的 ID 重建布隆过滤器，服务启动时调用
+func (a *ArticleService) RebuildBloomFilter() error {
+	articleIDs, err := a.articleRepository.GetAllArticleIDs()
 	if err != nil {
-		return fmt.Errorf("ArticleService.AddLikes err: %w", err)
+		return fmt.Errorf("ArticleService.RebuildBloomFilter err: %w", err)
+	}
+	offsetsList := make([][]uint64, len(articleIDs))
+	for i, id := range articleIDs {
+		offsetsList[i] = a.bloomOffsets(id)
 	}
+	if err := a.articleCacheRepository.RebuildBloom(offsetsList, a.config.Article.BloomBits); err != nil {
+		return fmt.Errorf("ArticleService.RebuildBloomFilter err: %w", err)
+	}
+	log.Printf("重建文章布隆过滤器，文章数: %d", len(articleIDs))
 	return nil
 }
 
-func (a *ArticleService) RemoveLikes(articleID int, userID int) error {
-	err := a.articleCacheRepository.RemoveLikes(articleID, userID, -1)
+// FlushLikesJob 调度器中的点赞回写任务
+// 一轮回写的文章数达到批次大小时说明点赞频繁，间隔减半；没有需要回写的文章时间隔加倍，间隔限制在配置的最小值与最大值之间
+func (a *ArticleService) FlushLikesJob(ctx context.Context, jc *job_service.JobContext) error {
+	count, err := a.FlushLikes(jc.Token)
 	if err != nil {
-		return fmt.Errorf("ArticleService.RemoveLikes err: %w", err)
+		return fmt.Errorf("ArticleService.FlushLikesJob err: %w", err)
+	}
+	interval := time.Duration(a.likeFlushInterval.Load())
+	switch {
+	case count >= a.config.Article.LikeFlushBatchSize:
+		interval = interval / 2
+	case count == 0:
+		interval = interval * 2
 	}
+	interval = min(max(interval, a.config.Article.LikeFlushMinInterval), a.config.Article.LikeFlushMaxInterval)
+	a.likeFlushInterval.Store(int64(interval))
+	jc.Reschedule(time.Now().Add(interval))
 	return nil
 }
 
-func (a *ArticleService) UpdateArticle(article *article_model.Article) error {
-	//为防止文章点赞量丢失，先从缓存中获取点赞量
-	like, err := a.articleCacheRepository.GetArticleByID(article.ID)
+// FlushLikes 回写点赞事件、有变化的文章的点赞数以及浏览统计，返回回写的文章数
+// token 为点赞回写任务的防护令牌，为 0 时表示由管理员手动触发，不校验令牌
+// 整个回写过程持有点赞回写锁，手动回写与定时任务不会同时取出和删除处理中集合，已取出的文章 ID 不会在回写前被另一次回写删除
+func (a *ArticleService) FlushLikes(token int64) (int, error) {
+	var count int
+	err := a.withLikeFlushLock(func(renew func() error) error {
+		var err error
+		count, err = a.flushLikes(token, renew)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("ArticleService.UpdateArticle err: %w", err)
+		return 0, fmt.Errorf("ArticleService.FlushLikes err: 500: %w", err)
 	}
-	article.Like = like.Like
+	return count, nil
+}
 
-	//第一次删除缓存
-	if err = a.articleCacheRepository.DeleteArticleForUpdate(article.ID); err != nil {
-		return fmt.Errorf("ArticleService.UpdateArticle err: %w", err)
+// flushLikes 在持有点赞回写锁时依次回写点赞事件、点赞数与浏览统计，每一步之前调用 renew 续期锁
+func (a *ArticleService) flushLikes(token int64, renew func() error) (int, error) {
+	if err := a.flushLikeEvents(token, renew); err != nil {
+		return 0, err
 	}
+	if err := renew(); err != nil {
+		return 0, err
+	}
+	count, err := a.flushLikeCounts(token)
+	if err != nil {
+		return 0, err
+	}
+	if err := renew(); err != nil {
+		return 0, err
+	}
+	viewCount, err := a.flushViews(token)
+	if err != nil {
+		return 0, err
+	}
+	return count + viewCount, nil
+}
 
-	//更新数据库
-	newArticle, err := a.articleRepository.UpdateArticle(article)
+// flushViews 将浏览数有变化的文章的浏览数、独立访客数以及当天的浏览统计回写到 MySQL，返回回写的文章数
+// 与点赞数一样，取出的 "文章ID:日期" 在回写成功前一直保存在处理中集合
+func (a *ArticleService) flushViews(token int64) (int, error) {
+	daily, err := a.articleCacheRepository.TakeDirtyViews()
 	if err != nil {
-		return fmt.Errorf("ArticleService.UpdateArticle err: %w", err)
+		return 0, err
+	}
+	if len(daily) == 0 {
+		return 0, nil
+	}
+	if err := a.articleCacheRepository.FillViewDaily(daily); err != nil {
+		return 0, err
+	}
+	seen := make(map[int]bool)
+	var articleIDs []int
+	for _, stat := range daily {
+		if !seen[stat.ArticleID] {
+			seen[stat.ArticleID] = true
+			articleIDs = append(articleIDs, stat.ArticleID)
+		}
+	}
+	views, uniqueViews, err := a.articleCacheRepository.GetViewTotals(articleIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	tx := a.articleRepository.DB.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	if err := a.checkFence(tx, token); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := a.articleRepository.BatchUpdateViews(tx, views, uniqueViews, a.config.Article.LikeFlushBatchSize); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := a.articleRepository.SaveViewDaily(tx, daily); err != nil {
+		tx.Rollback()
+		return 0, err
 	}
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+
+	if err := a.articleCacheRepository.FinishDirtyViews(); err != nil {
+		return 0, err
+	}
+	log.Printf("回写%d篇文章的浏览统计", len(articleIDs))
+	return len(articleIDs), nil
+}
 
-	//第二次删除缓存
-	err = a.articleCacheRepository.DeleteArticleForUpdate(article.ID)
+// GetViewStats 获取文章的浏览数、独立访客数以及最近 days 天每天的浏览统计
+// 每天的统计来自 MySQL，最多比 Redis 晚一个点赞回写间隔
+func (a *ArticleService) GetViewStats(articleID int, days int) (*article_model.ArticleViewStats, error) {
+	days, err := a.viewStatsDays(days)
 	if err != nil {
-		return fmt.Errorf("ArticleService.UpdateArticle err: %w", err)
+		return nil, fmt.Errorf("ArticleService.GetViewStats err: %w", err)
+	}
+	article, err := a.articleRepository.GetArticleByID(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetViewStats err: 500: %w", err)
 	}
+	if article == nil {
+		return nil, fmt.Errorf("ArticleService.GetViewStats err: 400:文章不存在")
+	}
+	stats := &article_model.ArticleViewStats{ArticleID: articleID, Title: article.Title}
+	views, uniqueViews, err := a.articleCacheRepository.GetViewTotals([]int{articleID})
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetViewStats err: 500: %w", err)
+	}
+	if view, ok := views[articleID]; ok {
+		stats.View = view
+		stats.UniqueView = uniqueViews[articleID]
+	} else {
+		stats.View = article.View
+	}
+	if stats.Daily, err = a.articleRepository.GetViewDaily(articleID, viewStatsFrom(days)); err != nil {
+		return nil, fmt.Errorf("ArticleService.GetViewStats err: 500: %w", err)
+	}
+	return stats, nil
+}
 
-	//将更新后的基本文章重新加入到缓存
-	go func() {
-		if err := a.articleCacheRepository.AddBasicArticleWithoutAddList(newArticle); err != nil {
-			log.Printf("ArticleService.UpdateArticle err:重新添加基本文章失败：%v", err)
-		}
-	}()
+// GetTopViewedArticles 获取最近 days 天浏览次数最多的 limit 篇文章，kind 为空时不限类型
+func (a *ArticleService) GetTopViewedArticles(days int, kind string, limit int) ([]*article_model.ArticleViewRank, error) {
+	days, err := a.viewStatsDays(days)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetTopViewedArticles err: %w", err)
+	}
+	if limit <= 0 {
+		limit = a.config.Article.Hot.PageSize
+	}
+	ranks, err := a.articleRepository.GetTopViewed(viewStatsFrom(days), kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetTopViewedArticles err: 500: %w", err)
+	}
+	return ranks, nil
+}
 
-	return nil
+// viewStatsDays 校验浏览统计的天数，不大于 0 时默认为 7 天
+func (a *ArticleService) viewStatsDays(days int) (int, error) {
+	if days <= 0 {
+		return 7, nil
+	}
+	if days > a.config.Article.ViewStatsMaxDays {
+		return 0, fmt.Errorf("400: 统计天数不能超过%d天", a.config.Article.ViewStatsMaxDays)
+	}
+	return days, nil
+}
+
+// viewStatsFrom 返回最近 days 天（含今天）的第一天零点
+func viewStatsFrom(days int) time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, now.Location())
+}
+
+// flushLikeCounts 将脏集合中文章的点赞数批量回写到文章表
+// 取出的文章 ID 在回写成功前一直保存在处理中集合，失败时下次会与新的脏集合合并后重新回写
+func (a *ArticleService) flushLikeCounts(token int64) (int, error) {
+	articleIDs, err := a.articleCacheRepository.TakeDirtyArticles()
+	if err != nil {
+		return 0, err
+	}
+	if len(articleIDs) == 0 {
+		return 0, nil
+	}
+
+	likes, err := a.articleCacheRepository.GetLikeCounts(articleIDs)
+	if err != nil {
+		return 0, err
+	}
+	tx := a.articleRepository.DB.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	if err := a.checkFence(tx, token); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := a.articleRepository.BatchUpdateLikes(tx, likes, a.config.Article.LikeFlushBatchSize); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+
+	if err := a.articleCacheRepository.FinishDirtyArticles(); err != nil {
+		return 0, err
+	}
+	log.Printf("回写%d篇文章的点赞数", len(articleIDs))
+	return len(articleIDs), nil
+}
+
+// flushLikeEvents 在持有点赞回写锁时分批回写点赞事件，每批之前调用 renew 续期锁
+// 每批事件原子地移到处理中列表，在一个事务中写入，提交后才删除处理中列表，中途失败时下次会重新回写这一批，点赞关系的写入是幂等的
+func (a *ArticleService) flushLikeEvents(token int64, renew func() error) error {
+	for {
+		if err := renew(); err != nil {
+			return err
+		}
+		events, err := a.articleCacheRepository.TakeLikeEvents(a.config.Article.LikeFlushBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		likes, unlikes := mergeLikeEvents(events)
+		tx := a.articleRepository.DB.Begin()
+		if tx.Error != nil {
+			return tx.Error
+		}
+		if err := a.checkFence(tx, token); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := a.articleRepository.AddLikeRecords(tx, likes); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := a.articleRepository.RemoveLikeRecords(tx, unlikes); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return err
+		}
+
+		if err := a.articleCacheRepository.FinishLikeEvents(); err != nil {
+			return err
+		}
+		log.Printf("回写点赞事件%d条", len(events))
+	}
+}
+
+// withLikeFlushLock 持有点赞回写锁执行 fn，fn 在每批处理之前调用 renew 续期锁
+// 上一批耗时过长导致锁被其他实例获取时 renew 返回错误，fn 应当停止处理
+func (a *ArticleService) withLikeFlushLock(fn func(renew func() error) error) error {
+	owner := strconv.FormatInt(rand.Int63(), 36)
+	if err := a.acquireLikeFlushLock(owner); err != nil {
+		return err
+	}
+	defer func() {
+		if err := a.articleCacheRepository.ReleaseLikeFlushLock(owner); err != nil {
+			log.Printf("释放点赞回写锁失败: %v", err)
+		}
+	}()
+	return fn(func() error {
+		ok, err := a.articleCacheRepository.AcquireLikeFlushLock(owner, a.config.Article.LikeFlushLockTTL)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("点赞回写锁已被其他实例获取")
+		}
+		return nil
+	})
+}
+
+// acquireLikeFlushLock 等待并获取点赞回写锁，最多等待 LikeFlushLockTTL，此时持有者即使异常退出锁也已过期
+func (a *ArticleService) acquireLikeFlushLock(owner string) error {
+	deadline := time.Now().Add(a.config.Article.LikeFlushLockTTL)
+	for {
+		ok, err := a.articleCacheRepository.AcquireLikeFlushLock(owner, a.config.Article.LikeFlushLockTTL)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("500: 等待点赞回写锁超时")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// checkFence 校验点赞回写任务的防护令牌，持有旧令牌的实例不能再写入
+func (a *ArticleService) checkFence(tx *gorm.DB, token int64) error {
+	if token == 0 {
+		return nil
+	}
+	return a.jobService.CheckFence(tx, token)
+}
+
+// mergeLikeEvents 合并同一用户对同一文章的多次操作，只保留最后一次的结果
+func mergeLikeEvents(events []*article_model.LikeEvent) ([]*article_model.ArticleLike, []*article_model.ArticleLike) {
+	type likeKey struct{ articleID, userID int }
+	last := make(map[likeKey]int)
+	var order []likeKey
+	for _, event := range events {
+		if event == nil {
+			continue
+		}
+		key := likeKey{event.ArticleID, event.UserID}
+		if _, ok := last[key]; !ok {
+			order = append(order, key)
+		}
+		last[key] = event.Delta
+	}
+
+	var likes, unlikes []*article_model.ArticleLike
+	now := time.Now()
+	for _, key := range order {
+		like := &article_model.ArticleLike{ArticleID: key.articleID, UserID: key.userID, CreateAt: now}
+		if last[key] > 0 {
+			likes = append(likes, like)
+		} else {
+			unlikes = append(unlikes, like)
+		}
+	}
+	return likes, unlikes
+}
+
+// RebuildLikes 以 MySQL 中的点赞关系为准重建缓存中的点赞集合和点赞数，并同步文章表中的点赞数，用于 Redis 数据丢失后的恢复
+// 重建期间持有点赞回写锁：先回写已有的点赞事件，之后产生的事件留在事件列表中，由 ResetLikes 重放到重建后的点赞集合
+func (a *ArticleService) RebuildLikes() error {
+	err := a.withLikeFlushLock(func(renew func() error) error {
+		if err := a.flushLikeEvents(0, renew); err != nil {
+			return err
+		}
+		articleIDs, err := a.articleRepository.GetAllArticleIDs()
+		if err != nil {
+			return err
+		}
+		likes := make(map[int]int, len(articleIDs))
+		for batch := range slices.Chunk(articleIDs, a.config.Article.RebuildBatchSize) {
+			if err := renew(); err != nil {
+				return err
+			}
+			userIDs, err := a.articleRepository.GetLikeUserIDsByArticleIDs(batch)
+			if err != nil {
+				return err
+			}
+			if err := a.articleCacheRepository.ResetLikes(batch, userIDs); err != nil {
+				return err
+			}
+			for _, articleID := range batch {
+				likes[articleID] = len(userIDs[articleID])
+			}
+		}
+		if err := a.articleRepository.BatchUpdateLikes(a.articleRepository.DB, likes, a.config.Article.LikeFlushBatchSize); err != nil {
+			return err
+		}
+		log.Printf("重建%d篇文章的点赞数据完成", len(articleIDs))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ArticleService.RebuildLikes err: 500: %w", err)
+	}
+	return nil
+}
+
+// AddLikes 点赞文章，重复点赞不会报错，也不会重复计数
+func (a *ArticleService) AddLikes(articleID int, userID int) (*article_model.LikeResult, error) {
+	like, changed, err := a.articleCacheRepository.AddLikes(articleID, userID, a.config.Article.Hot.MaxSize)
+	if errors.Is(err, article_repository.ErrArticleNotCached) {
+		// 基本文章不在缓存中时先由 MySQL 加载，文章确实存在时再点赞一次
+		basicArticle, loadErr := a.getBasicArticle(articleID)
+		if loadErr != nil {
+			return nil, fmt.Errorf("ArticleService.AddLikes err: 500: %w", loadErr)
+		}
+		if basicArticle != nil {
+			like, changed, err = a.articleCacheRepository.AddLikes(articleID, userID, a.config.Article.Hot.MaxSize)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, article_repository.ErrArticleNotCached) {
+			return nil, fmt.Errorf("ArticleService.AddLikes err: 400:%w", err)
+		}
+		return nil, fmt.Errorf("ArticleService.AddLikes err: 500: %w", err)
+	}
+	return &article_model.LikeResult{Like: like, Liked: true, Changed: changed}, nil
+}
+
+// RemoveLikes 取消点赞文章，未点赞时取消不会报错
+func (a *ArticleService) RemoveLikes(articleID int, userID int) (*article_model.LikeResult, error) {
+	like, changed, err := a.articleCacheRepository.RemoveLikes(articleID, userID, a.config.Article.Hot.MaxSize)
+	if errors.Is(err, article_repository.ErrArticleNotCached) {
+		// 基本文章不在缓存中时先由 MySQL 加载，文章确实存在时再取消点赞一次
+		basicArticle, loadErr := a.getBasicArticle(articleID)
+		if loadErr != nil {
+			return nil, fmt.Errorf("ArticleService.RemoveLikes err: 500: %w", loadErr)
+		}
+		if basicArticle != nil {
+			like, changed, err = a.articleCacheRepository.RemoveLikes(articleID, userID, a.config.Article.Hot.MaxSize)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, article_repository.ErrArticleNotCached) {
+			return nil, fmt.Errorf("ArticleService.RemoveLikes err: 400:%w", err)
+		}
+		return nil, fmt.Errorf("ArticleService.RemoveLikes err: 500: %w", err)
+	}
+	return &article_model.LikeResult{Like: like, Liked: false, Changed: changed}, nil
+}
+
+// GetLikers 按点赞时间从新到旧分页获取点赞了文章的用户
+// 点赞关系由点赞回写任务写入 MySQL，最近的点赞要等下一次回写后才会出现在列表中
+func (a *ArticleService) GetLikers(articleID int, page int, size int) (*article_model.ArticleLikerPage, error) {
+	basicArticle, err := a.getBasicArticle(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetLikers err: 500: %w", err)
+	}
+	if basicArticle == nil {
+		return nil, fmt.Errorf("ArticleService.GetLikers err: 400:文章不存在")
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = a.config.Article.LikerPageSize
+	}
+	size = min(size, a.config.Article.LikerMaxPageSize)
+	likers, total, err := a.articleRepository.GetLikers(articleID, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetLikers err: 500: %w", err)
+	}
+	return &article_model.ArticleLikerPage{
+		Total:  total,
+		Page:   page,
+		Size:   size,
+		Likers: likers,
+	}, nil
+}
+
+// AddFavorite 收藏文章，重复收藏不会报错，也不会重复计数
+func (a *ArticleService) AddFavorite(articleID int, userID int) (*article_model.FavoriteResult, error) {
+	basicArticle, err := a.getBasicArticle(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.AddFavorite err: 500: %w", err)
+	}
+	if basicArticle == nil {
+		return nil, fmt.Errorf("ArticleService.AddFavorite err: 400:文章不存在")
+	}
+	result, err := a.changeFavorite(articleID, userID, true)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.AddFavorite err: 500: %w", err)
+	}
+	return result, nil
+}
+
+// RemoveFavorite 取消收藏文章，未收藏时取消不会报错；文章已被删除时也可以取消收藏
+func (a *ArticleService) RemoveFavorite(articleID int, userID int) (*article_model.FavoriteResult, error) {
+	result, err := a.changeFavorite(articleID, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RemoveFavorite err: 500: %w", err)
+	}
+	return result, nil
+}
+
+// changeFavorite 修改收藏关系，收藏关系以 MySQL 为准
+// 收藏状态改变时在同一个事务中写入发件箱，由发件箱重新统计缓存中的收藏数；
+// 提交后再修改已缓存的收藏列表，修改失败时删除收藏列表，下次读取时由 MySQL 重新加载
+func (a *ArticleService) changeFavorite(articleID int, userID int, add bool) (*article_model.FavoriteResult, error) {
+	now := time.Now()
+	var changed bool
+	var events []*article_model.ArticleOutbox
+	err := a.articleRepository.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if add {
+			changed, err = a.articleRepository.AddFavorite(tx, &article_model.ArticleFavorite{
+				ArticleID: articleID,
+				UserID:    userID,
+				CreateAt:  now,
+			})
+		} else {
+			changed, err = a.articleRepository.RemoveFavorite(tx, articleID, userID)
+		}
+		if err != nil || !changed {
+			return err
+		}
+		events = []*article_model.ArticleOutbox{{
+			ArticleID:   articleID,
+			Action:      article_model.OutboxSyncFavorite,
+			AvailableAt: now,
+			CreateAt:    now,
+		}}
+		return a.articleRepository.AddOutboxEvents(tx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		a.relayOutboxEvents(events)
+		if add {
+			err = a.articleCacheRepository.AddFavorite(userID, articleID, now, a.config.Article.FavoriteCacheTTL)
+		} else {
+			err = a.articleCacheRepository.RemoveFavorite(userID, articleID, a.config.Article.FavoriteCacheTTL)
+		}
+		if err != nil {
+			log.Printf("修改用户%d的收藏列表缓存失败: %v", userID, err)
+			if err := a.articleCacheRepository.DeleteFavorites(userID, a.config.Article.FavoriteCacheTTL); err != nil {
+				log.Printf("删除用户%d的收藏列表缓存失败: %v", userID, err)
+			}
+		}
+	}
+
+	counts, err := a.articleRepository.GetFavoriteCounts([]int{articleID})
+	if err != nil {
+		return nil, err
+	}
+	return &article_model.FavoriteResult{Favorite: counts[articleID], Favorited: add, Changed: changed}, nil
+}
+
+// GetFavorites 按收藏时间从新到旧分页获取用户收藏的文章，收藏列表不在缓存中时由 MySQL 加载
+// 已被删除的文章不在结果中，但仍计入总数，用户可以取消收藏
+func (a *ArticleService) GetFavorites(userID int, page int, size int) (*article_model.FavoriteArticlePage, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = a.config.Article.FavoritePageSize
+	}
+	size = min(size, a.config.Article.FavoriteMaxPageSize)
+	offset := (page - 1) * size
+	favorites, total, cached, err := a.articleCacheRepository.GetFavorites(userID, offset, size)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetFavorites err: 500: %w", err)
+	}
+	if !cached {
+		// 先取版本号再查询 MySQL，查询期间有新的收藏或取消收藏时不写入缓存，由下次读取重新加载
+		version, err := a.articleCacheRepository.GetFavoriteVersion(userID)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.GetFavorites err: 500: %w", err)
+		}
+		all, err := a.articleRepository.GetFavoritesByUserID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.GetFavorites err: 500: %w", err)
+		}
+		if _, err := a.articleCacheRepository.LoadFavorites(userID, all, version, a.config.Article.FavoriteCacheTTL); err != nil {
+			log.Printf("缓存用户%d的收藏列表失败: %v", userID, err)
+		}
+		total = int64(len(all))
+		favorites = all[min(offset, len(all)):min(offset+size, len(all))]
+	}
+
+	articleIDs := make([]int, len(favorites))
+	for i, favorite := range favorites {
+		articleIDs[i] = favorite.ArticleID
+	}
+	basicArticles, err := a.articleCacheRepository.GetBasicArticlesByIDs(articleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetFavorites err: 500: %w", err)
+	}
+	articles := make([]*article_model.FavoriteArticle, 0, len(favorites))
+	pageArticles := make([]*article_model.BasicArticle, 0, len(favorites))
+	for _, favorite := range favorites {
+		basicArticle, ok := basicArticles[favorite.ArticleID]
+		if !ok {
+			if basicArticle, err = a.getBasicArticle(favorite.ArticleID); err != nil {
+				return nil, fmt.Errorf("ArticleService.GetFavorites err: 500: %w", err)
+			}
+			if basicArticle == nil {
+				continue
+			}
+		}
+		articles = append(articles, &article_model.FavoriteArticle{BasicArticle: basicArticle, FavoriteAt: favorite.CreateAt})
+		pageArticles = append(pageArticles, basicArticle)
+	}
+	if err := a.fillLikedByMe(pageArticles, userID); err != nil {
+		return nil, fmt.Errorf("ArticleService.GetFavorites err: 500: %w", err)
+	}
+	return &article_model.FavoriteArticlePage{
+		Total:    total,
+		Page:     page,
+		Size:     size,
+		Articles: articles,
+	}, nil
+}
+
+// UpdateArticle 更新文章
+// 更新前删除一次完整文章缓存；文章、标签的修改与缓存发件箱在同一个事务中写入，提交后由发件箱同步基本文章，
+// 并在延时后再删除一次完整文章缓存，避免并发读取在两次删除之间把旧数据写回缓存
+func (a *ArticleService) UpdateArticle(article *article_model.Article) error {
+	checkResult, err := a.filterArticle(article)
+	if err != nil {
+		return fmt.Errorf("ArticleService.UpdateArticle err: %w", err)
+	}
+	existing, err := a.articleRepository.GetArticleByID(article.ID)
+	if err != nil {
+		return fmt.Errorf("ArticleService.UpdateArticle err: 500: %w", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("ArticleService.UpdateArticle err: 400:文章不存在")
+	}
+
+	//第一次删除缓存
+	if err := a.articleCacheRepository.DeleteFullArticle(article.ID); err != nil {
+		return fmt.Errorf("ArticleService.UpdateArticle err: 500: %w", err)
+	}
+
+	events := a.newOutboxEvents(article.ID, true)
+	err = a.articleRepository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := a.articleRepository.UpdateArticle(tx, article); err != nil {
+			return err
+		}
+		//更新文章标签，Tags 为 nil 时表示不修改标签
+		if article.Tags != nil {
+			if err := a.articleRepository.ReplaceTags(tx, article.ID, article.Tags); err != nil {
+				return err
+			}
+		}
+		if checkResult.Action == moderation_model.ActionReview {
+			if err := a.moderationService.Submit(tx, moderation_model.ContentArticle, article.ID, existing.ManagerID,
+				article.Title+"\n"+article.Content, checkResult.Words); err != nil {
+				return err
+			}
+		}
+		return a.articleRepository.AddOutboxEvents(tx, events)
+	})
+	if err != nil {
+		return fmt.Errorf("ArticleService.UpdateArticle err: 500: %w", err)
+	}
+
+	a.relayOutboxEvents(events)
+	return nil
+}
+
+// newOutboxEvents 生成文章修改后需要执行的缓存操作，delayDelete 为 true 时追加延时的第二次删除
+func (a *ArticleService) newOutboxEvents(articleID int, delayDelete bool) []*article_model.ArticleOutbox {
+	now := time.Now()
+	events := []*article_model.ArticleOutbox{{
+		ArticleID:   articleID,
+		Action:      article_model.OutboxSyncArticle,
+		AvailableAt: now,
+		CreateAt:    now,
+	}}
+	if delayDelete {
+		events = append(events, &article_model.ArticleOutbox{
+			ArticleID:   articleID,
+			Action:      article_model.OutboxDeleteFull,
+			AvailableAt: now.Add(a.config.Article.CacheDeleteDelay),
+			CreateAt:    now,
+		})
+	}
+	return events
+}
+
+// RelayOutboxJob 调度器中的缓存发件箱任务，分批执行所有到期的发件箱操作
+func (a *ArticleService) RelayOutboxJob(ctx context.Context, jc *job_service.JobContext) error {
+	for ctx.Err() == nil {
+		events, err := a.articleRepository.GetDueOutboxEvents(time.Now(), a.config.Article.OutboxBatchSize)
+		if err != nil {
+			return fmt.Errorf("ArticleService.RelayOutboxJob err: %w", err)
+		}
+		a.relayOutboxEvents(events)
+		if len(events) < a.config.Article.OutboxBatchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// relayOutboxEvents 执行已到期的发件箱操作，成功后删除，失败时按指数退避推迟重试
+// 事务提交后会立即执行一次，失败或实例宕机时由发件箱任务补上；操作都是幂等的，重复执行不影响结果
+func (a *ArticleService) relayOutboxEvents(events []*article_model.ArticleOutbox) {
+	now := time.Now()
+	for _, event := range events {
+		if event.AvailableAt.After(now) {
+			continue
+		}
+		if err := a.applyOutboxEvent(event); err != nil {
+			attempts := event.Attempts + 1
+			backoff := min(time.Second<<min(attempts, 16), a.config.Article.OutboxMaxBackoff)
+			log.Printf("执行文章%d的缓存操作%s失败，%s后重试: %v", event.ArticleID, event.Action, backoff, err)
+			if err := a.articleRepository.DeferOutboxEvent(event.ID, attempts, utils.Substring(err.Error(), 500), now.Add(backoff)); err != nil {
+				log.Printf("推迟缓存操作%d失败: %v", event.ID, err)
+			}
+			continue
+		}
+		if err := a.articleRepository.DeleteOutboxEvent(event.ID); err != nil {
+			log.Printf("删除缓存操作%d失败: %v", event.ID, err)
+		}
+	}
+}
+
+// applyOutboxEvent 执行一条发件箱操作
+func (a *ArticleService) applyOutboxEvent(event *article_model.ArticleOutbox) error {
+	switch event.Action {
+	case article_model.OutboxSyncArticle:
+		basicArticle, err := a.loadBasicArticle(event.ArticleID)
+		if err != nil {
+			return err
+		}
+		if basicArticle == nil {
+			return nil
+		}
+		if err := a.articleCacheRepository.SyncBasicArticle(basicArticle); err != nil {
+			return err
+		}
+		if err := a.articleCacheRepository.DeleteArticleNull(event.ArticleID); err != nil {
+			return err
+		}
+		if err := a.articleCacheRepository.RefreshHotScore(event.ArticleID, a.config.Article.Hot.MaxSize); err != nil {
+			return err
+		}
+		return a.articleCacheRepository.DeleteFullArticle(event.ArticleID)
+	case article_model.OutboxDeleteFull:
+		return a.articleCacheRepository.DeleteFullArticle(event.ArticleID)
+	case article_model.OutboxPublish:
+		basicArticle, err := a.loadBasicArticle(event.ArticleID)
+		if err != nil {
+			return err
+		}
+		if basicArticle == nil {
+			return nil
+		}
+		for _, handler := range a.publishHandlers {
+			if err := handler(basicArticle); err != nil {
+				return err
+			}
+		}
+		return nil
+	case article_model.OutboxSyncFavorite:
+		counts, err := a.articleRepository.GetFavoriteCounts([]int{event.ArticleID})
+		if err != nil {
+			return err
+		}
+		return a.articleCacheRepository.SetFavoriteCount(event.ArticleID, counts[event.ArticleID])
+	default:
+		log.Printf("未知的缓存操作: %s", event.Action)
+		return nil
+	}
+}
+
+// toBasicArticle 由完整文章生成首页展示用的基本文章，摘要取自正文的纯文本，封面取正文中的第一张图片
+func (a *ArticleService) toBasicArticle(article *article_model.Article) *article_model.BasicArticle {
+	return &article_model.BasicArticle{
+		ID:          article.ID,
+		Title:       article.Title,
+		Content:     utils.Excerpt(utils.ArticlePlainText(article.Content, article.Format), a.config.Article.ExcerptLength),
+		Kind:        article.Kind,
+		Like:        article.Like,
+		Comment:     article.Comment,
+		View:        article.View,
+		Favorite:    article.Favorite,
+		ManagerID:   article.ManagerID,
+		Tags:        article.Tags,
+		Cover:       utils.ExtractCoverImage(article.Content, article.Format),
+		CreateAt:    article.CreateAt,
+		PinPriority: article.PinPriority,
+		PinExpireAt: article.PinExpireAt,
+		Archived:    article.Archived,
+		RequireAck:  article.RequireAck,
+	}
+}
+
+// filterArticle 对文章标题和正文做敏感词处理，返回两者中最严格的检查结果
+// 文章由管理员发布，命中审核词时照常发布并提交审核，审核拒绝后再对敏感词打码
+func (a *ArticleService) filterArticle(article *article_model.Article) (*moderation_model.CheckResult, error) {
+	title, titleResult, err := a.moderationService.Filter(article.Title)
+	if err != nil {
+		return nil, err
+	}
+	content, contentResult, err := a.moderationService.Filter(article.Content)
+	if err != nil {
+		return nil, err
+	}
+	article.Title = title
+	article.Content = content
+
+	result := contentResult
+	if moderation_model.ActionLevel(titleResult.Action) > moderation_model.ActionLevel(contentResult.Action) {
+		result = titleResult
+	}
+	result.Words = append(titleResult.Words, contentResult.Words...)
+	return result, nil
+}
+
+// RejectModeratedArticle 审核拒绝后将文章标题和正文中的敏感词打码
+// 文章的修改与缓存发件箱在审核的事务中写入，提交后同步缓存
+func (a *ArticleService) RejectModeratedArticle(tx *gorm.DB, record *moderation_model.ModerationRecord) (func(), error) {
+	article, err := a.articleRepository.GetArticleByID(record.ContentID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RejectModeratedArticle err: %w", err)
+	}
+	if article == nil {
+		return nil, nil
+	}
+	if err := a.articleCacheRepository.DeleteFullArticle(article.ID); err != nil {
+		return nil, fmt.Errorf("ArticleService.RejectModeratedArticle err: %w", err)
+	}
+
+	events := a.newOutboxEvents(article.ID, true)
+	if err := a.articleRepository.UpdateArticle(tx, &article_model.Article{
+		ID:      article.ID,
+		Title:   a.moderationService.Mask(article.Title),
+		Content: a.moderationService.Mask(article.Content),
+	}); err != nil {
+		return nil, fmt.Errorf("ArticleService.RejectModeratedArticle err: %w", err)
+	}
+	if err := a.articleRepository.AddOutboxEvents(tx, events); err != nil {
+		return nil, fmt.Errorf("ArticleService.RejectModeratedArticle err: %w", err)
+	}
+	return func() { a.relayOutboxEvents(events) }, nil
+}
+
+// GetAllArticleByTag 获取某个标签下的所有基本文章，并标记 userID 是否已点赞
+func (a *ArticleService) GetAllArticleByTag(tag string, userID int) ([]*article_model.BasicArticle, error) {
+	articles, err := a.articleCacheRepository.GetAllArticleByTag(tag)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAllArticleByTag err: 500: %w", err)
+	}
+	if err := a.fillLikedByMe(articles, userID); err != nil {
+		return nil, fmt.Errorf("ArticleService.GetAllArticleByTag err: 500: %w", err)
+	}
+	return articles, nil
+}
+
+func (a *ArticleService) AutocompleteTags(prefix string, n int) ([]string, error) {
+	tags, err := a.articleCacheRepository.AutocompleteTags(prefix, n)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.AutocompleteTags err: %w", err)
+	}
+	return tags, nil
+}
+
+func (a *ArticleService) GetPopularTags(n int) ([]*article_model.PopularTag, error) {
+	tags, err := a.articleCacheRepository.GetPopularTags(n)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetPopularTags err: %w", err)
+	}
+	return tags, nil
+}
+
+// SearchArticles 按关键词搜索文章，可按类型过滤，返回带高亮片段的分页结果
+func (a *ArticleService) SearchArticles(keyword string, kinds []string, page int, size int) (*article_model.SearchArticlePage, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = a.config.Article.SearchPageSize
+	}
+	size = min(size, a.config.Article.SearchMaxPageSize)
+
+	articles, total, err := a.articleRepository.SearchArticles(keyword, kinds, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.SearchArticles err: 500: %w", err)
+	}
+
+	terms := utils.SegmentKeyword(keyword, 2)
+	for _, article := range articles {
+		article.Title = utils.HighlightFragment(article.Title, terms, 0)
+		article.Content = utils.HighlightFragment(utils.ArticlePlainText(article.Content, article.Format), terms, a.config.Article.SearchFragmentLength)
+	}
 
+	return &article_model.SearchArticlePage{
+		Total:    total,
+		Page:     page,
+		Size:     size,
+		Articles: articles,
+	}, nil
 }
@@ -0,0 +1,41 @@
+package article_service
+
+import (
+	"huancuilou/internal/article/article_model"
+	"slices"
+	"testing"
+)
+
+func TestMergeLikeEvents(t *testing.T) {
+	events := []*article_model.LikeEvent{
+		{ArticleID: 1, UserID: 10, Delta: 1},
+		{ArticleID: 2, UserID: 10, Delta: 1},
+		nil,
+		{ArticleID: 1, UserID: 10, Delta: -1},
+		{ArticleID: 1, UserID: 11, Delta: -1},
+		{ArticleID: 1, UserID: 11, Delta: 1},
+		{ArticleID: 3, UserID: 12, Delta: -1},
+	}
+	likes, unlikes := mergeLikeEvents(events)
+
+	pairs := func(likes []*article_model.ArticleLike) [][2]int {
+		var result [][2]int
+		for _, like := range likes {
+			result = append(result, [2]int{like.ArticleID, like.UserID})
+		}
+		return result
+	}
+	// 按每个用户与文章第一次出现的顺序输出最后一次操作的结果
+	wantLikes := [][2]int{{2, 10}, {1, 11}}
+	wantUnlikes := [][2]int{{1, 10}, {3, 12}}
+	if got := pairs(likes); !slices.Equal(got, wantLikes) {
+		t.Errorf("likes = %v, want %v", got, wantLikes)
+	}
+	if got := pairs(unlikes); !slices.Equal(got, wantUnlikes) {
+		t.Errorf("unlikes = %v, want %v", got, wantUnlikes)
+	}
+
+	if likes, unlikes := mergeLikeEvents(nil); len(likes) != 0 || len(unlikes) != 0 {
+		t.Errorf("mergeLikeEvents(nil) = %v, %v, want empty", likes, unlikes)
+	}
+}
@@ -0,0 +1,128 @@
+package article_service
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/internal/article/article_model"
+	"huancuilou/internal/job/job_service"
+	"log"
+	"time"
+)
+
+// PinExpireJob 取消过期置顶任务的名称
+const PinExpireJob = "article_pin_expire"
+
+// validatePin 校验置顶参数，priority 为 0 表示不置顶
+func validatePin(priority int, expireAt *time.Time, now time.Time) error {
+	if priority < 0 {
+		return fmt.Errorf("400:置顶优先级不能小于0")
+	}
+	if expireAt != nil && priority == 0 {
+		return fmt.Errorf("400:没有置顶的文章不能设置置顶过期时间")
+	}
+	if expireAt != nil && !expireAt.After(now) {
+		return fmt.Errorf("400:置顶过期时间必须晚于当前时间")
+	}
+	return nil
+}
+
+// PinArticle 置顶文章或修改置顶的优先级与过期时间，已归档的文章置顶后恢复到列表中
+// 与修改文章一样在事务中写入缓存发件箱，提交后同步基本文章与置顶文章集合
+func (a *ArticleService) PinArticle(articleID int, pin *article_model.PinRequest) error {
+	if pin.Priority <= 0 {
+		return fmt.Errorf("ArticleService.PinArticle err: 400:置顶优先级必须大于0")
+	}
+	if err := validatePin(pin.Priority, pin.ExpireAt, time.Now()); err != nil {
+		return fmt.Errorf("ArticleService.PinArticle err: %w", err)
+	}
+	if err := a.updatePin(articleID, pin); err != nil {
+		return fmt.Errorf("ArticleService.PinArticle err: %w", err)
+	}
+	return nil
+}
+
+// UnpinArticle 取消置顶，文章回到按发布时间排列的位置
+func (a *ArticleService) UnpinArticle(articleID int) error {
+	if err := a.updatePin(articleID, &article_model.PinRequest{}); err != nil {
+		return fmt.Errorf("ArticleService.UnpinArticle err: %w", err)
+	}
+	return nil
+}
+
+// updatePin 修改文章的置顶状态
+func (a *ArticleService) updatePin(articleID int, pin *article_model.PinRequest) error {
+	return a.updateArticleState(articleID, func(tx *gorm.DB) error {
+		return a.articleRepository.UpdatePin(tx, articleID, pin)
+	})
+}
+
+// updateArticleState 修改文章的置顶、确认已读等状态，修改与缓存发件箱在同一个事务中写入，提交后同步基本文章
+func (a *ArticleService) updateArticleState(articleID int, update func(tx *gorm.DB) error) error {
+	existing, err := a.articleRepository.GetArticleByID(articleID)
+	if err != nil {
+		return fmt.Errorf("500: %w", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("400:文章不存在")
+	}
+
+	events := a.newOutboxEvents(articleID, true)
+	err = a.articleRepository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := update(tx); err != nil {
+			return err
+		}
+		return a.articleRepository.AddOutboxEvents(tx, events)
+	})
+	if err != nil {
+		return fmt.Errorf("500: %w", err)
+	}
+	a.relayOutboxEvents(events)
+	return nil
+}
+
+// ExpirePinsJob 调度器中的取消过期置顶任务
+func (a *ArticleService) ExpirePinsJob(ctx context.Context, jc *job_service.JobContext) error {
+	_, err := a.ExpirePins(ctx)
+	return err
+}
+
+// ExpirePins 分批取消置顶已过期的文章，设置了过期后归档的文章同时归档并移出类型与标签列表，返回处理的文章数
+// 状态修改与缓存发件箱在同一个事务中写入，缓存更新失败时由发件箱任务重试
+func (a *ArticleService) ExpirePins(ctx context.Context) (int, error) {
+	total := 0
+	for ctx.Err() == nil {
+		now := time.Now()
+		pins, err := a.articleRepository.GetExpiredPins(now, a.config.Article.PinExpireBatchSize)
+		if err != nil {
+			return total, fmt.Errorf("ArticleService.ExpirePins err: %w", err)
+		}
+		if len(pins) == 0 {
+			return total, nil
+		}
+
+		articleIDs := make([]int, len(pins))
+		var events []*article_model.ArticleOutbox
+		for i, pin := range pins {
+			articleIDs[i] = pin.ID
+			events = append(events, a.newOutboxEvents(pin.ID, true)...)
+		}
+		var expired int
+		err = a.articleRepository.DB.Transaction(func(tx *gorm.DB) error {
+			if expired, err = a.articleRepository.ExpirePins(tx, articleIDs, now); err != nil {
+				return err
+			}
+			return a.articleRepository.AddOutboxEvents(tx, events)
+		})
+		if err != nil {
+			return total, fmt.Errorf("ArticleService.ExpirePins err: %w", err)
+		}
+		a.relayOutboxEvents(events)
+		total += expired
+		log.Printf("取消过期置顶的文章%d篇", expired)
+		if len(pins) < a.config.Article.PinExpireBatchSize {
+			return total, nil
+		}
+	}
+	return total, ctx.Err()
+}
@@ -0,0 +1,36 @@
+package article_service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidatePin(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	if err := validatePin(0, nil, now); err != nil {
+		t.Errorf("不置顶 err = %v", err)
+	}
+	if err := validatePin(1, nil, now); err != nil {
+		t.Errorf("永久置顶 err = %v", err)
+	}
+	if err := validatePin(2, &future, now); err != nil {
+		t.Errorf("定时置顶 err = %v", err)
+	}
+
+	if err := validatePin(-1, nil, now); err == nil {
+		t.Error("优先级为负数时应返回错误")
+	}
+	if err := validatePin(0, &future, now); err == nil {
+		t.Error("不置顶的文章设置过期时间时应返回错误")
+	}
+	if err := validatePin(1, &past, now); err == nil {
+		t.Error("过期时间早于当前时间时应返回错误")
+	}
+	// 过期时间必须严格晚于当前时间
+	if err := validatePin(1, &now, now); err == nil {
+		t.Error("过期时间等于当前时间时应返回错误")
+	}
+}
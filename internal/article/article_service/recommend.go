@@ -0,0 +1,194 @@
+package article_service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"huancuilou/internal/article/article_model"
+	"huancuilou/internal/job/job_service"
+	"log"
+	"maps"
+	"math"
+	"slices"
+)
+
+// SimilarRecomputeJob 重新计算文章相似度任务的名称
+const SimilarRecomputeJob = "article_similar_recompute"
+
+// RecomputeSimilarJob 调度器中的文章相似度计算任务
+func (a *ArticleService) RecomputeSimilarJob(ctx context.Context, jc *job_service.JobContext) error {
+	return a.RecomputeSimilarArticles()
+}
+
+// RecomputeSimilarArticles 由点赞集合计算每篇文章的相似文章：两篇文章的相似度为共同点赞用户数 / sqrt(两篇文章点赞数之积)
+// 先把所有点赞集合读入内存，按用户倒排后统计每对文章的共同点赞数
+func (a *ArticleService) RecomputeSimilarArticles() error {
+	cfg := a.config.Article.Recommend
+	articleIDs, err := a.articleRepository.GetAllArticleIDs()
+	if err != nil {
+		return fmt.Errorf("ArticleService.RecomputeSimilarArticles err: %w", err)
+	}
+	likeCounts := make(map[int]int, len(articleIDs))
+	userLikes := make(map[int][]int)
+	for batch := range slices.Chunk(articleIDs, a.config.Article.RebuildBatchSize) {
+		likeSets, err := a.articleCacheRepository.GetLikeSets(batch)
+		if err != nil {
+			return fmt.Errorf("ArticleService.RecomputeSimilarArticles err: %w", err)
+		}
+		for articleID, userIDs := range likeSets {
+			likeCounts[articleID] = len(userIDs)
+			for _, userID := range userIDs {
+				userLikes[userID] = append(userLikes[userID], articleID)
+			}
+		}
+	}
+
+	coLikes := make(map[int]map[int]int)
+	for _, liked := range userLikes {
+		// 点赞集合中没有点赞时间，点赞数超过上限时按文章 ID 从大到小保留最新发布的文章，
+		// 偏向用户对新文章的点赞，而不是按文章读取的批次顺序保留最早的文章
+		slices.SortFunc(liked, func(x, y int) int { return cmp.Compare(y, x) })
+		liked = liked[:min(len(liked), cfg.MaxUserLikes)]
+		for i, x := range liked {
+			for _, y := range liked[i+1:] {
+				if coLikes[x] == nil {
+					coLikes[x] = make(map[int]int)
+				}
+				if coLikes[y] == nil {
+					coLikes[y] = make(map[int]int)
+				}
+				coLikes[x][y]++
+				coLikes[y][x]++
+			}
+		}
+	}
+
+	similar := make(map[int][]*article_model.SimilarArticle, len(coLikes))
+	for x, counts := range coLikes {
+		articles := make([]*article_model.SimilarArticle, 0, len(counts))
+		for y, count := range counts {
+			articles = append(articles, &article_model.SimilarArticle{
+				ArticleID: y,
+				Score:     float64(count) / math.Sqrt(float64(likeCounts[x]*likeCounts[y])),
+			})
+		}
+		slices.SortFunc(articles, func(p, q *article_model.SimilarArticle) int {
+			return cmp.Or(cmp.Compare(q.Score, p.Score), cmp.Compare(p.ArticleID, q.ArticleID))
+		})
+		similar[x] = articles[:min(len(articles), cfg.SimilarSize)]
+	}
+
+	for batch := range slices.Chunk(articleIDs, a.config.Article.RebuildBatchSize) {
+		if err := a.articleCacheRepository.SaveSimilarArticles(batch, similar); err != nil {
+			return fmt.Errorf("ArticleService.RecomputeSimilarArticles err: %w", err)
+		}
+	}
+	log.Printf("计算文章相似度完成，有相似文章的文章数: %d", len(similar))
+	return nil
+}
+
+// RecommendArticles 猜你喜欢：以用户最近点赞的文章为兴趣来源，
+// 候选文章来自这些文章的相似文章以及偏好的类型、标签下最新的文章，按推荐分从高到低排列；
+// 没有足够的候选文章时用热门文章补足。用户点赞过和最近浏览过的文章不会被推荐
+// 点赞记录来自点赞回写后的 MySQL，最近的点赞要等下一次回写后才会影响推荐
+func (a *ArticleService) RecommendArticles(userID int, size int) ([]*article_model.RecommendedArticle, error) {
+	cfg := a.config.Article.Recommend
+	if size <= 0 {
+		size = cfg.PageSize
+	}
+	size = min(size, cfg.MaxPageSize)
+	seeds, err := a.articleRepository.GetLikedArticleIDs(userID, cfg.SeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RecommendArticles err: 500: %w", err)
+	}
+	seedArticles, err := a.GetBasicArticles(seeds, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RecommendArticles err: 500: %w", err)
+	}
+
+	// 类型和标签的偏好为点赞过的文章中该类型、标签出现的比例
+	kindAffinity := make(map[string]float64)
+	tagAffinity := make(map[string]float64)
+	for _, article := range seedArticles {
+		kindAffinity[article.Kind] += 1 / float64(len(seedArticles))
+		for _, tag := range article.Tags {
+			tagAffinity[tag] += 1 / float64(len(seedArticles))
+		}
+	}
+	similarity := make(map[int]float64)
+	similar, err := a.articleCacheRepository.GetSimilarArticles(seeds)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RecommendArticles err: 500: %w", err)
+	}
+	for _, articles := range similar {
+		for _, article := range articles {
+			similarity[article.ArticleID] += article.Score
+		}
+	}
+
+	candidates := slices.Sorted(maps.Keys(similarity))
+	tags := slices.SortedFunc(maps.Keys(tagAffinity), func(x, y string) int {
+		return cmp.Or(cmp.Compare(tagAffinity[y], tagAffinity[x]), cmp.Compare(x, y))
+	})
+	listIDs, err := a.articleCacheRepository.GetListArticleIDs(slices.Sorted(maps.Keys(kindAffinity)),
+		tags[:min(len(tags), cfg.InterestTags)], cfg.ListCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RecommendArticles err: 500: %w", err)
+	}
+	candidates = append(candidates, listIDs...)
+	// 热门文章排在候选文章的最后，推荐分相同时保持热度顺序
+	hotArticles, _, err := a.articleCacheRepository.GetHotArticles("", 0, size*2)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RecommendArticles err: 500: %w", err)
+	}
+	for _, article := range hotArticles {
+		candidates = append(candidates, article.ID)
+	}
+
+	excluded := make(map[int]bool, len(seeds))
+	for _, id := range seeds {
+		excluded[id] = true
+	}
+	read, err := a.articleCacheRepository.GetReadArticles(userID, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RecommendArticles err: 500: %w", err)
+	}
+	candidateIDs := make([]int, 0, len(candidates))
+	for _, id := range candidates {
+		if !excluded[id] && !read[id] {
+			excluded[id] = true
+			candidateIDs = append(candidateIDs, id)
+		}
+	}
+	articles, err := a.GetBasicArticles(candidateIDs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.RecommendArticles err: 500: %w", err)
+	}
+
+	recommended := make([]*article_model.RecommendedArticle, 0, len(articles))
+	for _, article := range articles {
+		if article.LikedByMe {
+			continue
+		}
+		interest := cfg.KindWeight * kindAffinity[article.Kind]
+		for _, tag := range article.Tags {
+			interest += cfg.TagWeight * tagAffinity[tag]
+		}
+		coLike := cfg.SimilarWeight * similarity[article.ID]
+		reason := article_model.RecommendReasonHot
+		if coLike > 0 {
+			reason = article_model.RecommendReasonCoLike
+		} else if interest > 0 {
+			reason = article_model.RecommendReasonInterest
+		}
+		recommended = append(recommended, &article_model.RecommendedArticle{
+			BasicArticle: article,
+			Score:        coLike + interest,
+			Reason:       reason,
+		})
+	}
+	slices.SortStableFunc(recommended, func(x, y *article_model.RecommendedArticle) int {
+		return cmp.Compare(y.Score, x.Score)
+	})
+	return recommended[:min(len(recommended), size)], nil
+}
@@ -0,0 +1,148 @@
+package article_service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"huancuilou/internal/article/article_model"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// GetSyndicationFeed 生成全部文章或某个类型的文章的 RSS 2.0 或 Atom 订阅，kind 为空时包含全部类型
+// 文章取自缓存中的类型列表与基本文章哈希表，订阅中的内容为文章摘要
+func (a *ArticleService) GetSyndicationFeed(format string, kind string) (*article_model.SyndicationFeed, error) {
+	cfg := a.config.Article.Syndication
+	if cfg.SiteURL == "" {
+		return nil, fmt.Errorf("ArticleService.GetSyndicationFeed err: 500: 没有配置站点地址，订阅不可用")
+	}
+	kinds := []string{kind}
+	if kind == "" {
+		kinds = slices.Sorted(maps.Keys(a.config.Article.KindMap))
+	}
+	// 文章 ID 随发布时间递增，合并多个类型列表后按 ID 从大到小取最新的文章
+	articleIDs, err := a.articleCacheRepository.GetListArticleIDs(kinds, nil, cfg.Size)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetSyndicationFeed err: 500: %w", err)
+	}
+	slices.Sort(articleIDs)
+	articleIDs = slices.Compact(articleIDs)
+	slices.Reverse(articleIDs)
+	articleIDs = articleIDs[:min(len(articleIDs), cfg.Size)]
+	basicArticles, err := a.articleCacheRepository.GetBasicArticlesByIDs(articleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetSyndicationFeed err: 500: %w", err)
+	}
+	articles := make([]*article_model.BasicArticle, 0, len(articleIDs))
+	for _, id := range articleIDs {
+		if article, ok := basicArticles[id]; ok {
+			articles = append(articles, article)
+		}
+	}
+	// 修改文章不会改变发布时间，Last-Modified 取这些类型的文章最后一次发布或修改的时间，秒以下的部分与 HTTP 日期一致舍去
+	lastModified, err := a.articleRepository.GetLastModified(kinds)
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetSyndicationFeed err: 500: %w", err)
+	}
+	lastModified = lastModified.Truncate(time.Second)
+
+	title := cfg.Title
+	query := ""
+	if kind != "" {
+		title = cfg.Title + " - " + kind
+		query = "?kind=" + url.QueryEscape(kind)
+	}
+	var doc interface{}
+	var contentType string
+	switch format {
+	case article_model.SyndicationRSS:
+		doc = a.buildRSS(title, articles, lastModified)
+		contentType = "application/rss+xml; charset=utf-8"
+	case article_model.SyndicationAtom:
+		doc = a.buildAtom(title, cfg.SiteURL+"/article/atom"+query, articles, lastModified)
+		contentType = "application/atom+xml; charset=utf-8"
+	default:
+		return nil, fmt.Errorf("ArticleService.GetSyndicationFeed err: 400: 不支持的订阅格式%s", format)
+	}
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.GetSyndicationFeed err: 500: %w", err)
+	}
+	body = append([]byte(xml.Header), body...)
+
+	sum := sha1.Sum(body)
+	return &article_model.SyndicationFeed{
+		Body:         body,
+		ContentType:  contentType,
+		ETag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		LastModified: lastModified,
+	}, nil
+}
+
+// articleLink 返回文章的链接
+func (a *ArticleService) articleLink(id int) string {
+	return fmt.Sprintf("%s/article/%d", a.config.Article.Syndication.SiteURL, id)
+}
+
+func (a *ArticleService) buildRSS(title string, articles []*article_model.BasicArticle, lastModified time.Time) *article_model.RSS {
+	cfg := a.config.Article.Syndication
+	channel := article_model.RSSChannel{
+		Title:       title,
+		Link:        cfg.SiteURL,
+		Description: cfg.Description,
+		Language:    "zh-cn",
+	}
+	if !lastModified.IsZero() {
+		channel.LastBuildDate = lastModified.Format(time.RFC1123Z)
+	}
+	for _, article := range articles {
+		link := a.articleLink(article.ID)
+		channel.Items = append(channel.Items, article_model.RSSItem{
+			Title:       article.Title,
+			Link:        link,
+			Description: article.Content,
+			GUID:        article_model.RSSGUID{IsPermaLink: true, Value: link},
+			PubDate:     article.CreateAt.Format(time.RFC1123Z),
+			Categories:  append([]string{article.Kind}, article.Tags...),
+		})
+	}
+	return &article_model.RSS{Version: "2.0", Channel: channel}
+}
+
+func (a *ArticleService) buildAtom(title string, selfLink string, articles []*article_model.BasicArticle, lastModified time.Time) *article_model.AtomFeed {
+	cfg := a.config.Article.Syndication
+	// 没有文章时使用固定的时间，保证内容不变时 ETag 也不变
+	if lastModified.IsZero() {
+		lastModified = time.Unix(0, 0)
+	}
+	feed := &article_model.AtomFeed{
+		Title:    title,
+		Subtitle: cfg.Description,
+		ID:       selfLink,
+		Updated:  lastModified.Format(time.RFC3339),
+		Author:   article_model.AtomAuthor{Name: cfg.Title},
+		Links: []article_model.AtomLink{
+			{Href: selfLink, Rel: "self", Type: "application/atom+xml"},
+			{Href: cfg.SiteURL, Rel: "alternate"},
+		},
+	}
+	for _, article := range articles {
+		link := a.articleLink(article.ID)
+		entry := article_model.AtomEntry{
+			Title:     article.Title,
+			ID:        link,
+			Published: article.CreateAt.Format(time.RFC3339),
+			Updated:   article.CreateAt.Format(time.RFC3339),
+			Link:      article_model.AtomLink{Href: link, Rel: "alternate"},
+			Summary:   article.Content,
+		}
+		for _, category := range append([]string{article.Kind}, article.Tags...) {
+			entry.Categories = append(entry.Categories, article_model.AtomCategory{Term: category})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
@@ -0,0 +1,305 @@
+package article_service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"huancuilou/common/utils"
+	"huancuilou/internal/article/article_model"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportArticles 按 ID 升序导出全部文章或某个类型的文章，点赞数以缓存中的点赞集合为准
+func (a *ArticleService) ExportArticles(kind string) ([]*article_model.ArticleRecord, error) {
+	var records []*article_model.ArticleRecord
+	maxID := 0
+	for {
+		articles, err := a.articleRepository.GetArticlesAfter(maxID, a.config.Article.RebuildBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.ExportArticles err: 500: %w", err)
+		}
+		if len(articles) == 0 {
+			break
+		}
+		maxID = articles[len(articles)-1].ID
+		articles = slices.DeleteFunc(articles, func(article *article_model.Article) bool {
+			return kind != "" && article.Kind != kind
+		})
+		if len(articles) == 0 {
+			continue
+		}
+
+		articleIDs := make([]int, len(articles))
+		for i, article := range articles {
+			articleIDs[i] = article.ID
+		}
+		tags, err := a.articleRepository.GetTagsByArticleIDs(articleIDs)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.ExportArticles err: 500: %w", err)
+		}
+		likes, err := a.articleCacheRepository.GetLikeCounts(articleIDs)
+		if err != nil {
+			return nil, fmt.Errorf("ArticleService.ExportArticles err: 500: %w", err)
+		}
+		for _, article := range articles {
+			like, ok := likes[article.ID]
+			if !ok {
+				like = article.Like
+			}
+			records = append(records, &article_model.ArticleRecord{
+				ID:        article.ID,
+				Title:     article.Title,
+				Kind:      article.Kind,
+				Format:    article.Format,
+				Tags:      tags[article.ID],
+				ManagerID: article.ManagerID,
+				Like:      like,
+				CreateAt:  article.CreateAt,
+				Content:   article.Content,
+			})
+		}
+	}
+	return records, nil
+}
+
+// WriteArticleRecords 按 format 把文章写入 w，CSV 的第一行为表头，标签用逗号连接
+func WriteArticleRecords(w io.Writer, format string, records []*article_model.ArticleRecord) error {
+	switch format {
+	case article_model.TransferJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case article_model.TransferCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(article_model.TransferColumns); err != nil {
+			return err
+		}
+		for _, record := range records {
+			row := []string{
+				strconv.Itoa(record.ID),
+				record.Title,
+				record.Kind,
+				record.Format,
+				strings.Join(record.Tags, ","),
+				strconv.Itoa(record.ManagerID),
+				strconv.Itoa(record.Like),
+				record.CreateAt.Format(time.RFC3339),
+				record.Content,
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("不支持的格式%s", format)
+	}
+}
+
+// ReadArticleRecords 按 format 从 r 中读取要导入的文章，超过 ImportMaxRows 行时返回错误
+// CSV 按表头的名称匹配列，缺少的列视为空；create_at 支持 RFC3339、"2006-01-02 15:04:05" 与 "2006-01-02"，
+// 无法解析的行记录在 rowErrors 中，由 ImportArticles 与校验失败的行一并报告
+func (a *ArticleService) ReadArticleRecords(r io.Reader, format string) ([]*article_model.ArticleRecord, map[int]error, error) {
+	maxRows := a.config.Article.ImportMaxRows
+	switch format {
+	case article_model.TransferJSON:
+		var records []*article_model.ArticleRecord
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, nil, fmt.Errorf("ArticleService.ReadArticleRecords err: 400: 解析JSON失败: %w", err)
+		}
+		if len(records) > maxRows {
+			return nil, nil, fmt.Errorf("ArticleService.ReadArticleRecords err: 400: 一次最多导入%d行", maxRows)
+		}
+		return records, map[int]error{}, nil
+	case article_model.TransferCSV:
+		records, rowErrors, err := readCSVRecords(r, maxRows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ArticleService.ReadArticleRecords err: 400: %w", err)
+		}
+		return records, rowErrors, nil
+	default:
+		return nil, nil, fmt.Errorf("ArticleService.ReadArticleRecords err: 400: 不支持的格式%s", format)
+	}
+}
+
+func readCSVRecords(r io.Reader, maxRows int) ([]*article_model.ArticleRecord, map[int]error, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取CSV表头失败: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel 保存的 UTF-8 CSV 第一列前有 BOM
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range []string{"title", "kind", "content"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("CSV缺少%s列", name)
+		}
+	}
+
+	var records []*article_model.ArticleRecord
+	rowErrors := make(map[int]error)
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取CSV第%d行失败: %w", row, err)
+		}
+		if row > maxRows {
+			return nil, nil, fmt.Errorf("一次最多导入%d行", maxRows)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		record := &article_model.ArticleRecord{
+			Title:   field("title"),
+			Kind:    field("kind"),
+			Format:  field("format"),
+			Tags:    utils.SplitTags(field("tags")),
+			Content: field("content"),
+		}
+		records = append(records, record)
+		if managerID := field("manager_id"); managerID != "" {
+			if record.ManagerID, err = strconv.Atoi(managerID); err != nil {
+				rowErrors[row] = fmt.Errorf("manager_id格式错误: %s", managerID)
+				continue
+			}
+		}
+		if createAt := field("create_at"); createAt != "" {
+			if record.CreateAt, err = parseImportTime(createAt); err != nil {
+				rowErrors[row] = fmt.Errorf("create_at格式错误: %s", createAt)
+			}
+		}
+	}
+	return records, rowErrors, nil
+}
+
+// parseImportTime 解析导入文件中的发布时间，不带时区的时间按本地时间处理
+func parseImportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateTime, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, time.Local)
+}
+
+// ImportArticles 批量导入文章，每行单独校验并通过与发布文章相同的流程写入，某一行失败不影响其他行
+// 发布时间为空时使用当前时间；导入的文章不会推送到粉丝的关注流
+// 列表、RSS、关注流和推荐都假定文章 ID 的顺序与发布时间一致，新文章的 ID 总是更大，
+// 因此发布时间不能早于已有的最新文章和前面导入的行，历史文章需要按发布时间从早到晚导入
+func (a *ArticleService) ImportArticles(records []*article_model.ArticleRecord, rowErrors map[int]error, options *article_model.ImportOptions) (*article_model.ImportReport, error) {
+	latest, err := a.articleRepository.GetLatestCreateAt()
+	if err != nil {
+		return nil, fmt.Errorf("ArticleService.ImportArticles err: 500: %w", err)
+	}
+	report := &article_model.ImportReport{DryRun: options.DryRun, Total: len(records), IDs: []int{}, Errors: []*article_model.ImportRowError{}}
+	now := time.Now()
+	managers := make(map[int]error)
+	for i, record := range records {
+		row := i + 1
+		err := rowErrors[row]
+		var article *article_model.Article
+		if err == nil {
+			article, err = a.validateImportRecord(record, options, now)
+		}
+		if err == nil {
+			err = a.checkImportManager(article.ManagerID, managers)
+		}
+		if err == nil && article.CreateAt.Before(latest) {
+			err = fmt.Errorf("发布时间不能早于已有的最新文章和前面导入的文章（%s），请按发布时间从早到晚导入", latest.Format(time.DateTime))
+		}
+		if err == nil {
+			if options.DryRun {
+				_, err = a.filterArticle(article)
+			} else {
+				err = a.addArticle(article, article.ManagerID, false)
+			}
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, &article_model.ImportRowError{Row: row, Title: record.Title, Error: err.Error()})
+			continue
+		}
+		report.Imported++
+		latest = article.CreateAt
+		if !options.DryRun {
+			report.IDs = append(report.IDs, article.ID)
+		}
+	}
+	if !options.DryRun {
+		log.Printf("导入文章%d篇，失败%d篇", report.Imported, len(report.Errors))
+	}
+	return report, nil
+}
+
+// checkImportManager 检查导入的文章指定的管理员存在，同一个管理员只查询一次，结果保存在 managers 中
+func (a *ArticleService) checkImportManager(managerID int, managers map[int]error) error {
+	if err, ok := managers[managerID]; ok {
+		return err
+	}
+	isManager, err := a.articleRepository.IsManager(managerID)
+	if err == nil && !isManager {
+		err = fmt.Errorf("用户%d不存在或不是管理员", managerID)
+	}
+	managers[managerID] = err
+	return err
+}
+
+// validateImportRecord 校验一行导入的文章，与发布文章接口的校验一致，通过时返回要写入的文章
+func (a *ArticleService) validateImportRecord(record *article_model.ArticleRecord, options *article_model.ImportOptions, now time.Time) (*article_model.Article, error) {
+	if strings.TrimSpace(record.Title) == "" {
+		return nil, errors.New("标题不能为空")
+	}
+	if strings.TrimSpace(record.Content) == "" {
+		return nil, errors.New("正文不能为空")
+	}
+	if !utils.ValidateArticleKind(record.Kind) {
+		return nil, fmt.Errorf("文章类型错误: %s", record.Kind)
+	}
+	if !utils.ValidateArticleFormat(record.Format) {
+		return nil, fmt.Errorf("文章格式错误: %s", record.Format)
+	}
+	tags, err := utils.NormalizeTags(record.Tags)
+	if err != nil {
+		return nil, err
+	}
+	managerID := record.ManagerID
+	if managerID == 0 {
+		managerID = options.ManagerID
+	}
+	if managerID <= 0 {
+		return nil, errors.New("没有指定管理员")
+	}
+	createAt := record.CreateAt
+	if createAt.IsZero() {
+		createAt = now
+	}
+	if createAt.After(now) {
+		return nil, errors.New("发布时间不能晚于当前时间")
+	}
+	return &article_model.Article{
+		Title:     strings.TrimSpace(record.Title),
+		Content:   record.Content,
+		Format:    record.Format,
+		Kind:      record.Kind,
+		Tags:      tags,
+		ManagerID: managerID,
+		CreateAt:  createAt,
+	}, nil
+}
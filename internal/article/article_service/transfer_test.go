@@ -0,0 +1,93 @@
+package article_service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImportTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-03-15T10:30:00+08:00", want: time.Date(2024, 3, 15, 2, 30, 0, 0, time.UTC)},
+		{value: "2024-03-15T02:30:00Z", want: time.Date(2024, 3, 15, 2, 30, 0, 0, time.UTC)},
+		{value: "2024-03-15 10:30:00", want: time.Date(2024, 3, 15, 10, 30, 0, 0, time.Local)},
+		{value: "2024-03-15", want: time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)},
+		{value: "2024/03/15", wantErr: true},
+		{value: "2024-13-01", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseImportTime(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseImportTime(%q) err = nil, want error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseImportTime(%q) err = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseImportTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSVRecords(t *testing.T) {
+	input := "\ufefftitle,kind,content,manager_id,create_at,tags\n" +
+		"停水通知,notice,明天停水,1,2024-03-15,\"物业, 停水\"\n" +
+		"坏的管理员,notice,正文,abc,,\n" +
+		"坏的时间,notice,正文,2,2024/03/15,\n" +
+		"缺少列,notice\n"
+	records, rowErrors, err := readCSVRecords(strings.NewReader(input), 10)
+	if err != nil {
+		t.Fatalf("readCSVRecords err = %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("len(records) = %d, want 4", len(records))
+	}
+
+	first := records[0]
+	if first.Title != "停水通知" || first.Kind != "notice" || first.Content != "明天停水" || first.ManagerID != 1 {
+		t.Errorf("records[0] = %+v", first)
+	}
+	if !first.CreateAt.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("records[0].CreateAt = %v", first.CreateAt)
+	}
+	if len(first.Tags) != 2 {
+		t.Errorf("records[0].Tags = %v, want 2 tags", first.Tags)
+	}
+	if records[3].Content != "" {
+		t.Errorf("records[3].Content = %q, want empty", records[3].Content)
+	}
+
+	for row, wantErr := range map[int]bool{1: false, 2: true, 3: true, 4: false} {
+		if _, ok := rowErrors[row]; ok != wantErr {
+			t.Errorf("rowErrors[%d] present = %v, want %v", row, ok, wantErr)
+		}
+	}
+}
+
+func TestReadCSVRecordsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"空文件", ""},
+		{"缺少必需列", "title,kind\n标题,notice\n"},
+		{"超过最大行数", "title,kind,content\n1,notice,a\n2,notice,b\n3,notice,c\n"},
+		{"引号未闭合", "title,kind,content\n\"标题,notice,a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := readCSVRecords(strings.NewReader(tt.input), 2); err == nil {
+				t.Errorf("readCSVRecords err = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package comment_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"huancuilou/common/error_handler"
+	"huancuilou/internal/comment/comment_model"
+	"huancuilou/internal/comment/comment_service"
+	"huancuilou/response"
+	"net/http"
+	"strconv"
+)
+
+type CommentController struct {
+	CommentService *comment_service.CommentService
+}
+
+func NewCommentController(commentService *comment_service.CommentService) *CommentController {
+	return &CommentController{
+		CommentService: commentService,
+	}
+}
+
+func (cc *CommentController) AddComment(c *gin.Context) {
+	var comment comment_model.Comment
+	if err := c.BindJSON(&comment); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.AddComment err: 400:将json数据绑定到结构体失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	newComment, err := cc.CommentService.AddComment(&comment, userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.AddComment err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(newComment))
+}
+
+func (cc *CommentController) GetComments(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.GetComments err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	page, size, err := parsePage(c)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.GetComments err: 400: %w", err))
+		return
+	}
+
+	comments, err := cc.CommentService.GetComments(articleID, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.GetComments err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(comments))
+}
+
+func (cc *CommentController) GetReplies(c *gin.Context) {
+	commentID, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.GetReplies err: 400: 将commentID转换为int失败:%w", err))
+		return
+	}
+	page, size, err := parsePage(c)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.GetReplies err: 400: %w", err))
+		return
+	}
+
+	replies, err := cc.CommentService.GetReplies(commentID, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.GetReplies err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(replies))
+}
+
+func (cc *CommentController) AddLikes(c *gin.Context) {
+	commentID, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.AddLikes err: 400: 将commentID转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	if err := cc.CommentService.AddLikes(commentID, userID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.AddLikes err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (cc *CommentController) RemoveLikes(c *gin.Context) {
+	commentID, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.RemoveLikes err: 400: 将commentID转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	if err := cc.CommentService.RemoveLikes(commentID, userID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.RemoveLikes err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	commentID, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.DeleteComment err: 400: 将commentID转换为int失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	if err := cc.CommentService.DeleteComment(commentID, userID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.DeleteComment err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (cc *CommentController) ModerateComment(c *gin.Context) {
+	commentID, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.ModerateComment err: 400: 将commentID转换为int失败:%w", err))
+		return
+	}
+	status, err := strconv.Atoi(c.Query("status"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.ModerateComment err: 400: 将status转换为int失败:%w", err))
+		return
+	}
+
+	if err := cc.CommentService.ModerateComment(commentID, status); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("CommentController.ModerateComment err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+// parsePage 解析分页参数，未传时返回 0 由服务层使用默认值
+func parsePage(c *gin.Context) (int, int, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("将page转换为int失败:%w", err)
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("将size转换为int失败:%w", err)
+	}
+	return page, size, nil
+}
@@ -0,0 +1,37 @@
+package comment_model
+
+import "time"
+
+// 评论状态
+const (
+	StatusNormal        = 0 // 正常
+	StatusDeletedByUser = 1 // 作者删除
+	StatusHiddenByAdmin = 2 // 管理员屏蔽
+	StatusPending       = 3 // 命中敏感词，等待审核
+)
+
+// Comment 文章评论，顶级评论的 ParentID 与 RootID 都为 0
+// 回复的 ParentID 为被回复的评论，RootID 为所属的顶级评论
+type Comment struct {
+	ID            int        `json:"id"`
+	ArticleID     int        `json:"articleID"`
+	UserID        int        `json:"userID"`
+	ParentID      int        `json:"parentID"`
+	RootID        int        `json:"rootID"`
+	ReplyToUserID int        `json:"replyToUserID"`
+	Content       string     `json:"content"`
+	Like          int        `json:"like"`
+	ReplyCount    int        `json:"replyCount"`
+	Status        int        `json:"status"`
+	CreateAt      time.Time  `json:"createAt"`
+	Replies       []*Comment `json:"replies,omitempty" gorm:"-"`
+}
+
+func (Comment) TableName() string {
+	return "comment"
+}
+
+// Visible 评论是否对普通用户可见
+func (c *Comment) Visible() bool {
+	return c.Status == StatusNormal
+}
@@ -0,0 +1,9 @@
+package comment_model
+
+// CommentPage 分页的评论列表
+type CommentPage struct {
+	Total    int64      `json:"total"`
+	Page     int        `json:"page"`
+	Size     int        `json:"size"`
+	Comments []*Comment `json:"comments"`
+}
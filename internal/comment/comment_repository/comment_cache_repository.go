@@ -0,0 +1,39 @@
+package comment_repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+)
+
+type CommentCacheRepository struct {
+	client *redis.Client
+}
+
+const CommentCachePrefix = "hcl:comment"
+
+func NewCommentCacheRepository(client *redis.Client) *CommentCacheRepository {
+	return &CommentCacheRepository{client: client}
+}
+
+// AddLike 将用户加入评论的点赞集合，返回用户此前是否未点赞
+func (cc *CommentCacheRepository) AddLike(commentID int, userID int) (bool, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:like:%d", CommentCachePrefix, commentID)
+	added, err := cc.client.SAdd(ctx, key, userID).Result()
+	if err != nil {
+		return false, fmt.Errorf("CommentCacheRepository.AddLike err: %w", err)
+	}
+	return added == 1, nil
+}
+
+// RemoveLike 将用户移出评论的点赞集合，返回用户此前是否已点赞
+func (cc *CommentCacheRepository) RemoveLike(commentID int, userID int) (bool, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:like:%d", CommentCachePrefix, commentID)
+	removed, err := cc.client.SRem(ctx, key, userID).Result()
+	if err != nil {
+		return false, fmt.Errorf("CommentCacheRepository.RemoveLike err: %w", err)
+	}
+	return removed == 1, nil
+}
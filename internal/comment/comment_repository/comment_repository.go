@@ -0,0 +1,92 @@
+package comment_repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/internal/comment/comment_model"
+)
+
+type CommentRepository struct {
+	DB *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{
+		DB: db,
+	}
+}
+
+func (cr *CommentRepository) AddComment(tx *gorm.DB, comment *comment_model.Comment) error {
+	if err := tx.Create(comment).Error; err != nil {
+		return fmt.Errorf("CommentRepository.AddComment err: %w", err)
+	}
+	return nil
+}
+
+func (cr *CommentRepository) GetCommentByID(id int) (*comment_model.Comment, error) {
+	var comment comment_model.Comment
+	result := cr.DB.First(&comment, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("CommentRepository.GetCommentByID err: %w", result.Error)
+	}
+	return &comment, nil
+}
+
+// GetRootComments 分页获取文章的顶级评论
+// 已删除或被屏蔽但仍有回复的顶级评论也会返回，以便展示其下的回复
+func (cr *CommentRepository) GetRootComments(articleID int, offset int, limit int) ([]*comment_model.Comment, int64, error) {
+	var comments []*comment_model.Comment
+	var total int64
+	query := cr.DB.Model(&comment_model.Comment{}).
+		Where("article_id = ? AND root_id = 0 AND (status = ? OR reply_count > 0)", articleID, comment_model.StatusNormal)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("CommentRepository.GetRootComments err: %w", err)
+	}
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&comments).Error; err != nil {
+		return nil, 0, fmt.Errorf("CommentRepository.GetRootComments err: %w", err)
+	}
+	return comments, total, nil
+}
+
+// GetReplies 按时间顺序分页获取顶级评论下的可见回复
+func (cr *CommentRepository) GetReplies(rootID int, offset int, limit int) ([]*comment_model.Comment, int64, error) {
+	var comments []*comment_model.Comment
+	var total int64
+	query := cr.DB.Model(&comment_model.Comment{}).Where("root_id = ? AND status = ?", rootID, comment_model.StatusNormal)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("CommentRepository.GetReplies err: %w", err)
+	}
+	if err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&comments).Error; err != nil {
+		return nil, 0, fmt.Errorf("CommentRepository.GetReplies err: %w", err)
+	}
+	return comments, total, nil
+}
+
+func (cr *CommentRepository) UpdateStatus(tx *gorm.DB, id int, status int) error {
+	result := tx.Model(&comment_model.Comment{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return fmt.Errorf("CommentRepository.UpdateStatus err: %w", result.Error)
+	}
+	return nil
+}
+
+// IncrReplyCount 调整顶级评论的可见回复数
+func (cr *CommentRepository) IncrReplyCount(tx *gorm.DB, id int, delta int) error {
+	result := tx.Model(&comment_model.Comment{}).Where("id = ?", id).Update("reply_count", gorm.Expr("reply_count + ?", delta))
+	if result.Error != nil {
+		return fmt.Errorf("CommentRepository.IncrReplyCount err: %w", result.Error)
+	}
+	return nil
+}
+
+func (cr *CommentRepository) IncrLike(id int, delta int) error {
+	result := cr.DB.Model(&comment_model.Comment{}).Where("id = ?", id).Update("like", gorm.Expr("`like` + ?", delta))
+	if result.Error != nil {
+		return fmt.Errorf("CommentRepository.IncrLike err: %w", result.Error)
+	}
+	return nil
+}
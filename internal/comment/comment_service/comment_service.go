@@ -0,0 +1,389 @@
+package comment_service
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/configs"
+	"huancuilou/internal/article/article_repository"
+	"huancuilou/internal/comment/comment_model"
+	"huancuilou/internal/comment/comment_repository"
+	"huancuilou/internal/moderation/moderation_model"
+	"huancuilou/internal/moderation/moderation_service"
+	"huancuilou/internal/notification/notification_model"
+	"huancuilou/internal/notification/notification_service"
+	"log"
+	"strings"
+	"time"
+)
+
+type CommentService struct {
+	commentRepository      *comment_repository.CommentRepository
+	commentCacheRepository *comment_repository.CommentCacheRepository
+	articleRepository      *article_repository.ArticleRepository
+	articleCacheRepository *article_repository.ArticleCacheRepository
+	notificationService    *notification_service.NotificationService
+	moderationService      *moderation_service.ModerationService
+	config                 *configs.Config
+}
+
+func NewCommentService(commentRepository *comment_repository.CommentRepository, commentCacheRepository *comment_repository.CommentCacheRepository,
+	articleRepository *article_repository.ArticleRepository, articleCacheRepository *article_repository.ArticleCacheRepository,
+	notificationService *notification_service.NotificationService, moderationService *moderation_service.ModerationService, config *configs.Config) *CommentService {
+	return &CommentService{
+		commentRepository:      commentRepository,
+		commentCacheRepository: commentCacheRepository,
+		articleRepository:      articleRepository,
+		articleCacheRepository: articleCacheRepository,
+		notificationService:    notificationService,
+		moderationService:      moderationService,
+		config:                 config,
+	}
+}
+
+// AddComment 发表评论或回复评论，成功后通知文章作者和被回复的用户
+// 命中需要审核的敏感词时评论先进入待审状态，审核通过后才对其他用户可见
+func (cs *CommentService) AddComment(comment *comment_model.Comment, userID int) (*comment_model.Comment, error) {
+	comment.Content = strings.TrimSpace(comment.Content)
+	if comment.Content == "" {
+		return nil, fmt.Errorf("CommentService.AddComment err: 400: 评论内容不能为空")
+	}
+	if len([]rune(comment.Content)) > cs.config.Comment.MaxLength {
+		return nil, fmt.Errorf("CommentService.AddComment err: 400: 评论内容不能超过%d个字符", cs.config.Comment.MaxLength)
+	}
+
+	content, checkResult, err := cs.moderationService.Filter(comment.Content)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.AddComment err: %w", err)
+	}
+	pending := checkResult.Action == moderation_model.ActionReview
+
+	article, err := cs.articleRepository.GetArticleByID(comment.ArticleID)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.AddComment err: 500: %w", err)
+	}
+	if article == nil {
+		return nil, fmt.Errorf("CommentService.AddComment err: 400: 文章不存在")
+	}
+
+	comment.ID = 0
+	comment.Content = content
+	comment.UserID = userID
+	comment.RootID = 0
+	comment.ReplyToUserID = 0
+	comment.Like = 0
+	comment.ReplyCount = 0
+	comment.Status = comment_model.StatusNormal
+	if pending {
+		comment.Status = comment_model.StatusPending
+	}
+	comment.CreateAt = time.Now()
+
+	var parent *comment_model.Comment
+	if comment.ParentID != 0 {
+		parent, err = cs.commentRepository.GetCommentByID(comment.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("CommentService.AddComment err: 500: %w", err)
+		}
+		if parent == nil || !parent.Visible() || parent.ArticleID != comment.ArticleID {
+			return nil, fmt.Errorf("CommentService.AddComment err: 400: 回复的评论不存在")
+		}
+		comment.RootID = parent.RootID
+		if comment.RootID == 0 {
+			comment.RootID = parent.ID
+		}
+		comment.ReplyToUserID = parent.UserID
+	}
+
+	err = cs.commentRepository.DB.Transaction(func(tx *gorm.DB) error {
+		if err := cs.commentRepository.AddComment(tx, comment); err != nil {
+			return err
+		}
+		if pending {
+			return cs.moderationService.Submit(tx, moderation_model.ContentComment, comment.ID, userID, comment.Content, checkResult.Words)
+		}
+		if comment.RootID != 0 {
+			return cs.commentRepository.IncrReplyCount(tx, comment.RootID, 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.AddComment err: 500: %w", err)
+	}
+	if pending {
+		return comment, nil
+	}
+
+	if err := cs.articleCacheRepository.IncrCommentCount(comment.ArticleID, 1, cs.config.Article.Hot.MaxSize); err != nil {
+		log.Printf("CommentService.AddComment err: 更新文章评论数失败: %v", err)
+	}
+	cs.notify(comment, article.ManagerID, parent)
+
+	return comment, nil
+}
+
+// notify 通知文章作者有新评论，回复时同时通知被回复的用户，评论者不会收到自己评论的通知
+func (cs *CommentService) notify(comment *comment_model.Comment, managerID int, parent *comment_model.Comment) {
+	if comment.UserID != managerID {
+		cs.notificationService.Notify(&notification_model.Notification{
+			UserID:    managerID,
+			SenderID:  comment.UserID,
+			Kind:      notification_model.KindComment,
+			ArticleID: comment.ArticleID,
+			CommentID: comment.ID,
+			Content:   comment.Content,
+		})
+	}
+	if parent != nil && parent.UserID != managerID && parent.UserID != comment.UserID {
+		cs.notificationService.Notify(&notification_model.Notification{
+			UserID:    parent.UserID,
+			SenderID:  comment.UserID,
+			Kind:      notification_model.KindReply,
+			ArticleID: comment.ArticleID,
+			CommentID: comment.ID,
+			Content:   comment.Content,
+		})
+	}
+}
+
+// ApproveModeratedComment 审核通过后公开评论，事务提交后补发通知
+func (cs *CommentService) ApproveModeratedComment(tx *gorm.DB, record *moderation_model.ModerationRecord) (func(), error) {
+	comment, err := cs.commentRepository.GetCommentByID(record.ContentID)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.ApproveModeratedComment err: %w", err)
+	}
+	if comment == nil || comment.Status != comment_model.StatusPending {
+		return nil, nil
+	}
+	delta, err := cs.updateStatus(tx, comment, comment_model.StatusNormal)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.ApproveModeratedComment err: %w", err)
+	}
+
+	return func() {
+		cs.incrCommentCount(comment, delta)
+		article, err := cs.articleRepository.GetArticleByID(comment.ArticleID)
+		if err != nil || article == nil {
+			log.Printf("CommentService.ApproveModeratedComment err: 获取文章失败，不发送通知: %v", err)
+			return
+		}
+		var parent *comment_model.Comment
+		if comment.ParentID != 0 {
+			if parent, err = cs.commentRepository.GetCommentByID(comment.ParentID); err != nil {
+				log.Printf("CommentService.ApproveModeratedComment err: 获取被回复的评论失败: %v", err)
+			}
+		}
+		cs.notify(comment, article.ManagerID, parent)
+	}, nil
+}
+
+// RejectModeratedComment 审核拒绝后屏蔽评论
+func (cs *CommentService) RejectModeratedComment(tx *gorm.DB, record *moderation_model.ModerationRecord) (func(), error) {
+	comment, err := cs.commentRepository.GetCommentByID(record.ContentID)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.RejectModeratedComment err: %w", err)
+	}
+	if comment == nil || comment.Status != comment_model.StatusPending {
+		return nil, nil
+	}
+	delta, err := cs.updateStatus(tx, comment, comment_model.StatusHiddenByAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.RejectModeratedComment err: %w", err)
+	}
+	return func() { cs.incrCommentCount(comment, delta) }, nil
+}
+
+// GetComments 分页获取文章的顶级评论，每条顶级评论附带最早的几条回复
+func (cs *CommentService) GetComments(articleID int, page int, size int) (*comment_model.CommentPage, error) {
+	page, size = cs.normalizePage(page, size)
+	comments, total, err := cs.commentRepository.GetRootComments(articleID, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.GetComments err: 500: %w", err)
+	}
+
+	for _, comment := range comments {
+		maskInvisible(comment)
+		if comment.ReplyCount == 0 {
+			continue
+		}
+		replies, _, err := cs.commentRepository.GetReplies(comment.ID, 0, cs.config.Comment.ReplyPreviewSize)
+		if err != nil {
+			return nil, fmt.Errorf("CommentService.GetComments err: 500: %w", err)
+		}
+		comment.Replies = replies
+	}
+
+	return &comment_model.CommentPage{
+		Total:    total,
+		Page:     page,
+		Size:     size,
+		Comments: comments,
+	}, nil
+}
+
+// GetReplies 分页获取顶级评论下的全部回复
+func (cs *CommentService) GetReplies(rootID int, page int, size int) (*comment_model.CommentPage, error) {
+	page, size = cs.normalizePage(page, size)
+	replies, total, err := cs.commentRepository.GetReplies(rootID, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("CommentService.GetReplies err: 500: %w", err)
+	}
+	return &comment_model.CommentPage{
+		Total:    total,
+		Page:     page,
+		Size:     size,
+		Comments: replies,
+	}, nil
+}
+
+func (cs *CommentService) AddLikes(commentID int, userID int) error {
+	comment, err := cs.commentRepository.GetCommentByID(commentID)
+	if err != nil {
+		return fmt.Errorf("CommentService.AddLikes err: 500: %w", err)
+	}
+	if comment == nil || !comment.Visible() {
+		return fmt.Errorf("CommentService.AddLikes err: 400: 评论不存在")
+	}
+
+	added, err := cs.commentCacheRepository.AddLike(commentID, userID)
+	if err != nil {
+		return fmt.Errorf("CommentService.AddLikes err: 500: %w", err)
+	}
+	if !added {
+		return fmt.Errorf("CommentService.AddLikes err: 400: 用户已经点赞，无法重复点赞")
+	}
+
+	if err := cs.commentRepository.IncrLike(commentID, 1); err != nil {
+		if _, rollbackErr := cs.commentCacheRepository.RemoveLike(commentID, userID); rollbackErr != nil {
+			log.Printf("CommentService.AddLikes err: 回滚评论点赞集合失败: %v", rollbackErr)
+		}
+		return fmt.Errorf("CommentService.AddLikes err: 500: %w", err)
+	}
+	return nil
+}
+
+func (cs *CommentService) RemoveLikes(commentID int, userID int) error {
+	removed, err := cs.commentCacheRepository.RemoveLike(commentID, userID)
+	if err != nil {
+		return fmt.Errorf("CommentService.RemoveLikes err: 500: %w", err)
+	}
+	if !removed {
+		return fmt.Errorf("CommentService.RemoveLikes err: 400: 无法重复取消点赞")
+	}
+
+	if err := cs.commentRepository.IncrLike(commentID, -1); err != nil {
+		if _, rollbackErr := cs.commentCacheRepository.AddLike(commentID, userID); rollbackErr != nil {
+			log.Printf("CommentService.RemoveLikes err: 回滚评论点赞集合失败: %v", rollbackErr)
+		}
+		return fmt.Errorf("CommentService.RemoveLikes err: 500: %w", err)
+	}
+	return nil
+}
+
+// DeleteComment 作者删除自己的评论
+func (cs *CommentService) DeleteComment(commentID int, userID int) error {
+	comment, err := cs.commentRepository.GetCommentByID(commentID)
+	if err != nil {
+		return fmt.Errorf("CommentService.DeleteComment err: 500: %w", err)
+	}
+	if comment == nil || comment.Status == comment_model.StatusDeletedByUser {
+		return fmt.Errorf("CommentService.DeleteComment err: 400: 评论不存在")
+	}
+	if comment.UserID != userID {
+		return fmt.Errorf("CommentService.DeleteComment err: 403: 只能删除自己的评论")
+	}
+
+	if err := cs.changeStatus(comment, comment_model.StatusDeletedByUser); err != nil {
+		return fmt.Errorf("CommentService.DeleteComment err: 500: %w", err)
+	}
+	return nil
+}
+
+// ModerateComment 管理员屏蔽或恢复评论，不能恢复作者自己删除的评论
+func (cs *CommentService) ModerateComment(commentID int, status int) error {
+	if status != comment_model.StatusNormal && status != comment_model.StatusHiddenByAdmin {
+		return fmt.Errorf("CommentService.ModerateComment err: 400: 评论状态错误")
+	}
+	comment, err := cs.commentRepository.GetCommentByID(commentID)
+	if err != nil {
+		return fmt.Errorf("CommentService.ModerateComment err: 500: %w", err)
+	}
+	if comment == nil || comment.Status == comment_model.StatusDeletedByUser {
+		return fmt.Errorf("CommentService.ModerateComment err: 400: 评论不存在")
+	}
+	if comment.Status == comment_model.StatusPending {
+		return fmt.Errorf("CommentService.ModerateComment err: 400: 评论正在审核中，请在审核队列中处理")
+	}
+	if comment.Status == status {
+		return nil
+	}
+
+	if err := cs.changeStatus(comment, status); err != nil {
+		return fmt.Errorf("CommentService.ModerateComment err: 500: %w", err)
+	}
+	return nil
+}
+
+// changeStatus 修改评论状态，评论可见性变化时同步调整顶级评论的回复数和缓存中文章的评论数
+func (cs *CommentService) changeStatus(comment *comment_model.Comment, status int) error {
+	var delta int
+	err := cs.commentRepository.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		delta, err = cs.updateStatus(tx, comment, status)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	cs.incrCommentCount(comment, delta)
+	return nil
+}
+
+// updateStatus 在事务中修改评论状态，评论是否显示发生变化时更新顶级评论的回复数，返回文章评论数的变化
+func (cs *CommentService) updateStatus(tx *gorm.DB, comment *comment_model.Comment, status int) (int, error) {
+	delta := 0
+	if comment.Visible() && status != comment_model.StatusNormal {
+		delta = -1
+	} else if !comment.Visible() && status == comment_model.StatusNormal {
+		delta = 1
+	}
+
+	if err := cs.commentRepository.UpdateStatus(tx, comment.ID, status); err != nil {
+		return 0, err
+	}
+	if comment.RootID != 0 && delta != 0 {
+		if err := cs.commentRepository.IncrReplyCount(tx, comment.RootID, delta); err != nil {
+			return 0, err
+		}
+	}
+	return delta, nil
+}
+
+// incrCommentCount 事务提交后更新缓存中文章的评论数
+func (cs *CommentService) incrCommentCount(comment *comment_model.Comment, delta int) {
+	if delta == 0 {
+		return
+	}
+	if err := cs.articleCacheRepository.IncrCommentCount(comment.ArticleID, delta, cs.config.Article.Hot.MaxSize); err != nil {
+		log.Printf("CommentService.incrCommentCount err: 更新文章评论数失败: %v", err)
+	}
+}
+
+func (cs *CommentService) normalizePage(page int, size int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = cs.config.Comment.PageSize
+	}
+	return page, size
+}
+
+// maskInvisible 隐藏已删除或被屏蔽的顶级评论内容，只保留其位置用于展示回复
+func maskInvisible(comment *comment_model.Comment) {
+	switch comment.Status {
+	case comment_model.StatusDeletedByUser:
+		comment.Content = "该评论已删除"
+	case comment_model.StatusHiddenByAdmin, comment_model.StatusPending:
+		comment.Content = "该评论已被屏蔽"
+	}
+}
@@ -0,0 +1,43 @@
+package feed_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"huancuilou/common/error_handler"
+	"huancuilou/internal/feed/feed_service"
+	"huancuilou/response"
+	"net/http"
+	"strconv"
+)
+
+type FeedController struct {
+	FeedService *feed_service.FeedService
+}
+
+func NewFeedController(feedService *feed_service.FeedService) *FeedController {
+	return &FeedController{
+		FeedService: feedService,
+	}
+}
+
+// GetFeed 按游标分页获取当前用户的关注流，cursor 为上一页返回的 nextCursor，第一页不传
+func (f *FeedController) GetFeed(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	cursor, err := strconv.Atoi(c.DefaultQuery("cursor", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("FeedController.GetFeed err: 400: 将cursor转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("FeedController.GetFeed err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	page, err := f.FeedService.GetFeed(userID, cursor, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("FeedController.GetFeed err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(page))
+}
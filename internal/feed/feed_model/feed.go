@@ -0,0 +1,16 @@
+package feed_model
+
+import "huancuilou/internal/article/article_model"
+
+// FeedItem 关注流中的一篇文章，文章 ID 随发布时间递增，同时作为排序依据和分页游标
+type FeedItem struct {
+	ArticleID int
+	ManagerID int
+}
+
+// FeedPage 按游标分页的关注流，NextCursor 为下一页的游标，没有更多文章时 HasMore 为 false
+type FeedPage struct {
+	Articles   []*article_model.BasicArticle `json:"articles"`
+	NextCursor int                           `json:"nextCursor"`
+	HasMore    bool                          `json:"hasMore"`
+}
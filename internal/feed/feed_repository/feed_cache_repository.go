@@ -0,0 +1,303 @@
+package feed_repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"huancuilou/internal/feed/feed_model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeedCachePrefix 定义关注流缓存键的前缀
+const FeedCachePrefix = "hcl:feed"
+
+// pullManagersKey 发布文章时粉丝过多、改为由粉丝拉取的管理员集合
+var pullManagersKey = FeedCachePrefix + ":pull"
+
+type FeedCacheRepository struct {
+	client *redis.Client
+}
+
+func NewFeedCacheRepository(client *redis.Client) *FeedCacheRepository {
+	return &FeedCacheRepository{client: client}
+}
+
+// inboxKey 用户的收件箱，成员为 "文章ID:管理员ID"，分数为文章 ID
+func inboxKey(userID int) string {
+	return fmt.Sprintf("%s:inbox:%d", FeedCachePrefix, userID)
+}
+
+// syncedKey 已经同步到收件箱的关注对象集合，与用户当前的关注集合比较得出新关注和取消关注的管理员
+func syncedKey(userID int) string {
+	return fmt.Sprintf("%s:synced:%d", FeedCachePrefix, userID)
+}
+
+// outboxKey 管理员的发件箱，成员与分数都是文章 ID
+func outboxKey(managerID int) string {
+	return fmt.Sprintf("%s:outbox:%d", FeedCachePrefix, managerID)
+}
+
+func inboxMember(item *feed_model.FeedItem) string {
+	return fmt.Sprintf("%d:%d", item.ArticleID, item.ManagerID)
+}
+
+func parseInboxMember(member string) (*feed_model.FeedItem, error) {
+	articleID, managerID, ok := strings.Cut(member, ":")
+	if !ok {
+		return nil, fmt.Errorf("收件箱成员格式错误: %s", member)
+	}
+	item := &feed_model.FeedItem{}
+	var err error
+	if item.ArticleID, err = strconv.Atoi(articleID); err != nil {
+		return nil, err
+	}
+	if item.ManagerID, err = strconv.Atoi(managerID); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// beforeScore 返回游标对应的分数上限，游标为 0 时从最新的文章开始
+func beforeScore(before int) string {
+	if before <= 0 {
+		return "+inf"
+	}
+	return "(" + strconv.Itoa(before)
+}
+
+// addToOutboxScript 只在发件箱已缓存时加入文章，避免只含最新文章的残缺发件箱被当作完整发件箱
+// KEYS[1] 为发件箱，ARGV[1] 为文章 ID，ARGV[2] 为保留的文章数
+var addToOutboxScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[1])
+redis.call('ZREMRANGEBYRANK', KEYS[1], 0, -tonumber(ARGV[2]) - 1)
+return 1
+`)
+
+// AddToOutbox 把文章加入管理员的发件箱，发件箱未缓存时返回 false，由调用方从 MySQL 加载
+func (f *FeedCacheRepository) AddToOutbox(managerID int, articleID int, size int) (bool, error) {
+	ctx := context.Background()
+	added, err := addToOutboxScript.Run(ctx, f.client, []string{outboxKey(managerID)}, articleID, size).Int()
+	if err != nil {
+		return false, fmt.Errorf("FeedCacheRepository.AddToOutbox err: %w", err)
+	}
+	return added == 1, nil
+}
+
+// LoadOutbox 用 MySQL 中管理员最近发布的文章重建发件箱，没有文章时不写入
+func (f *FeedCacheRepository) LoadOutbox(managerID int, articleIDs []int) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	key := outboxKey(managerID)
+	members := make([]redis.Z, len(articleIDs))
+	for i, id := range articleIDs {
+		members[i] = redis.Z{Score: float64(id), Member: id}
+	}
+	_, err := f.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.ZAdd(ctx, key, members...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("FeedCacheRepository.LoadOutbox err: %w", err)
+	}
+	return nil
+}
+
+// GetOutbox 从新到旧获取发件箱中 ID 小于 before 的至多 limit 篇文章，发件箱未缓存时 cached 为 false
+func (f *FeedCacheRepository) GetOutbox(managerID int, before int, limit int) (articleIDs []int, cached bool, err error) {
+	ctx := context.Background()
+	key := outboxKey(managerID)
+	pipe := f.client.Pipeline()
+	existsCmd := pipe.Exists(ctx, key)
+	rangeCmd := pipe.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{Max: beforeScore(before), Min: "-inf", Count: int64(limit)})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, false, fmt.Errorf("FeedCacheRepository.GetOutbox err: %w", err)
+	}
+	if existsCmd.Val() == 0 {
+		return nil, false, nil
+	}
+	for _, member := range rangeCmd.Val() {
+		id, err := strconv.Atoi(member)
+		if err != nil {
+			return nil, false, fmt.Errorf("FeedCacheRepository.GetOutbox err: %w", err)
+		}
+		articleIDs = append(articleIDs, id)
+	}
+	return articleIDs, true, nil
+}
+
+// MarkPullManager 记录管理员的文章改为由粉丝拉取，此后粉丝读取关注流时都会拉取这个管理员的发件箱
+func (f *FeedCacheRepository) MarkPullManager(managerID int) error {
+	ctx := context.Background()
+	if err := f.client.SAdd(ctx, pullManagersKey, managerID).Err(); err != nil {
+		return fmt.Errorf("FeedCacheRepository.MarkPullManager err: %w", err)
+	}
+	return nil
+}
+
+// UnmarkPullManager 记录管理员的文章改回推送到粉丝的收件箱，粉丝读取关注流时不再拉取这个管理员的发件箱
+func (f *FeedCacheRepository) UnmarkPullManager(managerID int) error {
+	ctx := context.Background()
+	if err := f.client.SRem(ctx, pullManagersKey, managerID).Err(); err != nil {
+		return fmt.Errorf("FeedCacheRepository.UnmarkPullManager err: %w", err)
+	}
+	return nil
+}
+
+// GetPullManagers 从 managerIDs 中找出文章需要由粉丝拉取的管理员
+func (f *FeedCacheRepository) GetPullManagers(managerIDs []int) ([]int, error) {
+	if len(managerIDs) == 0 {
+		return nil, nil
+	}
+	ctx := context.Background()
+	members := make([]interface{}, len(managerIDs))
+	for i, id := range managerIDs {
+		members[i] = id
+	}
+	isPull, err := f.client.SMIsMember(ctx, pullManagersKey, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("FeedCacheRepository.GetPullManagers err: %w", err)
+	}
+	var pullManagers []int
+	for i, pull := range isPull {
+		if pull {
+			pullManagers = append(pullManagers, managerIDs[i])
+		}
+	}
+	return pullManagers, nil
+}
+
+// pushScript 把文章推送到一个用户的收件箱
+// KEYS[1] 为收件箱，KEYS[2] 为已同步的关注对象集合，ARGV[1] 为文章 ID，ARGV[2] 为收件箱成员，ARGV[3] 为保留的文章数
+// 用户很久没有读取、已同步集合过期时不推送，下次读取时由发件箱重建；收件箱与已同步集合同时过期
+var pushScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[2])
+if ttl == -2 then
+    return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZREMRANGEBYRANK', KEYS[1], 0, -tonumber(ARGV[3]) - 1)
+if ttl > 0 then
+    redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// PushToInboxes 把文章推送到一批用户的收件箱，在同一个管道中发送
+func (f *FeedCacheRepository) PushToInboxes(userIDs []int, item *feed_model.FeedItem, size int) error {
+	ctx := context.Background()
+	// 管道中的 EVALSHA 在脚本未加载时会失败，先加载一次脚本
+	if err := pushScript.Load(ctx, f.client).Err(); err != nil {
+		return fmt.Errorf("FeedCacheRepository.PushToInboxes err: %w", err)
+	}
+	pipe := f.client.Pipeline()
+	member := inboxMember(item)
+	for _, userID := range userIDs {
+		pushScript.EvalSha(ctx, pipe, []string{inboxKey(userID), syncedKey(userID)}, item.ArticleID, member, size)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("FeedCacheRepository.PushToInboxes err: %w", err)
+	}
+	return nil
+}
+
+// GetSyncedFollows 获取已经同步到收件箱的关注对象
+func (f *FeedCacheRepository) GetSyncedFollows(userID int) ([]int, error) {
+	ctx := context.Background()
+	members, err := f.client.SMembers(ctx, syncedKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("FeedCacheRepository.GetSyncedFollows err: %w", err)
+	}
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.Atoi(member)
+		if err != nil {
+			return nil, fmt.Errorf("FeedCacheRepository.GetSyncedFollows err: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// removeManagersScript 从收件箱中移除给定管理员的文章，KEYS[1] 为收件箱，ARGV 为管理员 ID
+var removeManagersScript = redis.NewScript(`
+local removed = {}
+for _, id in ipairs(ARGV) do
+    removed[id] = true
+end
+for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+    if removed[string.match(member, ':(%d+)$')] then
+        redis.call('ZREM', KEYS[1], member)
+    end
+end
+return 1
+`)
+
+// SyncInbox 把新关注的管理员的文章加入收件箱，移除取消关注的管理员的文章，并更新已同步的关注对象
+// 收件箱与已同步集合的过期时间一起延长为 ttl，用户持续读取关注流时不会过期
+func (f *FeedCacheRepository) SyncInbox(userID int, items []*feed_model.FeedItem, added []int, removed []int, size int, ttl time.Duration) error {
+	ctx := context.Background()
+	inbox := inboxKey(userID)
+	synced := syncedKey(userID)
+	if len(removed) > 0 {
+		args := make([]interface{}, len(removed))
+		for i, id := range removed {
+			args[i] = id
+		}
+		if err := removeManagersScript.Run(ctx, f.client, []string{inbox}, args...).Err(); err != nil {
+			return fmt.Errorf("FeedCacheRepository.SyncInbox err: %w", err)
+		}
+	}
+	_, err := f.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(items) > 0 {
+			members := make([]redis.Z, len(items))
+			for i, item := range items {
+				members[i] = redis.Z{Score: float64(item.ArticleID), Member: inboxMember(item)}
+			}
+			pipe.ZAdd(ctx, inbox, members...)
+			pipe.ZRemRangeByRank(ctx, inbox, 0, int64(-size-1))
+		}
+		for _, id := range added {
+			pipe.SAdd(ctx, synced, id)
+		}
+		for _, id := range removed {
+			pipe.SRem(ctx, synced, id)
+		}
+		pipe.Expire(ctx, inbox, ttl)
+		pipe.Expire(ctx, synced, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("FeedCacheRepository.SyncInbox err: %w", err)
+	}
+	return nil
+}
+
+// GetInbox 从新到旧获取收件箱中文章 ID 小于 before 的至多 limit 篇文章
+func (f *FeedCacheRepository) GetInbox(userID int, before int, limit int) ([]*feed_model.FeedItem, error) {
+	ctx := context.Background()
+	members, err := f.client.ZRevRangeByScore(ctx, inboxKey(userID), &redis.ZRangeBy{
+		Max:   beforeScore(before),
+		Min:   "-inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("FeedCacheRepository.GetInbox err: %w", err)
+	}
+	items := make([]*feed_model.FeedItem, 0, len(members))
+	for _, member := range members {
+		item, err := parseInboxMember(member)
+		if err != nil {
+			return nil, fmt.Errorf("FeedCacheRepository.GetInbox err: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
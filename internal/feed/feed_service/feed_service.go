@@ -0,0 +1,219 @@
+package feed_service
+
+import (
+	"fmt"
+	"huancuilou/common/utils"
+	"huancuilou/configs"
+	"huancuilou/internal/article/article_model"
+	"huancuilou/internal/article/article_repository"
+	"huancuilou/internal/article/article_service"
+	"huancuilou/internal/feed/feed_model"
+	"huancuilou/internal/feed/feed_repository"
+	"huancuilou/internal/user/user_model"
+	"huancuilou/internal/user/user_repository"
+	"log"
+	"slices"
+)
+
+// FeedService 关注流由推拉结合实现：
+// 粉丝不多的管理员发布文章时推送到每个粉丝的收件箱；粉丝过多的管理员只写入自己的发件箱，粉丝读取时再拉取
+type FeedService struct {
+	feedCacheRepository *feed_repository.FeedCacheRepository
+	articleRepository   *article_repository.ArticleRepository
+	articleService      *article_service.ArticleService
+	userCacheRepository *user_repository.UserCacheRepository
+	config              *configs.Config
+}
+
+func NewFeedService(feedCacheRepository *feed_repository.FeedCacheRepository, articleRepository *article_repository.ArticleRepository,
+	articleService *article_service.ArticleService, userCacheRepository *user_repository.UserCacheRepository, config *configs.Config) *FeedService {
+	return &FeedService{
+		feedCacheRepository: feedCacheRepository,
+		articleRepository:   articleRepository,
+		articleService:      articleService,
+		userCacheRepository: userCacheRepository,
+		config:              config,
+	}
+}
+
+// FanOut 新文章发布后写入管理员的发件箱，并推送到粉丝的收件箱，注册为文章的发布处理方
+// 粉丝数超过 PushMaxFans 时不推送，把管理员标记为由粉丝拉取；重复执行时结果不变
+func (f *FeedService) FanOut(article *article_model.BasicArticle) error {
+	added, err := f.feedCacheRepository.AddToOutbox(article.ManagerID, article.ID, f.config.Feed.OutboxSize)
+	if err != nil {
+		return fmt.Errorf("FeedService.FanOut err: %w", err)
+	}
+	if !added {
+		// 发件箱不在缓存中时由 MySQL 重建，事务已经提交，重建的发件箱中包含这篇文章
+		if _, err := f.loadOutbox(article.ManagerID); err != nil {
+			return fmt.Errorf("FeedService.FanOut err: %w", err)
+		}
+	}
+
+	fanCount, err := f.userCacheRepository.CountFollowSet(user_model.AuditFans, article.ManagerID)
+	if err != nil {
+		return fmt.Errorf("FeedService.FanOut err: %w", err)
+	}
+	if fanCount > f.config.Feed.PushMaxFans {
+		log.Printf("管理员%d的粉丝数为%d，文章%d改为由粉丝拉取", article.ManagerID, fanCount, article.ID)
+		if err := f.feedCacheRepository.MarkPullManager(article.ManagerID); err != nil {
+			return fmt.Errorf("FeedService.FanOut err: %w", err)
+		}
+		return nil
+	}
+
+	fans, err := f.userCacheRepository.GetFollowSets(user_model.AuditFans, []int{article.ManagerID})
+	if err != nil {
+		return fmt.Errorf("FeedService.FanOut err: %w", err)
+	}
+	items := []*feed_model.FeedItem{{ArticleID: article.ID, ManagerID: article.ManagerID}}
+	pullManagers, err := f.feedCacheRepository.GetPullManagers([]int{article.ManagerID})
+	if err != nil {
+		return fmt.Errorf("FeedService.FanOut err: %w", err)
+	}
+	pulled := len(pullManagers) > 0
+	if pulled {
+		// 粉丝数回落到阈值以内，改回推送。之前由粉丝拉取的文章不在收件箱中，
+		// 先把发件箱中最近的文章补推到收件箱，再移出拉取集合，期间读取时两边重复的文章由 GetFeed 去重
+		articleIDs, err := f.getOutbox(article.ManagerID, 0, f.config.Feed.InboxSize)
+		if err != nil {
+			return fmt.Errorf("FeedService.FanOut err: %w", err)
+		}
+		items = items[:0]
+		for _, id := range articleIDs {
+			items = append(items, &feed_model.FeedItem{ArticleID: id, ManagerID: article.ManagerID})
+		}
+	}
+	for batch := range slices.Chunk(fans[article.ManagerID], f.config.Feed.FanOutBatchSize) {
+		for _, item := range items {
+			if err := f.feedCacheRepository.PushToInboxes(batch, item, f.config.Feed.InboxSize); err != nil {
+				return fmt.Errorf("FeedService.FanOut err: %w", err)
+			}
+		}
+	}
+	if pulled {
+		log.Printf("管理员%d的粉丝数为%d，文章改回推送到粉丝的收件箱", article.ManagerID, fanCount)
+		if err := f.feedCacheRepository.UnmarkPullManager(article.ManagerID); err != nil {
+			return fmt.Errorf("FeedService.FanOut err: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetFeed 按游标分页获取用户关注的管理员发布的文章，cursor 为上一页返回的 NextCursor，为 0 时从最新的文章开始
+// 收件箱中的文章与从粉丝过多的管理员的发件箱拉取的文章合并后按发布时间从新到旧排列
+func (f *FeedService) GetFeed(userID int, cursor int, size int) (*feed_model.FeedPage, error) {
+	if size <= 0 {
+		size = f.config.Feed.PageSize
+	}
+	size = min(size, f.config.Feed.InboxSize)
+
+	follows, err := f.userCacheRepository.GetFollowSets(user_model.AuditFollows, []int{userID})
+	if err != nil {
+		return nil, fmt.Errorf("FeedService.GetFeed err: 500: %w", err)
+	}
+	managerIDs := follows[userID]
+	if err := f.syncInbox(userID, managerIDs); err != nil {
+		return nil, fmt.Errorf("FeedService.GetFeed err: 500: %w", err)
+	}
+
+	// 每个来源多取一篇，用于判断是否还有下一页
+	items, err := f.feedCacheRepository.GetInbox(userID, cursor, size+1)
+	if err != nil {
+		return nil, fmt.Errorf("FeedService.GetFeed err: 500: %w", err)
+	}
+	pullManagers, err := f.feedCacheRepository.GetPullManagers(managerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("FeedService.GetFeed err: 500: %w", err)
+	}
+	for _, managerID := range pullManagers {
+		articleIDs, err := f.getOutbox(managerID, cursor, size+1)
+		if err != nil {
+			return nil, fmt.Errorf("FeedService.GetFeed err: 500: %w", err)
+		}
+		for _, id := range articleIDs {
+			items = append(items, &feed_model.FeedItem{ArticleID: id, ManagerID: managerID})
+		}
+	}
+
+	// 管理员的粉丝数越过阈值前后发布的文章可能既在收件箱中又在发件箱中，按文章 ID 去重
+	articleIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		articleIDs = append(articleIDs, item.ArticleID)
+	}
+	slices.Sort(articleIDs)
+	articleIDs = slices.Compact(articleIDs)
+	slices.Reverse(articleIDs)
+
+	page := &feed_model.FeedPage{}
+	if len(articleIDs) > size {
+		articleIDs = articleIDs[:size]
+		page.HasMore = true
+		page.NextCursor = articleIDs[size-1]
+	}
+	if page.Articles, err = f.articleService.GetBasicArticles(articleIDs, userID); err != nil {
+		return nil, fmt.Errorf("FeedService.GetFeed err: 500: %w", err)
+	}
+	return page, nil
+}
+
+// syncInbox 比较用户当前的关注集合与已同步到收件箱的关注对象，
+// 把新关注的管理员最近的文章补进收件箱，移除取消关注的管理员的文章
+// 收件箱过期后已同步集合也一起过期，下次读取时所有关注对象都视为新关注，由发件箱重建收件箱
+func (f *FeedService) syncInbox(userID int, managerIDs []int) error {
+	synced, err := f.feedCacheRepository.GetSyncedFollows(userID)
+	if err != nil {
+		return err
+	}
+	if len(managerIDs) == 0 && len(synced) == 0 {
+		return nil
+	}
+	added := utils.Difference(managerIDs, synced)
+	removed := utils.Difference(synced, managerIDs)
+
+	var items []*feed_model.FeedItem
+	for _, managerID := range added {
+		articleIDs, err := f.getOutbox(managerID, 0, f.config.Feed.InboxSize)
+		if err != nil {
+			return err
+		}
+		for _, id := range articleIDs {
+			items = append(items, &feed_model.FeedItem{ArticleID: id, ManagerID: managerID})
+		}
+	}
+	return f.feedCacheRepository.SyncInbox(userID, items, added, removed, f.config.Feed.InboxSize, f.config.Feed.InboxTTL)
+}
+
+// getOutbox 从新到旧获取管理员发件箱中 ID 小于 before 的至多 limit 篇文章，发件箱不在缓存中时由 MySQL 重建
+func (f *FeedService) getOutbox(managerID int, before int, limit int) ([]int, error) {
+	articleIDs, cached, err := f.feedCacheRepository.GetOutbox(managerID, before, limit)
+	if err != nil || cached {
+		return articleIDs, err
+	}
+	all, err := f.loadOutbox(managerID)
+	if err != nil {
+		return nil, err
+	}
+	articleIDs = make([]int, 0, limit)
+	for _, id := range all {
+		if len(articleIDs) == limit {
+			break
+		}
+		if before <= 0 || id < before {
+			articleIDs = append(articleIDs, id)
+		}
+	}
+	return articleIDs, nil
+}
+
+// loadOutbox 由 MySQL 重建管理员的发件箱，返回从新到旧排列的文章 ID
+func (f *FeedService) loadOutbox(managerID int) ([]int, error) {
+	articleIDs, err := f.articleRepository.GetArticleIDsByManager(managerID, f.config.Feed.OutboxSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.feedCacheRepository.LoadOutbox(managerID, articleIDs); err != nil {
+		return nil, err
+	}
+	return articleIDs, nil
+}
@@ -0,0 +1,94 @@
+package job_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"huancuilou/common/error_handler"
+	"huancuilou/internal/job/job_service"
+	"huancuilou/response"
+	"net/http"
+	"strconv"
+)
+
+type JobController struct {
+	JobService *job_service.JobService
+}
+
+func NewJobController(jobService *job_service.JobService) *JobController {
+	return &JobController{
+		JobService: jobService,
+	}
+}
+
+func (j *JobController) GetJobs(c *gin.Context) {
+	jobs, err := j.JobService.GetJobs()
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.GetJobs err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(jobs))
+}
+
+func (j *JobController) GetRuns(c *gin.Context) {
+	jobID, err := strconv.Atoi(c.Param("jobID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.GetRuns err: 400: 将jobID转换为int失败:%w", err))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.GetRuns err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.GetRuns err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	result, err := j.JobService.GetRuns(jobID, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.GetRuns err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+func (j *JobController) PauseJob(c *gin.Context) {
+	jobID, err := strconv.Atoi(c.Param("jobID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.PauseJob err: 400: 将jobID转换为int失败:%w", err))
+		return
+	}
+	if err := j.JobService.PauseJob(jobID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.PauseJob err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (j *JobController) ResumeJob(c *gin.Context) {
+	jobID, err := strconv.Atoi(c.Param("jobID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.ResumeJob err: 400: 将jobID转换为int失败:%w", err))
+		return
+	}
+	if err := j.JobService.ResumeJob(jobID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.ResumeJob err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (j *JobController) TriggerJob(c *gin.Context) {
+	jobID, err := strconv.Atoi(c.Param("jobID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.TriggerJob err: 400: 将jobID转换为int失败:%w", err))
+		return
+	}
+	if err := j.JobService.TriggerJob(jobID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("JobController.TriggerJob err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
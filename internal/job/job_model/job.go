@@ -0,0 +1,62 @@
+package job_model
+
+import "time"
+
+// 任务类型
+const (
+	KindCron = "cron" // 按 cron 表达式周期运行
+	KindOnce = "once" // 在指定时间运行一次
+)
+
+// 任务状态
+const (
+	StatusActive   = 0 // 到期后运行
+	StatusPaused   = 1 // 管理员暂停，到期也不运行
+	StatusFinished = 2 // 一次性任务已运行结束
+)
+
+// 运行记录状态
+const (
+	RunRunning     = 0
+	RunSuccess     = 1
+	RunFailed      = 2
+	RunInterrupted = 3 // 运行中的实例失去调度器租约或宕机
+)
+
+// Job 调度器中的一个任务
+// Handler 为注册到调度器的处理函数名称，Payload 为传给处理函数的参数，由处理函数自行解析
+type Job struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Kind         string     `json:"kind"`
+	Spec         string     `json:"spec"`
+	Handler      string     `json:"handler"`
+	Payload      string     `json:"payload"`
+	Status       int        `json:"status"`
+	Running      bool       `json:"running"`
+	MaxRetries   int        `json:"maxRetries"`
+	RetryBackoff int        `json:"retryBackoff"` // 第一次重试前等待的秒数，之后每次重试翻倍
+	NextRunAt    *time.Time `json:"nextRunAt"`
+	LastRunAt    *time.Time `json:"lastRunAt"`
+	CreateAt     time.Time  `json:"createAt"`
+}
+
+func (Job) TableName() string {
+	return "job"
+}
+
+// JobRun 任务的一次运行记录，每次重试单独记录
+type JobRun struct {
+	ID       int        `json:"id"`
+	JobID    int        `json:"jobID"`
+	Attempt  int        `json:"attempt"`
+	Status   int        `json:"status"`
+	Error    string     `json:"error"`
+	Instance string     `json:"instance"`
+	StartAt  time.Time  `json:"startAt"`
+	EndAt    *time.Time `json:"endAt"`
+}
+
+func (JobRun) TableName() string {
+	return "job_run"
+}
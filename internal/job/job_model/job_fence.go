@@ -0,0 +1,12 @@
+package job_model
+
+// JobFence 记录每个任务已经写入过的最大防护令牌（fencing token）
+// 令牌由租约每次被新的实例获得时递增，写入 MySQL 前校验令牌，旧租约持有者在失去租约后无法再写入
+type JobFence struct {
+	Name  string `gorm:"primaryKey"`
+	Token int64
+}
+
+func (JobFence) TableName() string {
+	return "job_fence"
+}
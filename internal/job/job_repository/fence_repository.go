@@ -0,0 +1,43 @@
+package job_repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"huancuilou/internal/job/job_model"
+)
+
+// ErrStaleToken 防护令牌小于已经写入过的令牌，说明租约已被其他实例获得
+var ErrStaleToken = errors.New("防护令牌已过期")
+
+type FenceRepository struct {
+	DB *gorm.DB
+}
+
+func NewFenceRepository(db *gorm.DB) *FenceRepository {
+	return &FenceRepository{
+		DB: db,
+	}
+}
+
+// CheckToken 在事务中校验并记录任务的防护令牌
+// 使用 SELECT ... FOR UPDATE 锁住任务的令牌行，持有旧令牌的事务会在新令牌写入后被拒绝
+func (f *FenceRepository) CheckToken(tx *gorm.DB, name string, token int64) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&job_model.JobFence{Name: name}).Error; err != nil {
+		return fmt.Errorf("FenceRepository.CheckToken err: %w", err)
+	}
+	var fence job_model.JobFence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&fence).Error; err != nil {
+		return fmt.Errorf("FenceRepository.CheckToken err: %w", err)
+	}
+	if token < fence.Token {
+		return fmt.Errorf("FenceRepository.CheckToken err: 任务: %s, 令牌: %d, 最新令牌: %d: %w", name, token, fence.Token, ErrStaleToken)
+	}
+	if token > fence.Token {
+		if err := tx.Model(&job_model.JobFence{}).Where("name = ?", name).Update("token", token).Error; err != nil {
+			return fmt.Errorf("FenceRepository.CheckToken err: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package job_repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/internal/job/job_model"
+	"time"
+)
+
+type JobRepository struct {
+	DB *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{
+		DB: db,
+	}
+}
+
+// EnsureJob 任务不存在时创建；已存在时保留原有的状态，只在 cron 表达式被修改时同步表达式和下次运行时间
+func (j *JobRepository) EnsureJob(job *job_model.Job) error {
+	var existing job_model.Job
+	if err := j.DB.Where("name = ?", job.Name).Attrs(job).FirstOrCreate(&existing).Error; err != nil {
+		return fmt.Errorf("JobRepository.EnsureJob err: %w", err)
+	}
+	if existing.Spec == job.Spec {
+		return nil
+	}
+	if err := j.DB.Model(&job_model.Job{}).Where("id = ?", existing.ID).Updates(map[string]interface{}{
+		"spec":        job.Spec,
+		"next_run_at": job.NextRunAt,
+	}).Error; err != nil {
+		return fmt.Errorf("JobRepository.EnsureJob err: %w", err)
+	}
+	return nil
+}
+
+// SaveOnceJob 创建或重新设置一次性任务的运行时间和参数，任务正在运行时不修改
+func (j *JobRepository) SaveOnceJob(job *job_model.Job) error {
+	existing, err := j.GetJobByName(job.Name)
+	if err != nil {
+		return fmt.Errorf("JobRepository.SaveOnceJob err: %w", err)
+	}
+	if existing == nil {
+		if err := j.DB.Create(job).Error; err != nil {
+			return fmt.Errorf("JobRepository.SaveOnceJob err: %w", err)
+		}
+		return nil
+	}
+	result := j.DB.Model(&job_model.Job{}).Where("id = ? AND running = ?", existing.ID, false).
+		Updates(map[string]interface{}{
+			"payload":     job.Payload,
+			"status":      job_model.StatusActive,
+			"next_run_at": job.NextRunAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("JobRepository.SaveOnceJob err: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("JobRepository.SaveOnceJob err: 任务%s正在运行", job.Name)
+	}
+	return nil
+}
+
+func (j *JobRepository) GetJobs() ([]*job_model.Job, error) {
+	var jobs []*job_model.Job
+	if err := j.DB.Order("id ASC").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("JobRepository.GetJobs err: %w", err)
+	}
+	return jobs, nil
+}
+
+func (j *JobRepository) GetJobByID(id int) (*job_model.Job, error) {
+	var job job_model.Job
+	if err := j.DB.Where("id = ?", id).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("JobRepository.GetJobByID err: %w", err)
+	}
+	return &job, nil
+}
+
+func (j *JobRepository) GetJobByName(name string) (*job_model.Job, error) {
+	var job job_model.Job
+	if err := j.DB.Where("name = ?", name).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("JobRepository.GetJobByName err: %w", err)
+	}
+	return &job, nil
+}
+
+// GetDueJobs 获取已到运行时间且没有在运行的任务
+func (j *JobRepository) GetDueJobs(now time.Time, limit int) ([]*job_model.Job, error) {
+	var jobs []*job_model.Job
+	if err := j.DB.Where("status = ? AND running = ? AND next_run_at <= ?", job_model.StatusActive, false, now).
+		Order("next_run_at ASC").Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("JobRepository.GetDueJobs err: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimJob 将任务标记为运行中，只有没有在运行的任务才会被标记，返回是否标记成功
+func (j *JobRepository) ClaimJob(id int) (bool, error) {
+	result := j.DB.Model(&job_model.Job{}).Where("id = ? AND running = ?", id, false).Update("running", true)
+	if result.Error != nil {
+		return false, fmt.Errorf("JobRepository.ClaimJob err: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FinishJob 任务运行结束后取消运行中标记并更新下次运行时间，finished 为 true 时将任务标记为已结束
+// 运行期间管理员可能暂停了任务，未结束的任务不修改状态
+func (j *JobRepository) FinishJob(id int, finished bool, lastRunAt time.Time, nextRunAt *time.Time) error {
+	updates := map[string]interface{}{
+		"running":     false,
+		"last_run_at": &lastRunAt,
+		"next_run_at": nextRunAt,
+	}
+	if finished {
+		updates["status"] = job_model.StatusFinished
+	}
+	if err := j.DB.Model(&job_model.Job{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return fmt.Errorf("JobRepository.FinishJob err: %w", err)
+	}
+	return nil
+}
+
+// ReleaseJob 只取消运行中标记，不改变下次运行时间，任务会被重新调度
+func (j *JobRepository) ReleaseJob(id int) error {
+	if err := j.DB.Model(&job_model.Job{}).Where("id = ?", id).Update("running", false).Error; err != nil {
+		return fmt.Errorf("JobRepository.ReleaseJob err: %w", err)
+	}
+	return nil
+}
+
+// ResetRunningJobs 重置上一个调度器实例遗留的运行中标记，并将未结束的运行记录标记为中断
+func (j *JobRepository) ResetRunningJobs() error {
+	now := time.Now()
+	return j.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&job_model.Job{}).Where("running = ?", true).Update("running", false).Error; err != nil {
+			return fmt.Errorf("JobRepository.ResetRunningJobs err: %w", err)
+		}
+		if err := tx.Model(&job_model.JobRun{}).Where("status = ?", job_model.RunRunning).Updates(map[string]interface{}{
+			"status": job_model.RunInterrupted,
+			"end_at": &now,
+		}).Error; err != nil {
+			return fmt.Errorf("JobRepository.ResetRunningJobs err: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateJobStatus 修改任务状态，nextRunAt 不为 nil 时同时修改下次运行时间
+func (j *JobRepository) UpdateJobStatus(id int, status int, nextRunAt *time.Time) error {
+	updates := map[string]interface{}{"status": status}
+	if nextRunAt != nil {
+		updates["next_run_at"] = nextRunAt
+	}
+	if err := j.DB.Model(&job_model.Job{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return fmt.Errorf("JobRepository.UpdateJobStatus err: %w", err)
+	}
+	return nil
+}
+
+func (j *JobRepository) AddRun(run *job_model.JobRun) error {
+	if err := j.DB.Create(run).Error; err != nil {
+		return fmt.Errorf("JobRepository.AddRun err: %w", err)
+	}
+	return nil
+}
+
+func (j *JobRepository) FinishRun(run *job_model.JobRun) error {
+	if err := j.DB.Model(&job_model.JobRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"status": run.Status,
+		"error":  run.Error,
+		"end_at": run.EndAt,
+	}).Error; err != nil {
+		return fmt.Errorf("JobRepository.FinishRun err: %w", err)
+	}
+	return nil
+}
+
+// GetRuns 分页获取任务的运行记录，最新的在前
+func (j *JobRepository) GetRuns(jobID int, offset int, limit int) ([]*job_model.JobRun, int64, error) {
+	var runs []*job_model.JobRun
+	var total int64
+	query := j.DB.Model(&job_model.JobRun{}).Where("job_id = ?", jobID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("JobRepository.GetRuns err: %w", err)
+	}
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&runs).Error; err != nil {
+		return nil, 0, fmt.Errorf("JobRepository.GetRuns err: %w", err)
+	}
+	return runs, total, nil
+}
@@ -0,0 +1,92 @@
+package job_repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// JobCachePrefix 定义任务相关缓存键的前缀
+const JobCachePrefix = "hcl:job"
+
+// LeaseRepository 基于 Redis 的租约，同一时刻只有一个实例持有某个任务的租约
+// 租约的值为 "实例 ID:防护令牌"，令牌由计数器递增生成，每次有实例新获得租约时加一
+type LeaseRepository struct {
+	client *redis.Client
+}
+
+func NewLeaseRepository(client *redis.Client) *LeaseRepository {
+	return &LeaseRepository{client: client}
+}
+
+// acquireScript 租约空闲时由当前实例获得并生成新令牌；当前实例已持有时续期并返回原令牌；被其他实例持有时返回 0
+var acquireScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if value then
+    local owner, token = string.match(value, '^(.*):(%d+)$')
+    if owner == ARGV[1] then
+        redis.call('PEXPIRE', KEYS[1], ARGV[2])
+        return tonumber(token)
+    end
+    return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. ':' .. token, 'PX', ARGV[2])
+return token
+`)
+
+// renewScript 只有租约仍由当前实例以相同令牌持有时才续期
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript 只有租约仍由当前实例以相同令牌持有时才释放，避免误删其他实例的租约
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Acquire 尝试获得任务的租约，获得时返回防护令牌，被其他实例持有时返回 0
+func (l *LeaseRepository) Acquire(name string, instanceID string, ttl time.Duration) (int64, error) {
+	ctx := context.Background()
+	token, err := acquireScript.Run(ctx, l.client, []string{leaseKey(name), tokenKey(name)}, instanceID, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("LeaseRepository.Acquire err: %w", err)
+	}
+	return token, nil
+}
+
+// Renew 为租约续期，返回租约是否仍由当前实例持有
+func (l *LeaseRepository) Renew(name string, instanceID string, token int64, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+	value := fmt.Sprintf("%s:%d", instanceID, token)
+	renewed, err := renewScript.Run(ctx, l.client, []string{leaseKey(name)}, value, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("LeaseRepository.Renew err: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// Release 主动释放租约，其他实例可以立即获得
+func (l *LeaseRepository) Release(name string, instanceID string, token int64) error {
+	ctx := context.Background()
+	value := fmt.Sprintf("%s:%d", instanceID, token)
+	if err := releaseScript.Run(ctx, l.client, []string{leaseKey(name)}, value).Err(); err != nil {
+		return fmt.Errorf("LeaseRepository.Release err: %w", err)
+	}
+	return nil
+}
+
+func leaseKey(name string) string {
+	return fmt.Sprintf("%s:lease:%s", JobCachePrefix, name)
+}
+
+func tokenKey(name string) string {
+	return fmt.Sprintf("%s:token:%s", JobCachePrefix, name)
+}
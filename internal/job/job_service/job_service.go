@@ -0,0 +1,118 @@
+package job_service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/configs"
+	"huancuilou/internal/job/job_repository"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ExclusiveJob 只能在一个实例上运行的任务，token 为本次获得租约时的防护令牌
+// 任务应在 ctx 被取消（失去租约）时尽快返回
+type ExclusiveJob func(ctx context.Context, token int64)
+
+type JobService struct {
+	jobRepository   *job_repository.JobRepository
+	leaseRepository *job_repository.LeaseRepository
+	fenceRepository *job_repository.FenceRepository
+	handlers        map[string]JobHandler
+	handlersMu      sync.RWMutex
+	instanceID      string
+	config          *configs.Config
+}
+
+func NewJobService(jobRepository *job_repository.JobRepository, leaseRepository *job_repository.LeaseRepository,
+	fenceRepository *job_repository.FenceRepository, config *configs.Config) *JobService {
+	return &JobService{
+		jobRepository:   jobRepository,
+		leaseRepository: leaseRepository,
+		fenceRepository: fenceRepository,
+		handlers:        make(map[string]JobHandler),
+		instanceID:      newInstanceID(),
+		config:          config,
+	}
+}
+
+// RunExclusive 在所有实例中选出一个运行任务，阻塞运行，通常放在单独的协程中调用
+// 未获得租约的实例定期重试；持有租约的实例定期续期，续期失败时取消任务并重新竞争租约；
+// 持有租约的实例宕机后租约自然过期，由其他实例接管并获得更大的防护令牌
+func (j *JobService) RunExclusive(name string, job ExclusiveJob) {
+	ttl := j.config.Job.LeaseTTL
+	for {
+		token, err := j.leaseRepository.Acquire(name, j.instanceID, ttl)
+		if err != nil {
+			log.Printf("获取任务%s的租约出错: %v", name, err)
+		}
+		if token == 0 {
+			time.Sleep(j.config.Job.AcquireInterval)
+			continue
+		}
+
+		log.Printf("实例%s获得任务%s的租约，令牌: %d", j.instanceID, name, token)
+		ctx, cancel := context.WithCancel(context.Background())
+		go j.keepAlive(ctx, cancel, name, token)
+		job(ctx, token)
+		cancel()
+
+		if err := j.leaseRepository.Release(name, j.instanceID, token); err != nil {
+			log.Printf("释放任务%s的租约出错: %v", name, err)
+		}
+		log.Printf("实例%s结束任务%s", j.instanceID, name)
+		time.Sleep(j.config.Job.AcquireInterval)
+	}
+}
+
+// keepAlive 每隔租约有效期的三分之一续期一次，租约丢失或连续续期失败直到租约过期时取消任务
+func (j *JobService) keepAlive(ctx context.Context, cancel context.CancelFunc, name string, token int64) {
+	ttl := j.config.Job.LeaseTTL
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	lastRenewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := j.leaseRepository.Renew(name, j.instanceID, token, ttl)
+			if err != nil {
+				log.Printf("任务%s的租约续期出错: %v", name, err)
+				if time.Since(lastRenewed) < ttl {
+					continue
+				}
+			}
+			if !renewed {
+				log.Printf("实例%s失去任务%s的租约", j.instanceID, name)
+				cancel()
+				return
+			}
+			lastRenewed = time.Now()
+		}
+	}
+}
+
+// CheckFence 在事务中校验调度器的防护令牌，令牌已过期时返回 job_repository.ErrStaleToken
+// 调度器中的任务都在持有调度器租约的实例上运行，写入 MySQL 的任务使用 JobContext.Token 调用
+func (j *JobService) CheckFence(tx *gorm.DB, token int64) error {
+	if err := j.fenceRepository.CheckToken(tx, SchedulerJob, token); err != nil {
+		return fmt.Errorf("JobService.CheckFence err: %w", err)
+	}
+	return nil
+}
+
+// newInstanceID 生成实例 ID，由主机名、进程号和随机串组成，同一台机器上的多个进程也不会重复
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(buf))
+}
@@ -0,0 +1,364 @@
+package job_service
+
+import (
+	"context"
+	"fmt"
+	"huancuilou/common/utils"
+	"huancuilou/internal/job/job_model"
+	"log"
+	"sync"
+	"time"
+)
+
+// SchedulerJob 调度器的租约名称，调度器只在持有租约的一个实例上运行，调度器中的任务共用这个租约的防护令牌
+const SchedulerJob = "job_scheduler"
+
+// JobContext 任务运行时的上下文
+type JobContext struct {
+	Job       *job_model.Job
+	Token     int64 // 调度器的防护令牌，写入 MySQL 时通过 JobService.CheckFence 校验
+	Attempt   int   // 第几次运行，重试时递增
+	nextRunAt *time.Time
+}
+
+// Reschedule 指定周期任务的下次运行时间，代替 cron 表达式计算出的时间，只对本次运行成功后生效
+func (c *JobContext) Reschedule(t time.Time) {
+	c.nextRunAt = &t
+}
+
+// JobHandler 任务的处理函数，返回错误时按任务的重试策略重试
+// 处理函数应在 ctx 被取消（调度器失去租约）时尽快返回 ctx.Err()，任务会由新的调度器实例重新运行
+type JobHandler func(ctx context.Context, jc *JobContext) error
+
+// RegisterHandler 注册任务的处理函数，需要在调度器运行前注册
+func (j *JobService) RegisterHandler(name string, handler JobHandler) {
+	j.handlersMu.Lock()
+	defer j.handlersMu.Unlock()
+	j.handlers[name] = handler
+}
+
+func (j *JobService) getHandler(name string) JobHandler {
+	j.handlersMu.RLock()
+	defer j.handlersMu.RUnlock()
+	return j.handlers[name]
+}
+
+// EnsureCronJob 创建周期任务，处理函数与任务同名；任务已存在时保留管理员设置的状态
+func (j *JobService) EnsureCronJob(name string, spec string, maxRetries int, retryBackoff int) error {
+	schedule, err := utils.ParseCron(spec)
+	if err != nil {
+		return fmt.Errorf("JobService.EnsureCronJob err: %w", err)
+	}
+	nextRunAt := schedule.Next(time.Now())
+	if nextRunAt.IsZero() {
+		return fmt.Errorf("JobService.EnsureCronJob err: cron 表达式 %q 不会被触发", spec)
+	}
+	job := &job_model.Job{
+		Name:         name,
+		Kind:         job_model.KindCron,
+		Spec:         spec,
+		Handler:      name,
+		Status:       job_model.StatusActive,
+		MaxRetries:   maxRetries,
+		RetryBackoff: retryBackoff,
+		NextRunAt:    &nextRunAt,
+		CreateAt:     time.Now(),
+	}
+	if err := j.jobRepository.EnsureJob(job); err != nil {
+		return fmt.Errorf("JobService.EnsureCronJob err: %w", err)
+	}
+	return nil
+}
+
+// ScheduleOnce 设置在 runAt 运行一次的任务，处理函数与任务同名；同名任务已存在时重新设置运行时间和参数
+func (j *JobService) ScheduleOnce(name string, runAt time.Time, payload string, maxRetries int, retryBackoff int) error {
+	job := &job_model.Job{
+		Name:         name,
+		Kind:         job_model.KindOnce,
+		Handler:      name,
+		Payload:      payload,
+		Status:       job_model.StatusActive,
+		MaxRetries:   maxRetries,
+		RetryBackoff: retryBackoff,
+		NextRunAt:    &runAt,
+		CreateAt:     time.Now(),
+	}
+	if err := j.jobRepository.SaveOnceJob(job); err != nil {
+		return fmt.Errorf("JobService.ScheduleOnce err: %w", err)
+	}
+	return nil
+}
+
+// RunScheduler 调度器主循环，通过 RunExclusive 在持有租约的实例上运行，失去租约时等待运行中的任务返回后退出
+// 每隔一个轮询间隔取出到期的任务，标记为运行中后在单独的协程中运行，同一个任务不会同时运行多次
+func (j *JobService) RunScheduler(ctx context.Context, token int64) {
+	// 上一个持有租约的实例可能在运行任务时宕机，遗留的运行中标记会使任务永远不再被调度
+	if err := j.jobRepository.ResetRunningJobs(); err != nil {
+		log.Printf("重置运行中的任务出错: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ticker := time.NewTicker(j.config.Job.PollInterval)
+	defer ticker.Stop()
+	for {
+		j.dispatch(ctx, token, &wg)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch 取出到期的任务并逐个启动
+func (j *JobService) dispatch(ctx context.Context, token int64, wg *sync.WaitGroup) {
+	jobs, err := j.jobRepository.GetDueJobs(time.Now(), j.config.Job.PollBatchSize)
+	if err != nil {
+		log.Printf("查询到期任务出错: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		claimed, err := j.jobRepository.ClaimJob(job.ID)
+		if err != nil {
+			log.Printf("标记任务%s为运行中出错: %v", job.Name, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		wg.Add(1)
+		go func(job *job_model.Job) {
+			defer wg.Done()
+			j.execute(ctx, job, token)
+		}(job)
+	}
+}
+
+// execute 运行任务，失败时按指数退避重试，每次运行都记录到运行记录中
+func (j *JobService) execute(ctx context.Context, job *job_model.Job, token int64) {
+	handler := j.getHandler(job.Handler)
+	startAt := time.Now()
+	jc := &JobContext{Job: job, Token: token}
+	var err error
+	for attempt := 1; ; attempt++ {
+		jc.Attempt = attempt
+		run := &job_model.JobRun{
+			JobID:    job.ID,
+			Attempt:  attempt,
+			Status:   job_model.RunRunning,
+			Instance: j.instanceID,
+			StartAt:  time.Now(),
+		}
+		if err := j.jobRepository.AddRun(run); err != nil {
+			log.Printf("记录任务%s的运行出错: %v", job.Name, err)
+		}
+
+		if handler == nil {
+			err = fmt.Errorf("任务处理函数%s未注册", job.Handler)
+		} else {
+			err = callHandler(ctx, handler, jc)
+		}
+		j.finishRun(ctx, run, err)
+		if err == nil || handler == nil || ctx.Err() != nil || attempt > job.MaxRetries {
+			break
+		}
+		backoff := time.Duration(job.RetryBackoff) * time.Second << min(attempt-1, 10)
+		log.Printf("任务%s第%d次运行失败，%s后重试: %v", job.Name, attempt, backoff, err)
+		if !sleepContext(ctx, backoff) {
+			break
+		}
+	}
+
+	// 失去租约时不更新下次运行时间，由新的调度器实例重新运行
+	if err != nil && ctx.Err() != nil {
+		if err := j.jobRepository.ReleaseJob(job.ID); err != nil {
+			log.Printf("释放任务%s出错: %v", job.Name, err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("任务%s运行失败: %v", job.Name, err)
+	}
+
+	finished, nextRunAt := nextRun(job, jc, err)
+	if err := j.jobRepository.FinishJob(job.ID, finished, startAt, nextRunAt); err != nil {
+		log.Printf("更新任务%s的下次运行时间出错: %v", job.Name, err)
+	}
+}
+
+// finishRun 根据运行结果更新运行记录
+func (j *JobService) finishRun(ctx context.Context, run *job_model.JobRun, err error) {
+	endAt := time.Now()
+	run.EndAt = &endAt
+	switch {
+	case err == nil:
+		run.Status = job_model.RunSuccess
+	case ctx.Err() != nil:
+		run.Status = job_model.RunInterrupted
+		run.Error = utils.Substring(err.Error(), 500)
+	default:
+		run.Status = job_model.RunFailed
+		run.Error = utils.Substring(err.Error(), 500)
+	}
+	if run.ID == 0 {
+		return
+	}
+	if err := j.jobRepository.FinishRun(run); err != nil {
+		log.Printf("更新任务运行记录%d出错: %v", run.ID, err)
+	}
+}
+
+// nextRun 计算任务的下次运行时间
+// 一次性任务无论成功与否都结束，失败可以在运行记录中查看；周期任务优先使用处理函数指定的时间
+func nextRun(job *job_model.Job, jc *JobContext, err error) (bool, *time.Time) {
+	if job.Kind == job_model.KindOnce {
+		return true, nil
+	}
+	if err == nil && jc.nextRunAt != nil {
+		return false, jc.nextRunAt
+	}
+	schedule, parseErr := utils.ParseCron(job.Spec)
+	if parseErr != nil {
+		log.Printf("任务%s的 cron 表达式错误: %v", job.Name, parseErr)
+		return true, nil
+	}
+	next := schedule.Next(time.Now())
+	if next.IsZero() {
+		return true, nil
+	}
+	return false, &next
+}
+
+// callHandler 调用处理函数，处理函数 panic 时转换为错误，不影响调度器和其他任务
+func callHandler(ctx context.Context, handler JobHandler, jc *JobContext) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务处理函数 panic: %v", r)
+		}
+	}()
+	return handler(ctx, jc)
+}
+
+// RunLocal 在当前实例上按 cron 表达式周期运行函数，用于清理本实例内存数据这类每个实例都要运行的任务
+// 这类任务不写入数据库也不记录运行历史，出错时只记录日志
+func (j *JobService) RunLocal(name string, spec string, fn func() error) error {
+	schedule, err := utils.ParseCron(spec)
+	if err != nil {
+		return fmt.Errorf("JobService.RunLocal err: %w", err)
+	}
+	go func() {
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				return
+			}
+			time.Sleep(time.Until(next))
+			if err := fn(); err != nil {
+				log.Printf("本地任务%s运行出错: %v", name, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// GetJobs 获取所有任务
+func (j *JobService) GetJobs() ([]*job_model.Job, error) {
+	jobs, err := j.jobRepository.GetJobs()
+	if err != nil {
+		return nil, fmt.Errorf("JobService.GetJobs err: 500: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetRuns 分页获取任务的运行记录
+func (j *JobService) GetRuns(jobID int, page int, size int) (map[string]interface{}, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = j.config.Job.PageSize
+	}
+	runs, total, err := j.jobRepository.GetRuns(jobID, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("JobService.GetRuns err: 500: %w", err)
+	}
+	return map[string]interface{}{
+		"total": total,
+		"page":  page,
+		"size":  size,
+		"runs":  runs,
+	}, nil
+}
+
+// PauseJob 暂停任务，正在进行的运行不受影响
+func (j *JobService) PauseJob(id int) error {
+	job, err := j.getJob(id)
+	if err != nil {
+		return fmt.Errorf("JobService.PauseJob err: %w", err)
+	}
+	if job.Status != job_model.StatusActive {
+		return fmt.Errorf("JobService.PauseJob err: 400:只能暂停运行中的任务")
+	}
+	if err := j.jobRepository.UpdateJobStatus(id, job_model.StatusPaused, nil); err != nil {
+		return fmt.Errorf("JobService.PauseJob err: 500: %w", err)
+	}
+	return nil
+}
+
+// ResumeJob 恢复暂停的任务，暂停期间错过的运行会在恢复后立即补运行一次
+func (j *JobService) ResumeJob(id int) error {
+	job, err := j.getJob(id)
+	if err != nil {
+		return fmt.Errorf("JobService.ResumeJob err: %w", err)
+	}
+	if job.Status != job_model.StatusPaused {
+		return fmt.Errorf("JobService.ResumeJob err: 400:任务没有被暂停")
+	}
+	if err := j.jobRepository.UpdateJobStatus(id, job_model.StatusActive, nil); err != nil {
+		return fmt.Errorf("JobService.ResumeJob err: 500: %w", err)
+	}
+	return nil
+}
+
+// TriggerJob 立即运行任务，已结束的一次性任务会再运行一次
+func (j *JobService) TriggerJob(id int) error {
+	job, err := j.getJob(id)
+	if err != nil {
+		return fmt.Errorf("JobService.TriggerJob err: %w", err)
+	}
+	if job.Status == job_model.StatusPaused {
+		return fmt.Errorf("JobService.TriggerJob err: 400:任务已暂停")
+	}
+	if job.Running {
+		return fmt.Errorf("JobService.TriggerJob err: 400:任务正在运行")
+	}
+	now := time.Now()
+	if err := j.jobRepository.UpdateJobStatus(id, job_model.StatusActive, &now); err != nil {
+		return fmt.Errorf("JobService.TriggerJob err: 500: %w", err)
+	}
+	return nil
+}
+
+func (j *JobService) getJob(id int) (*job_model.Job, error) {
+	job, err := j.jobRepository.GetJobByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("500: %w", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("400:任务不存在")
+	}
+	return job, nil
+}
+
+// sleepContext 等待 d 或 ctx 被取消，被取消时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
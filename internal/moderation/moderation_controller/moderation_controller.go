@@ -0,0 +1,107 @@
+package moderation_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"huancuilou/common/error_handler"
+	"huancuilou/internal/moderation/moderation_model"
+	"huancuilou/internal/moderation/moderation_service"
+	"huancuilou/response"
+	"net/http"
+	"strconv"
+)
+
+type ModerationController struct {
+	ModerationService *moderation_service.ModerationService
+}
+
+func NewModerationController(moderationService *moderation_service.ModerationService) *ModerationController {
+	return &ModerationController{
+		ModerationService: moderationService,
+	}
+}
+
+func (m *ModerationController) GetRecords(c *gin.Context) {
+	status, err := strconv.Atoi(c.DefaultQuery("status", strconv.Itoa(moderation_model.StatusPending)))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.GetRecords err: 400: 将status转换为int失败:%w", err))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.GetRecords err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.GetRecords err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	result, err := m.ModerationService.GetRecords(status, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.GetRecords err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+func (m *ModerationController) Approve(c *gin.Context) {
+	recordID, err := strconv.Atoi(c.Param("recordID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.Approve err: 400: 将recordID转换为int失败:%w", err))
+		return
+	}
+	reviewerID := c.MustGet("userID").(int)
+
+	if err := m.ModerationService.Approve(recordID, reviewerID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.Approve err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (m *ModerationController) Reject(c *gin.Context) {
+	recordID, err := strconv.Atoi(c.Param("recordID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.Reject err: 400: 将recordID转换为int失败:%w", err))
+		return
+	}
+	reviewerID := c.MustGet("userID").(int)
+
+	if err := m.ModerationService.Reject(recordID, reviewerID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.Reject err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (m *ModerationController) GetWords(c *gin.Context) {
+	c.JSON(http.StatusOK, response.Success(m.ModerationService.GetWords()))
+}
+
+func (m *ModerationController) AddWord(c *gin.Context) {
+	var word moderation_model.SensitiveWord
+	if err := c.BindJSON(&word); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.AddWord err: 400:将json数据绑定到结构体失败:%w", err))
+		return
+	}
+	if err := m.ModerationService.AddWord(word.Word, word.Action); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.AddWord err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (m *ModerationController) RemoveWord(c *gin.Context) {
+	word := c.Query("word")
+	if word == "" {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.RemoveWord err: 400: 敏感词不能为空"))
+		return
+	}
+	if err := m.ModerationService.RemoveWord(word); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("ModerationController.RemoveWord err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
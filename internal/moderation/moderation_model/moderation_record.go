@@ -0,0 +1,36 @@
+package moderation_model
+
+import "time"
+
+// 审核队列中的内容类型
+const (
+	ContentUserName      = "user_name"
+	ContentUserBiography = "user_biography"
+	ContentArticle       = "article"
+	ContentComment       = "comment"
+)
+
+// 审核状态
+const (
+	StatusPending  = 0
+	StatusApproved = 1
+	StatusRejected = 2
+)
+
+// ModerationRecord 审核队列中的一条待审内容
+type ModerationRecord struct {
+	ID          int        `json:"id"`
+	ContentType string     `json:"contentType"`
+	ContentID   int        `json:"contentID"`
+	UserID      int        `json:"userID"`
+	Content     string     `json:"content"`
+	Words       string     `json:"words"`
+	Status      int        `json:"status"`
+	ReviewerID  int        `json:"reviewerID"`
+	CreateAt    time.Time  `json:"createAt"`
+	ReviewAt    *time.Time `json:"reviewAt"`
+}
+
+func (ModerationRecord) TableName() string {
+	return "moderation_record"
+}
@@ -0,0 +1,44 @@
+package moderation_model
+
+import "time"
+
+// 敏感词处理方式，按严格程度从低到高排列
+const (
+	ActionPass   = "pass"   // 未命中敏感词
+	ActionMask   = "mask"   // 将敏感词替换为 *
+	ActionReview = "review" // 内容进入审核队列，审核通过后生效
+	ActionBlock  = "block"  // 直接拒绝提交
+)
+
+// ActionLevel 返回处理方式的严格程度，用于在多个命中中取最严格的处理方式
+func ActionLevel(action string) int {
+	switch action {
+	case ActionMask:
+		return 1
+	case ActionReview:
+		return 2
+	case ActionBlock:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// SensitiveWord 管理员维护的敏感词
+type SensitiveWord struct {
+	ID       int       `json:"id"`
+	Word     string    `json:"word"`
+	Action   string    `json:"action"`
+	CreateAt time.Time `json:"createAt"`
+}
+
+func (SensitiveWord) TableName() string {
+	return "sensitive_word"
+}
+
+// CheckResult 敏感词检查结果
+type CheckResult struct {
+	Action string   // 命中词中最严格的处理方式
+	Words  []string // 命中的敏感词
+	Masked string   // 将所有命中词替换为 * 后的文本
+}
@@ -0,0 +1,102 @@
+package moderation_repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/internal/moderation/moderation_model"
+	"time"
+)
+
+type ModerationRepository struct {
+	DB *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) *ModerationRepository {
+	return &ModerationRepository{
+		DB: db,
+	}
+}
+
+func (m *ModerationRepository) GetAllWords() ([]*moderation_model.SensitiveWord, error) {
+	var words []*moderation_model.SensitiveWord
+	if err := m.DB.Order("id").Find(&words).Error; err != nil {
+		return nil, fmt.Errorf("ModerationRepository.GetAllWords err: %w", err)
+	}
+	return words, nil
+}
+
+// SaveWord 添加敏感词，敏感词已存在时更新其处理方式
+func (m *ModerationRepository) SaveWord(word *moderation_model.SensitiveWord) error {
+	var existing moderation_model.SensitiveWord
+	result := m.DB.Where("word = ?", word.Word).First(&existing)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("ModerationRepository.SaveWord err: %w", result.Error)
+	}
+	if result.Error == nil {
+		if err := m.DB.Model(&existing).Update("action", word.Action).Error; err != nil {
+			return fmt.Errorf("ModerationRepository.SaveWord err: %w", err)
+		}
+		return nil
+	}
+	word.CreateAt = time.Now()
+	if err := m.DB.Create(word).Error; err != nil {
+		return fmt.Errorf("ModerationRepository.SaveWord err: %w", err)
+	}
+	return nil
+}
+
+func (m *ModerationRepository) RemoveWord(word string) error {
+	if err := m.DB.Where("word = ?", word).Delete(&moderation_model.SensitiveWord{}).Error; err != nil {
+		return fmt.Errorf("ModerationRepository.RemoveWord err: %w", err)
+	}
+	return nil
+}
+
+func (m *ModerationRepository) AddRecord(tx *gorm.DB, record *moderation_model.ModerationRecord) error {
+	if err := tx.Create(record).Error; err != nil {
+		return fmt.Errorf("ModerationRepository.AddRecord err: %w", err)
+	}
+	return nil
+}
+
+func (m *ModerationRepository) GetRecordByID(id int) (*moderation_model.ModerationRecord, error) {
+	var record moderation_model.ModerationRecord
+	result := m.DB.First(&record, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ModerationRepository.GetRecordByID err: %w", result.Error)
+	}
+	return &record, nil
+}
+
+func (m *ModerationRepository) GetRecords(status int, offset int, limit int) ([]*moderation_model.ModerationRecord, int64, error) {
+	var records []*moderation_model.ModerationRecord
+	var total int64
+	query := m.DB.Model(&moderation_model.ModerationRecord{}).Where("status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("ModerationRepository.GetRecords err: %w", err)
+	}
+	if err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("ModerationRepository.GetRecords err: %w", err)
+	}
+	return records, total, nil
+}
+
+// FinishRecord 将待审内容标记为已审核，只有仍处于待审状态的记录才会被更新，防止重复审核
+func (m *ModerationRepository) FinishRecord(tx *gorm.DB, id int, status int, reviewerID int) (bool, error) {
+	now := time.Now()
+	result := tx.Model(&moderation_model.ModerationRecord{}).
+		Where("id = ? AND status = ?", id, moderation_model.StatusPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewer_id": reviewerID,
+			"review_at":   &now,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("ModerationRepository.FinishRecord err: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,317 @@
+package moderation_service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/common/utils"
+	"huancuilou/configs"
+	"huancuilou/internal/moderation/moderation_model"
+	"huancuilou/internal/moderation/moderation_repository"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReviewHandler 审核结果的处理方，由产生内容的模块提供
+// Approve 在审核通过后让内容生效，Reject 在审核拒绝后处理被拒绝的内容
+type ReviewHandler struct {
+	Approve ReviewFunc
+	Reject  ReviewFunc
+}
+
+// ReviewFunc 在更新审核状态的事务 tx 中处理审核结果，数据库的修改都要使用 tx，与审核状态一起提交或回滚
+// 返回的 afterCommit 在事务提交后调用，用于更新缓存、发送通知等不能回滚的操作，没有时返回 nil
+type ReviewFunc func(tx *gorm.DB, record *moderation_model.ModerationRecord) (afterCommit func(), err error)
+
+type ModerationService struct {
+	moderationRepository *moderation_repository.ModerationRepository
+	config               *configs.Config
+
+	mu       sync.RWMutex
+	matcher  *utils.SensitiveMatcher
+	actions  map[string]string // 敏感词 -> 处理方式
+	handlers map[string]ReviewHandler
+}
+
+func NewModerationService(moderationRepository *moderation_repository.ModerationRepository, config *configs.Config) *ModerationService {
+	return &ModerationService{
+		moderationRepository: moderationRepository,
+		config:               config,
+		matcher:              utils.NewSensitiveMatcher(nil),
+		actions:              make(map[string]string),
+		handlers:             make(map[string]ReviewHandler),
+	}
+}
+
+// RegisterHandler 注册某类内容的审核结果处理方
+func (m *ModerationService) RegisterHandler(contentType string, handler ReviewHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[contentType] = handler
+}
+
+// LoadWords 从词典文件和数据库加载敏感词并重建匹配器，同一个词以数据库中的处理方式为准
+func (m *ModerationService) LoadWords() error {
+	actions, err := m.loadDictFile()
+	if err != nil {
+		return fmt.Errorf("ModerationService.LoadWords err: %w", err)
+	}
+	words, err := m.moderationRepository.GetAllWords()
+	if err != nil {
+		return fmt.Errorf("ModerationService.LoadWords err: %w", err)
+	}
+	for _, word := range words {
+		actions[word.Word] = word.Action
+	}
+
+	list := make([]string, 0, len(actions))
+	for word := range actions {
+		list = append(list, word)
+	}
+	matcher := utils.NewSensitiveMatcher(list)
+
+	m.mu.Lock()
+	m.matcher = matcher
+	m.actions = actions
+	m.mu.Unlock()
+	log.Printf("加载敏感词 %d 个", len(list))
+	return nil
+}
+
+func (m *ModerationService) loadDictFile() (map[string]string, error) {
+	actions := make(map[string]string)
+	if m.config.Moderation.DictPath == "" {
+		return actions, nil
+	}
+	file, err := os.Open(m.config.Moderation.DictPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("敏感词词典文件不存在: %s", m.config.Moderation.DictPath)
+			return actions, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		action := m.config.Moderation.DefaultAction
+		if len(fields) > 1 && validAction(fields[1]) {
+			action = fields[1]
+		}
+		actions[fields[0]] = action
+	}
+	return actions, scanner.Err()
+}
+
+// Check 检查文本中的敏感词，返回命中词中最严格的处理方式
+func (m *ModerationService) Check(text string) *moderation_model.CheckResult {
+	result, _ := m.check(text)
+	return result
+}
+
+// check 检查文本中的敏感词，同时返回只替换了替换词、保留审核词的文本，用于提交审核的内容
+func (m *ModerationService) check(text string) (*moderation_model.CheckResult, string) {
+	m.mu.RLock()
+	matcher, actions := m.matcher, m.actions
+	m.mu.RUnlock()
+
+	result := &moderation_model.CheckResult{Action: moderation_model.ActionPass, Masked: text}
+	hits := matcher.Match(text)
+	if len(hits) == 0 {
+		return result, text
+	}
+	var maskHits []utils.SensitiveHit
+	seen := make(map[string]bool)
+	for _, hit := range hits {
+		if actions[hit.Word] == moderation_model.ActionMask {
+			maskHits = append(maskHits, hit)
+		}
+		if seen[hit.Word] {
+			continue
+		}
+		seen[hit.Word] = true
+		result.Words = append(result.Words, hit.Word)
+		if moderation_model.ActionLevel(actions[hit.Word]) > moderation_model.ActionLevel(result.Action) {
+			result.Action = actions[hit.Word]
+		}
+	}
+	result.Masked = matcher.Mask(text, hits)
+	return result, matcher.Mask(text, maskHits)
+}
+
+// Filter 按敏感词策略处理一段用户生成的内容
+// 命中屏蔽词时返回 400 错误；命中替换词时返回打码后的文本；命中审核词时返回替换了替换词、保留审核词的文本，
+// 由调用方提交审核，审核通过后生效的内容中也不会出现替换词
+func (m *ModerationService) Filter(text string) (string, *moderation_model.CheckResult, error) {
+	result, reviewText := m.check(text)
+	switch result.Action {
+	case moderation_model.ActionBlock:
+		return "", result, fmt.Errorf("400: 内容包含违禁词: %s", strings.Join(result.Words, ","))
+	case moderation_model.ActionMask:
+		return result.Masked, result, nil
+	case moderation_model.ActionReview:
+		return reviewText, result, nil
+	default:
+		return text, result, nil
+	}
+}
+
+// Mask 将文本中所有命中的敏感词替换为 *，不区分处理方式
+func (m *ModerationService) Mask(text string) string {
+	return m.Check(text).Masked
+}
+
+// Submit 将内容提交到审核队列，tx 为写入内容时所在的事务，保证内容与审核记录同时写入
+func (m *ModerationService) Submit(tx *gorm.DB, contentType string, contentID int, userID int, content string, words []string) error {
+	record := &moderation_model.ModerationRecord{
+		ContentType: contentType,
+		ContentID:   contentID,
+		UserID:      userID,
+		Content:     content,
+		Words:       strings.Join(words, ","),
+		Status:      moderation_model.StatusPending,
+		CreateAt:    time.Now(),
+	}
+	if err := m.moderationRepository.AddRecord(tx, record); err != nil {
+		return fmt.Errorf("ModerationService.Submit err: %w", err)
+	}
+	return nil
+}
+
+func (m *ModerationService) GetRecords(status int, page int, size int) (map[string]interface{}, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = m.config.Moderation.PageSize
+	}
+	records, total, err := m.moderationRepository.GetRecords(status, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("ModerationService.GetRecords err: 500: %w", err)
+	}
+	return map[string]interface{}{
+		"total":   total,
+		"page":    page,
+		"size":    size,
+		"records": records,
+	}, nil
+}
+
+// Approve 审核通过，交由内容所属模块让内容生效
+func (m *ModerationService) Approve(recordID int, reviewerID int) error {
+	if err := m.finish(recordID, reviewerID, moderation_model.StatusApproved); err != nil {
+		return fmt.Errorf("ModerationService.Approve err: %w", err)
+	}
+	return nil
+}
+
+// Reject 审核拒绝，交由内容所属模块处理被拒绝的内容
+func (m *ModerationService) Reject(recordID int, reviewerID int) error {
+	if err := m.finish(recordID, reviewerID, moderation_model.StatusRejected); err != nil {
+		return fmt.Errorf("ModerationService.Reject err: %w", err)
+	}
+	return nil
+}
+
+// finish 在事务中更新审核状态并调用处理方，处理失败时审核记录保持待审状态，处理方的修改一起回滚
+func (m *ModerationService) finish(recordID int, reviewerID int, status int) error {
+	record, err := m.moderationRepository.GetRecordByID(recordID)
+	if err != nil {
+		return fmt.Errorf("500: %w", err)
+	}
+	if record == nil {
+		return fmt.Errorf("400: 审核记录不存在")
+	}
+
+	m.mu.RLock()
+	handler, ok := m.handlers[record.ContentType]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("500: 未注册的内容类型: %s", record.ContentType)
+	}
+
+	var afterCommit func()
+	err = m.moderationRepository.DB.Transaction(func(tx *gorm.DB) error {
+		updated, err := m.moderationRepository.FinishRecord(tx, recordID, status, reviewerID)
+		if err != nil {
+			return fmt.Errorf("500: %w", err)
+		}
+		if !updated {
+			return fmt.Errorf("400: 该内容已审核")
+		}
+		apply := handler.Reject
+		if status == moderation_model.StatusApproved {
+			apply = handler.Approve
+		}
+		if apply == nil {
+			return nil
+		}
+		if afterCommit, err = apply(tx, record); err != nil {
+			return fmt.Errorf("500: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if afterCommit != nil {
+		afterCommit()
+	}
+	return nil
+}
+
+func (m *ModerationService) GetWords() []*moderation_model.SensitiveWord {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	words := make([]*moderation_model.SensitiveWord, 0, len(m.actions))
+	for word, action := range m.actions {
+		words = append(words, &moderation_model.SensitiveWord{Word: word, Action: action})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		return words[i].Word < words[j].Word
+	})
+	return words
+}
+
+func (m *ModerationService) AddWord(word string, action string) error {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return fmt.Errorf("ModerationService.AddWord err: 400: 敏感词不能为空")
+	}
+	if !validAction(action) {
+		return fmt.Errorf("ModerationService.AddWord err: 400: 处理方式错误")
+	}
+	if err := m.moderationRepository.SaveWord(&moderation_model.SensitiveWord{Word: word, Action: action}); err != nil {
+		return fmt.Errorf("ModerationService.AddWord err: 500: %w", err)
+	}
+	if err := m.LoadWords(); err != nil {
+		return fmt.Errorf("ModerationService.AddWord err: 500: %w", err)
+	}
+	return nil
+}
+
+// RemoveWord 删除管理员添加的敏感词，词典文件中的词需要修改文件后才能删除
+func (m *ModerationService) RemoveWord(word string) error {
+	if err := m.moderationRepository.RemoveWord(word); err != nil {
+		return fmt.Errorf("ModerationService.RemoveWord err: 500: %w", err)
+	}
+	if err := m.LoadWords(); err != nil {
+		return fmt.Errorf("ModerationService.RemoveWord err: 500: %w", err)
+	}
+	return nil
+}
+
+func validAction(action string) bool {
+	return action == moderation_model.ActionMask || action == moderation_model.ActionReview || action == moderation_model.ActionBlock
+}
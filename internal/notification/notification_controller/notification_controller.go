@@ -0,0 +1,75 @@
+package notification_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"huancuilou/common/error_handler"
+	"huancuilou/internal/notification/notification_service"
+	"huancuilou/response"
+	"net/http"
+	"strconv"
+)
+
+type NotificationController struct {
+	NotificationService *notification_service.NotificationService
+}
+
+func NewNotificationController(notificationService *notification_service.NotificationService) *NotificationController {
+	return &NotificationController{
+		NotificationService: notificationService,
+	}
+}
+
+func (n *NotificationController) GetNotifications(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("NotificationController.GetNotifications err: 400: 将page转换为int失败:%w", err))
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("NotificationController.GetNotifications err: 400: 将size转换为int失败:%w", err))
+		return
+	}
+
+	result, err := n.NotificationService.GetNotifications(userID, page, size)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("NotificationController.GetNotifications err: 500: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(result))
+}
+
+func (n *NotificationController) CountUnread(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	count, err := n.NotificationService.CountUnread(userID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("NotificationController.CountUnread err: 500: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(count))
+}
+
+func (n *NotificationController) MarkRead(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	id, err := strconv.Atoi(c.Param("notificationID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("NotificationController.MarkRead err: 400: 将notificationID转换为int失败:%w", err))
+		return
+	}
+	if err := n.NotificationService.MarkRead(userID, id); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("NotificationController.MarkRead err: 500: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+func (n *NotificationController) MarkAllRead(c *gin.Context) {
+	userID := c.MustGet("userID").(int)
+	if err := n.NotificationService.MarkRead(userID, 0); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("NotificationController.MarkAllRead err: 500: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
@@ -0,0 +1,26 @@
+package notification_model
+
+import "time"
+
+// 通知类型
+const (
+	KindComment = "comment" // 文章收到评论
+	KindReply   = "reply"   // 评论收到回复
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"userID"`
+	SenderID  int       `json:"senderID"`
+	Kind      string    `json:"kind"`
+	ArticleID int       `json:"articleID"`
+	CommentID int       `json:"commentID"`
+	Content   string    `json:"content"`
+	IsRead    int       `json:"isRead"`
+	CreateAt  time.Time `json:"createAt"`
+}
+
+func (Notification) TableName() string {
+	return "notification"
+}
@@ -0,0 +1,58 @@
+package notification_repository
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/internal/notification/notification_model"
+)
+
+type NotificationRepository struct {
+	DB *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{
+		DB: db,
+	}
+}
+
+func (n *NotificationRepository) AddNotification(notification *notification_model.Notification) error {
+	if err := n.DB.Create(notification).Error; err != nil {
+		return fmt.Errorf("NotificationRepository.AddNotification err: %w", err)
+	}
+	return nil
+}
+
+func (n *NotificationRepository) GetNotifications(userID int, offset int, limit int) ([]*notification_model.Notification, int64, error) {
+	var notifications []*notification_model.Notification
+	var total int64
+	query := n.DB.Model(&notification_model.Notification{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("NotificationRepository.GetNotifications err: %w", err)
+	}
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, 0, fmt.Errorf("NotificationRepository.GetNotifications err: %w", err)
+	}
+	return notifications, total, nil
+}
+
+func (n *NotificationRepository) CountUnread(userID int) (int64, error) {
+	var count int64
+	result := n.DB.Model(&notification_model.Notification{}).Where("user_id = ? AND is_read = 0", userID).Count(&count)
+	if result.Error != nil {
+		return 0, fmt.Errorf("NotificationRepository.CountUnread err: %w", result.Error)
+	}
+	return count, nil
+}
+
+// MarkRead 将通知标记为已读，id 为 0 时标记该用户的全部通知
+func (n *NotificationRepository) MarkRead(userID int, id int) error {
+	query := n.DB.Model(&notification_model.Notification{}).Where("user_id = ? AND is_read = 0", userID)
+	if id != 0 {
+		query = query.Where("id = ?", id)
+	}
+	if err := query.Update("is_read", 1).Error; err != nil {
+		return fmt.Errorf("NotificationRepository.MarkRead err: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package notification_service
+
+import (
+	"fmt"
+	"huancuilou/internal/notification/notification_model"
+	"huancuilou/internal/notification/notification_repository"
+	"log"
+	"time"
+)
+
+type NotificationService struct {
+	notificationRepository *notification_repository.NotificationRepository
+}
+
+func NewNotificationService(notificationRepository *notification_repository.NotificationRepository) *NotificationService {
+	return &NotificationService{
+		notificationRepository: notificationRepository,
+	}
+}
+
+// Notify 异步发送通知，发送者与接收者相同时不发送
+func (n *NotificationService) Notify(notification *notification_model.Notification) {
+	if notification.UserID == 0 || notification.UserID == notification.SenderID {
+		return
+	}
+	notification.CreateAt = time.Now()
+	go func() {
+		if err := n.notificationRepository.AddNotification(notification); err != nil {
+			log.Printf("NotificationService.Notify err: %v", err)
+		}
+	}()
+}
+
+func (n *NotificationService) GetNotifications(userID int, page int, size int) (map[string]interface{}, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 20
+	}
+	notifications, total, err := n.notificationRepository.GetNotifications(userID, (page-1)*size, size)
+	if err != nil {
+		return nil, fmt.Errorf("NotificationService.GetNotifications err: %w", err)
+	}
+	return map[string]interface{}{
+		"total":         total,
+		"page":          page,
+		"size":          size,
+		"notifications": notifications,
+	}, nil
+}
+
+func (n *NotificationService) CountUnread(userID int) (int64, error) {
+	count, err := n.notificationRepository.CountUnread(userID)
+	if err != nil {
+		return 0, fmt.Errorf("NotificationService.CountUnread err: %w", err)
+	}
+	return count, nil
+}
+
+func (n *NotificationService) MarkRead(userID int, id int) error {
+	if err := n.notificationRepository.MarkRead(userID, id); err != nil {
+		return fmt.Errorf("NotificationService.MarkRead err: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package upload_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"huancuilou/common/error_handler"
+	"huancuilou/internal/upload/upload_service"
+	"huancuilou/response"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type UploadController struct {
+	UploadService *upload_service.UploadService
+}
+
+func NewUploadController(uploadService *upload_service.UploadService) *UploadController {
+	return &UploadController{
+		UploadService: uploadService,
+	}
+}
+
+func (u *UploadController) UploadAvatar(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.UploadAvatar err: 400:获取上传文件失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	avatarURL, err := u.UploadService.UploadAvatar(userID, file)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.UploadAvatar err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(avatarURL))
+}
+
+func (u *UploadController) UploadAttachment(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.UploadAttachment err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.UploadAttachment err: 400:获取上传文件失败:%w", err))
+		return
+	}
+	userID := c.MustGet("userID").(int)
+
+	attachment, err := u.UploadService.UploadAttachment(articleID, userID, file)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.UploadAttachment err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(attachment))
+}
+
+func (u *UploadController) GetAttachments(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("articleID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.GetAttachments err: 400: 将articleID转换为int失败:%w", err))
+		return
+	}
+
+	attachments, err := u.UploadService.GetAttachments(articleID)
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.GetAttachments err: 500: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(attachments))
+}
+
+func (u *UploadController) DeleteAttachment(c *gin.Context) {
+	attachmentID, err := strconv.Atoi(c.Param("attachmentID"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.DeleteAttachment err: 400: 将attachmentID转换为int失败:%w", err))
+		return
+	}
+
+	if err := u.UploadService.DeleteAttachment(attachmentID); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.DeleteAttachment err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessWithoutData())
+}
+
+// GetFile 下载文件，附件需要携带 GetAttachments 返回的签名参数
+func (u *UploadController) GetFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+
+	reader, mimeType, err := u.UploadService.OpenFile(key, expires, c.Query("sign"))
+	if err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UploadController.GetFile err: %w", err))
+		return
+	}
+	defer reader.Close()
+	c.DataFromReader(http.StatusOK, -1, mimeType, reader, map[string]string{
+		"Content-Disposition":    "inline",
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=3600",
+	})
+}
@@ -0,0 +1,23 @@
+package upload_model
+
+import "time"
+
+// Attachment 文章附件，文件本身保存在对象存储中，这里只记录元数据
+type Attachment struct {
+	ID           int       `json:"id"`
+	ArticleID    int       `json:"articleID"`
+	UserID       int       `json:"userID"`
+	FileName     string    `json:"fileName"`
+	ObjectKey    string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	MimeType     string    `json:"mimeType"`
+	Size         int64     `json:"size"`
+	CreateAt     time.Time `json:"createAt"`
+	// URL、ThumbnailURL 为带签名的临时下载地址，每次查询时重新生成
+	URL          string `json:"url" gorm:"-"`
+	ThumbnailURL string `json:"thumbnailURL" gorm:"-"`
+}
+
+func (Attachment) TableName() string {
+	return "article_attachment"
+}
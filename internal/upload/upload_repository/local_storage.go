@@ -0,0 +1,81 @@
+package upload_repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage 将文件保存在本地文件系统中
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("NewLocalStorage err: %w", err)
+	}
+	if err := os.MkdirAll(absRoot, 0755); err != nil {
+		return nil, fmt.Errorf("NewLocalStorage err: %w", err)
+	}
+	return &LocalStorage{root: absRoot}, nil
+}
+
+// Put 先写入临时文件再重命名，避免读取到写了一半的文件
+func (l *LocalStorage) Put(key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return fmt.Errorf("LocalStorage.Put err: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("LocalStorage.Put err: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("LocalStorage.Put err: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("LocalStorage.Put err: %w", err)
+	}
+	return nil
+}
+
+func (l *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, fmt.Errorf("LocalStorage.Get err: %w", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("LocalStorage.Get err: %w", err)
+	}
+	return file, nil
+}
+
+func (l *LocalStorage) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return fmt.Errorf("LocalStorage.Delete err: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("LocalStorage.Delete err: %w", err)
+	}
+	return nil
+}
+
+// path 将 key 转换为根目录下的文件路径，拒绝跳出根目录的 key
+func (l *LocalStorage) path(key string) (string, error) {
+	path := filepath.Join(l.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, l.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("非法的文件名: %s", key)
+	}
+	return path, nil
+}
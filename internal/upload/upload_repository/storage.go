@@ -0,0 +1,30 @@
+package upload_repository
+
+import (
+	"errors"
+	"fmt"
+	"huancuilou/configs"
+	"io"
+)
+
+// ErrObjectNotFound 要读取的文件在存储中不存在
+var ErrObjectNotFound = errors.New("文件不存在")
+
+// Storage 文件存储接口，key 为以 "/" 分隔的相对路径
+type Storage interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// NewStorage 根据配置创建文件存储
+func NewStorage(config configs.UploadConfig) (Storage, error) {
+	switch config.Storage {
+	case "local":
+		return NewLocalStorage(config.LocalRoot)
+	case "s3":
+		return NewS3Storage(config.S3), nil
+	default:
+		return nil, fmt.Errorf("NewStorage err: 未知的存储方式: %s", config.Storage)
+	}
+}
@@ -0,0 +1,51 @@
+package upload_repository
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"huancuilou/internal/upload/upload_model"
+)
+
+type UploadRepository struct {
+	DB *gorm.DB
+}
+
+func NewUploadRepository(db *gorm.DB) *UploadRepository {
+	return &UploadRepository{
+		DB: db,
+	}
+}
+
+func (u *UploadRepository) AddAttachment(attachment *upload_model.Attachment) error {
+	if err := u.DB.Create(attachment).Error; err != nil {
+		return fmt.Errorf("UploadRepository.AddAttachment err: %w", err)
+	}
+	return nil
+}
+
+func (u *UploadRepository) GetAttachmentByID(id int) (*upload_model.Attachment, error) {
+	var attachment upload_model.Attachment
+	if err := u.DB.First(&attachment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("UploadRepository.GetAttachmentByID err: %w", err)
+	}
+	return &attachment, nil
+}
+
+func (u *UploadRepository) GetAttachmentsByArticleID(articleID int) ([]*upload_model.Attachment, error) {
+	var attachments []*upload_model.Attachment
+	if err := u.DB.Where("article_id = ?", articleID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("UploadRepository.GetAttachmentsByArticleID err: %w", err)
+	}
+	return attachments, nil
+}
+
+func (u *UploadRepository) DeleteAttachment(id int) error {
+	if err := u.DB.Delete(&upload_model.Attachment{}, id).Error; err != nil {
+		return fmt.Errorf("UploadRepository.DeleteAttachment err: %w", err)
+	}
+	return nil
+}
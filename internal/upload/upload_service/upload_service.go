@@ -0,0 +1,244 @@
+package upload_service
+
+import (
+	"errors"
+	"fmt"
+	"huancuilou/common/utils"
+	"huancuilou/configs"
+	"huancuilou/internal/article/article_repository"
+	"huancuilou/internal/upload/upload_model"
+	"huancuilou/internal/upload/upload_repository"
+	"huancuilou/internal/user/user_model"
+	"huancuilou/internal/user/user_repository"
+	"io"
+	"log"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	avatarDir     = "avatar"
+	attachmentDir = "attachment"
+)
+
+type UploadService struct {
+	uploadRepository  *upload_repository.UploadRepository
+	storage           upload_repository.Storage
+	articleRepository *article_repository.ArticleRepository
+	userRepository    *user_repository.UserRepository
+	config            *configs.Config
+}
+
+func NewUploadService(uploadRepository *upload_repository.UploadRepository, storage upload_repository.Storage,
+	articleRepository *article_repository.ArticleRepository, userRepository *user_repository.UserRepository, config *configs.Config) *UploadService {
+	return &UploadService{
+		uploadRepository:  uploadRepository,
+		storage:           storage,
+		articleRepository: articleRepository,
+		userRepository:    userRepository,
+		config:            config,
+	}
+}
+
+// UploadAvatar 上传头像并更新用户的头像地址
+// 头像只保存缩放后重新编码的图片，既限制了尺寸，也去掉了原图中的 EXIF 等元数据
+func (u *UploadService) UploadAvatar(userID int, file *multipart.FileHeader) (string, error) {
+	data, err := readFile(file, u.config.Upload.MaxAvatarSize)
+	if err != nil {
+		return "", fmt.Errorf("UploadService.UploadAvatar err: %w", err)
+	}
+	mimeType := utils.DetectMimeType(data)
+	if !u.config.Upload.AvatarTypes[mimeType] {
+		return "", fmt.Errorf("UploadService.UploadAvatar err: 400:不支持的头像类型: %s", mimeType)
+	}
+	avatar, err := utils.Thumbnail(data, u.config.Upload.ThumbnailSize, u.config.Upload.MaxImagePixels)
+	if err != nil {
+		return "", fmt.Errorf("UploadService.UploadAvatar err: 400:%w", err)
+	}
+
+	user, err := u.userRepository.GetUserByUserID(userID)
+	if err != nil {
+		return "", fmt.Errorf("UploadService.UploadAvatar err: 500: %w", err)
+	}
+	if user == nil {
+		return "", fmt.Errorf("UploadService.UploadAvatar err: 400:用户不存在")
+	}
+
+	key, err := utils.GenerateObjectKey(avatarDir, ".jpg")
+	if err != nil {
+		return "", fmt.Errorf("UploadService.UploadAvatar err: 500: %w", err)
+	}
+	if err := u.storage.Put(key, avatar, "image/jpeg"); err != nil {
+		return "", fmt.Errorf("UploadService.UploadAvatar err: 500: %w", err)
+	}
+
+	avatarURL := u.config.Upload.BaseURL + "/" + key
+	if err := u.userRepository.UpdateUserInfo(u.userRepository.DB, userID, &user_model.User{AvatarUrl: avatarURL}); err != nil {
+		u.deleteObjects(key)
+		return "", fmt.Errorf("UploadService.UploadAvatar err: 500: %w", err)
+	}
+
+	// 旧头像由本服务上传时一并删除，外部地址不做处理
+	if oldKey, ok := strings.CutPrefix(user.AvatarUrl, u.config.Upload.BaseURL+"/"); ok {
+		u.deleteObjects(oldKey)
+	}
+	return avatarURL, nil
+}
+
+// UploadAttachment 为文章上传附件，图片附件同时生成缩略图
+func (u *UploadService) UploadAttachment(articleID int, userID int, file *multipart.FileHeader) (*upload_model.Attachment, error) {
+	article, err := u.articleRepository.GetArticleByID(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("UploadService.UploadAttachment err: 500: %w", err)
+	}
+	if article == nil {
+		return nil, fmt.Errorf("UploadService.UploadAttachment err: 400:文章不存在")
+	}
+
+	data, err := readFile(file, u.config.Upload.MaxAttachmentSize)
+	if err != nil {
+		return nil, fmt.Errorf("UploadService.UploadAttachment err: %w", err)
+	}
+	mimeType := utils.DetectMimeType(data)
+	if !u.config.Upload.AttachmentTypes[mimeType] {
+		return nil, fmt.Errorf("UploadService.UploadAttachment err: 400:不支持的附件类型: %s", mimeType)
+	}
+
+	ext := utils.MimeExtension(mimeType)
+	key, err := utils.GenerateObjectKey(attachmentDir, ext)
+	if err != nil {
+		return nil, fmt.Errorf("UploadService.UploadAttachment err: 500: %w", err)
+	}
+	attachment := &upload_model.Attachment{
+		ArticleID: articleID,
+		UserID:    userID,
+		FileName:  utils.Substring(filepath.Base(file.Filename), 100),
+		ObjectKey: key,
+		MimeType:  mimeType,
+		Size:      int64(len(data)),
+		CreateAt:  time.Now(),
+	}
+
+	if utils.IsImageMime(mimeType) {
+		thumbnail, err := utils.Thumbnail(data, u.config.Upload.ThumbnailSize, u.config.Upload.MaxImagePixels)
+		if err != nil {
+			return nil, fmt.Errorf("UploadService.UploadAttachment err: 400:%w", err)
+		}
+		attachment.ThumbnailKey = strings.TrimSuffix(key, ext) + "_thumb.jpg"
+		if err := u.storage.Put(attachment.ThumbnailKey, thumbnail, "image/jpeg"); err != nil {
+			return nil, fmt.Errorf("UploadService.UploadAttachment err: 500: %w", err)
+		}
+	}
+	if err := u.storage.Put(key, data, mimeType); err != nil {
+		u.deleteObjects(attachment.ThumbnailKey)
+		return nil, fmt.Errorf("UploadService.UploadAttachment err: 500: %w", err)
+	}
+
+	if err := u.uploadRepository.AddAttachment(attachment); err != nil {
+		u.deleteObjects(key, attachment.ThumbnailKey)
+		return nil, fmt.Errorf("UploadService.UploadAttachment err: 500: %w", err)
+	}
+	u.fillURLs(attachment)
+	return attachment, nil
+}
+
+// GetAttachments 获取文章的所有附件，下载地址为带有效期的签名地址
+func (u *UploadService) GetAttachments(articleID int) ([]*upload_model.Attachment, error) {
+	attachments, err := u.uploadRepository.GetAttachmentsByArticleID(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("UploadService.GetAttachments err: %w", err)
+	}
+	for _, attachment := range attachments {
+		u.fillURLs(attachment)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment 删除附件记录及存储中的文件
+func (u *UploadService) DeleteAttachment(id int) error {
+	attachment, err := u.uploadRepository.GetAttachmentByID(id)
+	if err != nil {
+		return fmt.Errorf("UploadService.DeleteAttachment err: 500: %w", err)
+	}
+	if attachment == nil {
+		return fmt.Errorf("UploadService.DeleteAttachment err: 400:附件不存在")
+	}
+	if err := u.uploadRepository.DeleteAttachment(id); err != nil {
+		return fmt.Errorf("UploadService.DeleteAttachment err: 500: %w", err)
+	}
+	u.deleteObjects(attachment.ObjectKey, attachment.ThumbnailKey)
+	return nil
+}
+
+// OpenFile 打开要下载的文件，返回文件内容和 MIME 类型
+// 头像可以公开访问，其余文件需要校验下载地址的签名；路径不规范或含有 .. 的文件名直接拒绝，避免借头像目录绕过签名
+func (u *UploadService) OpenFile(key string, expires int64, sign string) (io.ReadCloser, string, error) {
+	if path.Clean(key) != key || path.IsAbs(key) || slices.Contains(strings.Split(key, "/"), "..") {
+		return nil, "", fmt.Errorf("UploadService.OpenFile err: 400:文件路径无效")
+	}
+	if !strings.HasPrefix(key, avatarDir+"/") && !utils.VerifyObjectSign(u.config.Upload.SignSecret, key, expires, sign) {
+		return nil, "", fmt.Errorf("UploadService.OpenFile err: 403:下载地址无效或已过期")
+	}
+	reader, err := u.storage.Get(key)
+	if err != nil {
+		if errors.Is(err, upload_repository.ErrObjectNotFound) {
+			return nil, "", fmt.Errorf("UploadService.OpenFile err: 400:%w", err)
+		}
+		return nil, "", fmt.Errorf("UploadService.OpenFile err: 500: %w", err)
+	}
+	return reader, utils.ExtensionMime(path.Ext(key)), nil
+}
+
+// fillURLs 为附件生成带签名的下载地址
+func (u *UploadService) fillURLs(attachment *upload_model.Attachment) {
+	attachment.URL = u.signedURL(attachment.ObjectKey)
+	if attachment.ThumbnailKey != "" {
+		attachment.ThumbnailURL = u.signedURL(attachment.ThumbnailKey)
+	}
+}
+
+func (u *UploadService) signedURL(key string) string {
+	expires := time.Now().Add(u.config.Upload.SignExpire).Unix()
+	sign := utils.SignObjectKey(u.config.Upload.SignSecret, key, expires)
+	return fmt.Sprintf("%s/%s?expires=%d&sign=%s", u.config.Upload.BaseURL, key, expires, sign)
+}
+
+// deleteObjects 删除存储中的文件，失败时只记录日志，残留的文件不影响业务
+func (u *UploadService) deleteObjects(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := u.storage.Delete(key); err != nil {
+			log.Printf("删除文件%s失败: %v", key, err)
+		}
+	}
+}
+
+// readFile 读取上传的文件，超过大小上限时返回错误
+// 客户端上报的大小不可信，读取时再限制一次
+func readFile(file *multipart.FileHeader, maxSize int64) ([]byte, error) {
+	if file.Size > maxSize {
+		return nil, fmt.Errorf("400:文件大小超过上限%dKB", maxSize>>10)
+	}
+	f, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("500: 打开上传文件失败: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("500: 读取上传文件失败: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("400:文件大小超过上限%dKB", maxSize>>10)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("400:文件为空")
+	}
+	return data, nil
+}
@@ -335,3 +335,18 @@ func (uc *UserController) AddChooseItemConsumer(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, response.SuccessWithoutData())
 }
+
+// Audit 发起一次 Redis 与 MySQL 的一致性检查，请求中指定 repairFrom 时同时修复
+// 检查由调度器异步运行，不一致之处写入日志，运行结果可在任务的运行记录中查看
+func (uc *UserController) Audit(c *gin.Context) {
+	var options user_model.AuditOptions
+	if err := c.ShouldBindJSON(&options); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UserController.Audit err: 400: 将json数据绑定到结构体失败:%w", err))
+		return
+	}
+	if err := uc.userService.ScheduleAudit(&options); err != nil {
+		error_handler.HandleUserError(c, fmt.Errorf("UserController.Audit err: %w", err))
+		return
+	}
+	c.JSON(http.StatusOK, response.Success(map[string]string{"job": user_service.AuditManualJob}))
+}
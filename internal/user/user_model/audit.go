@@ -0,0 +1,51 @@
+package user_model
+
+import "time"
+
+// 一致性检查的对象
+const (
+	AuditItemRemain = "item_remain" // community_item.remain 与 hcl:user:item:<id>:info 中的 remain
+	AuditUserLikes  = "user_likes"  // user.likes 与 hcl:user:likes 中的分数
+	AuditFollows    = "follows"     // user_follow 与 hcl:user:follows:<id>
+	AuditFans       = "fans"        // user_follow 与 hcl:user:fans:<id>
+)
+
+// AuditTargets 全部检查对象，按检查顺序排列
+var AuditTargets = []string{AuditItemRemain, AuditUserLikes, AuditFollows, AuditFans}
+
+// 修复时作为准确数据的一方
+const (
+	AuditRepairNone  = ""      // 只报告不修复
+	AuditRepairMySQL = "mysql" // 按 MySQL 修复 Redis
+	AuditRepairRedis = "redis" // 按 Redis 修复 MySQL
+)
+
+// AuditOptions 一致性检查的参数
+type AuditOptions struct {
+	Targets    []string `json:"targets"`    // 为空时检查全部对象
+	SampleSize int      `json:"sampleSize"` // 每类对象随机抽样检查的个数，0 表示全量扫描
+	RepairFrom string   `json:"repairFrom"` // 修复时作为准确数据的一方，为空时只报告
+}
+
+// AuditMismatch 一处不一致，MySQL 和 Redis 为两边的值，不存在时为空字符串
+// 关注和粉丝集合的 MySQL、Redis 为两边的集合大小，Missing、Extra 为 Redis 中缺少和多出的用户
+type AuditMismatch struct {
+	Target   string `json:"target"`
+	ID       int    `json:"id"`
+	Key      string `json:"key"`
+	MySQL    string `json:"mysql"`
+	Redis    string `json:"redis"`
+	Missing  []int  `json:"missing,omitempty"`
+	Extra    []int  `json:"extra,omitempty"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired"`
+}
+
+// AuditReport 一致性检查的结果
+type AuditReport struct {
+	Options    AuditOptions     `json:"options"`
+	Checked    map[string]int   `json:"checked"` // 每类对象检查的个数
+	Mismatches []*AuditMismatch `json:"mismatches"`
+	StartAt    time.Time        `json:"startAt"`
+	EndAt      time.Time        `json:"endAt"`
+}
@@ -2,6 +2,7 @@ package user_repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
 	"huancuilou/internal/user/user_model"
@@ -269,3 +270,215 @@ func (u *UserCacheRepository) ChooseItem(userID int, itemID int) error {
 		return fmt.Errorf("未知返回值: %d", result)
 	}
 }
+
+// GetItemRemains 批量获取物品缓存中的库存，缓存中不存在的物品不在结果中
+func (u *UserCacheRepository) GetItemRemains(ids []int) (map[int]int, error) {
+	ctx := context.Background()
+	pipe := u.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGet(ctx, fmt.Sprintf("%s:item:%d:info", UserCachePrefix, id), "remain")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("UserCacheRepository.GetItemRemains err:%w", err)
+	}
+	remains := make(map[int]int, len(ids))
+	for i, cmd := range cmds {
+		remain, err := cmd.Int()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("UserCacheRepository.GetItemRemains err:%w", err)
+		}
+		remains[ids[i]] = remain
+	}
+	return remains, nil
+}
+
+// CountItemUsers 批量统计缓存中每个物品已被选择的人数
+func (u *UserCacheRepository) CountItemUsers(ids []int) (map[int]int, error) {
+	ctx := context.Background()
+	pipe := u.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.SCard(ctx, fmt.Sprintf("%s:item:%d:users", UserCachePrefix, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("UserCacheRepository.CountItemUsers err:%w", err)
+	}
+	counts := make(map[int]int, len(ids))
+	for i, cmd := range cmds {
+		counts[ids[i]] = int(cmd.Val())
+	}
+	return counts, nil
+}
+
+// GetItemIDs 扫描缓存中所有物品的 ID
+func (u *UserCacheRepository) GetItemIDs() ([]int, error) {
+	ctx := context.Background()
+	var ids []int
+	iter := u.client.Scan(ctx, 0, UserCachePrefix+":item:*:info", 0).Iterator()
+	for iter.Next(ctx) {
+		var id int
+		if _, err := fmt.Sscanf(iter.Val(), UserCachePrefix+":item:%d:info", &id); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("UserCacheRepository.GetItemIDs err:%w", err)
+	}
+	return ids, nil
+}
+
+// RepairItem 以 MySQL 中的物品信息重写物品缓存，库存不取 MySQL 中的值，而是由容量减去已选择的人数得出
+// 已选择的人数取缓存中用户集合的大小与 MySQL 中已选择人数 dbUsers 的较大者，秒杀消息尚未消费时也不会超卖
+func (u *UserCacheRepository) RepairItem(item *user_model.CommunityItem, dbUsers int) (int, error) {
+	ctx := context.Background()
+	script := `
+    -- KEYS[1]: 用户集合键
+    -- KEYS[2]: 物品信息键
+    -- ARGV[1]: MySQL 中已选择的人数，ARGV[2] 起为物品信息的字段和值
+    local chosen = math.max(redis.call('SCARD', KEYS[1]), tonumber(ARGV[1]))
+    redis.call('HSET', KEYS[2], unpack(ARGV, 2))
+    local remain = math.max(tonumber(redis.call('HGET', KEYS[2], 'capacity')) - chosen, 0)
+    redis.call('HSET', KEYS[2], 'remain', remain)
+    return remain
+    `
+	usersKey := fmt.Sprintf("%s:item:%d:users", UserCachePrefix, item.ID)
+	itemKey := fmt.Sprintf("%s:item:%d:info", UserCachePrefix, item.ID)
+	remain, err := u.client.Eval(ctx, script, []string{usersKey, itemKey}, dbUsers, "id", item.ID, "name", item.Name,
+		"price", item.Price, "capacity", item.Capacity, "begin", item.Begin).Int()
+	if err != nil {
+		return 0, fmt.Errorf("UserCacheRepository.RepairItem err:%w", err)
+	}
+	return remain, nil
+}
+
+// DeleteItem 删除物品的缓存和已选择的用户集合
+func (u *UserCacheRepository) DeleteItem(id int) error {
+	ctx := context.Background()
+	if err := u.client.Del(ctx, fmt.Sprintf("%s:item:%d:info", UserCachePrefix, id),
+		fmt.Sprintf("%s:item:%d:users", UserCachePrefix, id)).Err(); err != nil {
+		return fmt.Errorf("UserCacheRepository.DeleteItem err:%w", err)
+	}
+	return nil
+}
+
+// GetLikeScores 批量获取用户在获赞有序集合中的分数，不在集合中的用户不在结果中
+func (u *UserCacheRepository) GetLikeScores(ids []int) (map[int]int, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:likes", UserCachePrefix)
+	pipe := u.client.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.ZScore(ctx, key, strconv.Itoa(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("UserCacheRepository.GetLikeScores err:%w", err)
+	}
+	scores := make(map[int]int, len(ids))
+	for i, cmd := range cmds {
+		score, err := cmd.Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("UserCacheRepository.GetLikeScores err:%w", err)
+		}
+		scores[ids[i]] = int(score)
+	}
+	return scores, nil
+}
+
+// GetLikeUserIDs 扫描获赞有序集合中的所有用户 ID
+func (u *UserCacheRepository) GetLikeUserIDs() ([]int, error) {
+	ctx := context.Background()
+	var ids []int
+	iter := u.client.ZScan(ctx, fmt.Sprintf("%s:likes", UserCachePrefix), 0, "", 0).Iterator()
+	for i := 0; iter.Next(ctx); i++ {
+		// ZSCAN 交替返回成员和分数
+		if i%2 == 1 {
+			continue
+		}
+		id, err := strconv.Atoi(iter.Val())
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("UserCacheRepository.GetLikeUserIDs err:%w", err)
+	}
+	return ids, nil
+}
+
+func (u *UserCacheRepository) SetLikes(id int, score int) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:likes", UserCachePrefix)
+	if err := u.client.ZAdd(ctx, key, redis.Z{Score: float64(score), Member: strconv.Itoa(id)}).Err(); err != nil {
+		return fmt.Errorf("UserCacheRepository.SetLikes err:%w", err)
+	}
+	return nil
+}
+
+func (u *UserCacheRepository) RemoveLikes(id int) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:likes", UserCachePrefix)
+	if err := u.client.ZRem(ctx, key, strconv.Itoa(id)).Err(); err != nil {
+		return fmt.Errorf("UserCacheRepository.RemoveLikes err:%w", err)
+	}
+	return nil
+}
+
+// GetFollowSets 批量获取用户的关注集合或粉丝集合，kind 为 follows 或 fans
+func (u *UserCacheRepository) GetFollowSets(kind string, userIDs []int) (map[int][]int, error) {
+	ctx := context.Background()
+	pipe := u.client.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(userIDs))
+	for i, userID := range userIDs {
+		cmds[i] = pipe.SMembers(ctx, fmt.Sprintf("%s:%s:%d", UserCachePrefix, kind, userID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("UserCacheRepository.GetFollowSets err:%w", err)
+	}
+	sets := make(map[int][]int, len(userIDs))
+	for i, cmd := range cmds {
+		for _, member := range cmd.Val() {
+			id, err := strconv.Atoi(member)
+			if err != nil {
+				return nil, fmt.Errorf("UserCacheRepository.GetFollowSets err:%w", err)
+			}
+			sets[userIDs[i]] = append(sets[userIDs[i]], id)
+		}
+	}
+	return sets, nil
+}
+
+// CountFollowSet 获取用户的关注集合或粉丝集合的大小，kind 为 follows 或 fans
+func (u *UserCacheRepository) CountFollowSet(kind string, userID int) (int, error) {
+	ctx := context.Background()
+	count, err := u.client.SCard(ctx, fmt.Sprintf("%s:%s:%d", UserCachePrefix, kind, userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("UserCacheRepository.CountFollowSet err:%w", err)
+	}
+	return int(count), nil
+}
+
+// UpdateFollowSet 向用户的关注集合或粉丝集合中加入 added、移除 removed
+func (u *UserCacheRepository) UpdateFollowSet(kind string, userID int, added []int, removed []int) error {
+	ctx := context.Background()
+	key := fmt.Sprintf("%s:%s:%d", UserCachePrefix, kind, userID)
+	pipe := u.client.TxPipeline()
+	for _, id := range added {
+		pipe.SAdd(ctx, key, id)
+	}
+	for _, id := range removed {
+		pipe.SRem(ctx, key, id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("UserCacheRepository.UpdateFollowSet err:%w", err)
+	}
+	return nil
+}
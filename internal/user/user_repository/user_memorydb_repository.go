@@ -3,73 +3,66 @@ package user_repository
 import (
 	"errors"
 	"huancuilou/internal/user/user_model"
+	"sync"
 	"time"
 )
 
+// codeEntry 内存中保存的验证码及其过期时间
+type codeEntry struct {
+	code     string
+	expireAt time.Time
+}
+
+// UserMemoryDBRepository 在内存中保存验证码
+// 过期的验证码在校验时视为不存在，由定时任务调用 RemoveExpiredCodes 统一清理
 type UserMemoryDBRepository struct {
-	codeMap  map[string]*user_model.UserCode
-	timerMap map[string]*time.Timer
-	//locks    sync.Map
+	mu      sync.Mutex
+	codeMap map[string]*codeEntry
 }
 
 func NewUserMemoryDBRepository() *UserMemoryDBRepository {
 	return &UserMemoryDBRepository{
-		codeMap:  make(map[string]*user_model.UserCode),
-		timerMap: make(map[string]*time.Timer),
+		codeMap: make(map[string]*codeEntry),
 	}
 }
 
-//// 获取行锁 感觉没啥必要 浪费性能
-//func (um *UserMemoryDBRepository) getLock(phoneNumber string) *sync.Mutex {
-//	lock, loaded := um.locks.Load(phoneNumber)
-//	if !loaded {
-//		newLock := &sync.Mutex{}
-//		lock, _ = um.locks.LoadOrStore(phoneNumber, newLock)
-//	}
-//	return lock.(*sync.Mutex)
-//}
-
+// AddCode 插入或更新手机号的验证码，验证码在 interval 后过期
 func (um *UserMemoryDBRepository) AddCode(userCode *user_model.UserCode, interval time.Duration) error {
-	//lock := um.getLock(userCode.PhoneNumber)
-	//lock.Lock()
-	//defer lock.Unlock()
-
-	// 检查是否已经存在该手机号的定时器，如果存在则取消它
-	if timer, exists := um.timerMap[userCode.PhoneNumber]; exists {
-		if timer.Stop() {
-			delete(um.timerMap, userCode.PhoneNumber)
-		}
+	um.mu.Lock()
+	defer um.mu.Unlock()
+	um.codeMap[userCode.PhoneNumber] = &codeEntry{
+		code:     userCode.Code,
+		expireAt: time.Now().Add(interval),
 	}
-
-	// 更新 codeMap 中的验证码
-	um.codeMap[userCode.PhoneNumber] = userCode
-
-	// 创建一个新的定时器
-	timer := time.AfterFunc(interval, func() {
-		//lock := um.getLock(userCode.PhoneNumber)
-		//lock.Lock()
-		//defer lock.Unlock()
-		delete(um.codeMap, userCode.PhoneNumber)
-		delete(um.timerMap, userCode.PhoneNumber)
-	})
-
-	// 将新的定时器保存到 timerMap 中
-	um.timerMap[userCode.PhoneNumber] = timer
-
 	return nil
 }
 
 func (um *UserMemoryDBRepository) ValidateCode(code string, phoneNumber string) error {
-	//lock := um.getLock(phoneNumber)
-	//lock.Lock()
-	//defer lock.Unlock()
-	if userCode, exists := um.codeMap[phoneNumber]; exists {
-		if userCode.Code == code {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+	entry, exists := um.codeMap[phoneNumber]
+	if !exists || time.Now().After(entry.expireAt) {
+		delete(um.codeMap, phoneNumber)
+		return errors.New("验证码不存在或已过期")
+	}
+	if entry.code != code {
+		return errors.New("验证码错误")
+	}
+	delete(um.codeMap, phoneNumber)
+	return nil
+}
+
+// RemoveExpiredCodes 删除所有已过期的验证码，返回删除的数量
+func (um *UserMemoryDBRepository) RemoveExpiredCodes() int {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+	now := time.Now()
+	removed := 0
+	for phoneNumber, entry := range um.codeMap {
+		if now.After(entry.expireAt) {
 			delete(um.codeMap, phoneNumber)
-			delete(um.timerMap, phoneNumber)
-			return nil
+			removed++
 		}
-		return errors.New("验证码错误")
 	}
-	return errors.New("验证码不存在或已过期")
+	return removed
 }
@@ -58,8 +58,8 @@ func (ur *UserRepository) AddAdminByPhoneNumber(phoneNumber string) error {
 	return nil
 }
 
-func (ur *UserRepository) UpdateUserInfo(id int, user *user_model.User) error {
-	result := ur.DB.Model(&user_model.User{}).Where("id = ?", id).Updates(user)
+func (ur *UserRepository) UpdateUserInfo(tx *gorm.DB, id int, user *user_model.User) error {
+	result := tx.Model(&user_model.User{}).Where("id = ?", id).Updates(user)
 	if result.Error != nil {
 		return fmt.Errorf("UserRepository.UpdateUserInfo err:%w", result.Error)
 	}
@@ -153,6 +153,15 @@ func (ur *UserRepository) GetItemByID(id int) (*user_model.CommunityItem, error)
 	return item, nil
 }
 
+// HasChosenItem 判断用户是否已经选择过物品
+func (ur *UserRepository) HasChosenItem(tx *gorm.DB, userID int, itemID int) (bool, error) {
+	var count int64
+	if err := tx.Model(&user_model.UserItem{}).Where("user_id = ? AND item_id = ?", userID, itemID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("UserRepository.HasChosenItem err:%w", err)
+	}
+	return count > 0, nil
+}
+
 func (ur *UserRepository) ChooseItem(tx *gorm.DB, userItem *user_model.UserItem) error {
 	result := tx.Create(userItem)
 	if result.Error != nil {
@@ -168,3 +177,104 @@ func (ur *UserRepository) UpdateItemInfo(tx *gorm.DB, id int) error {
 	}
 	return nil
 }
+
+// GetItemsByIDs 按 ID 批量获取物品，不存在的物品不在结果中
+func (ur *UserRepository) GetItemsByIDs(ids []int) (map[int]*user_model.CommunityItem, error) {
+	var items []*user_model.CommunityItem
+	if err := ur.DB.Where("id IN ?", ids).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("UserRepository.GetItemsByIDs err:%w", err)
+	}
+	itemMap := make(map[int]*user_model.CommunityItem, len(items))
+	for _, item := range items {
+		itemMap[item.ID] = item
+	}
+	return itemMap, nil
+}
+
+// CountItemUsers 统计每个物品已被选择的次数
+func (ur *UserRepository) CountItemUsers(itemIDs []int) (map[int]int, error) {
+	var rows []struct {
+		ItemID int
+		Count  int
+	}
+	if err := ur.DB.Model(&user_model.UserItem{}).Select("item_id, COUNT(*) AS count").
+		Where("item_id IN ?", itemIDs).Group("item_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("UserRepository.CountItemUsers err:%w", err)
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.ItemID] = row.Count
+	}
+	return counts, nil
+}
+
+func (ur *UserRepository) SetItemRemain(id int, remain int) error {
+	if err := ur.DB.Model(&user_model.CommunityItem{}).Where("id = ?", id).Update("remain", remain).Error; err != nil {
+		return fmt.Errorf("UserRepository.SetItemRemain err:%w", err)
+	}
+	return nil
+}
+
+// GetUserIDsAfter 按 ID 升序获取 afterID 之后的最多 limit 个用户 ID
+func (ur *UserRepository) GetUserIDsAfter(afterID int, limit int) ([]int, error) {
+	var ids []int
+	if err := ur.DB.Model(&user_model.User{}).Where("id > ?", afterID).Order("id ASC").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("UserRepository.GetUserIDsAfter err:%w", err)
+	}
+	return ids, nil
+}
+
+// SampleUserIDs 随机获取最多 n 个用户 ID
+func (ur *UserRepository) SampleUserIDs(n int) ([]int, error) {
+	var ids []int
+	if err := ur.DB.Model(&user_model.User{}).Order("RAND()").Limit(n).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("UserRepository.SampleUserIDs err:%w", err)
+	}
+	return ids, nil
+}
+
+// GetLikesByUserIDs 批量获取用户的获赞数，不存在的用户不在结果中
+func (ur *UserRepository) GetLikesByUserIDs(ids []int) (map[int]int, error) {
+	var users []*user_model.User
+	if err := ur.DB.Select("id", "likes").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("UserRepository.GetLikesByUserIDs err:%w", err)
+	}
+	likes := make(map[int]int, len(users))
+	for _, user := range users {
+		likes[user.ID] = user.Likes
+	}
+	return likes, nil
+}
+
+func (ur *UserRepository) SetLikes(userID int, likes int) error {
+	if err := ur.DB.Model(&user_model.User{}).Where("id = ?", userID).Update("likes", likes).Error; err != nil {
+		return fmt.Errorf("UserRepository.SetLikes err:%w", err)
+	}
+	return nil
+}
+
+// GetFollowIDs 批量获取用户关注的用户 ID
+func (ur *UserRepository) GetFollowIDs(userIDs []int) (map[int][]int, error) {
+	var follows []*user_model.UserFollow
+	if err := ur.DB.Where("user_id IN ?", userIDs).Find(&follows).Error; err != nil {
+		return nil, fmt.Errorf("UserRepository.GetFollowIDs err:%w", err)
+	}
+	followIDs := make(map[int][]int, len(userIDs))
+	for _, follow := range follows {
+		followIDs[follow.UserID] = append(followIDs[follow.UserID], follow.FollowID)
+	}
+	return followIDs, nil
+}
+
+// GetFanIDs 批量获取关注了这些用户的用户 ID
+func (ur *UserRepository) GetFanIDs(userIDs []int) (map[int][]int, error) {
+	var follows []*user_model.UserFollow
+	if err := ur.DB.Where("follow_id IN ?", userIDs).Find(&follows).Error; err != nil {
+		return nil, fmt.Errorf("UserRepository.GetFanIDs err:%w", err)
+	}
+	fanIDs := make(map[int][]int, len(userIDs))
+	for _, follow := range follows {
+		fanIDs[follow.FollowID] = append(fanIDs[follow.FollowID], follow.UserID)
+	}
+	return fanIDs, nil
+}
@@ -0,0 +1,447 @@
+package user_service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"huancuilou/common/utils"
+	"huancuilou/internal/job/job_service"
+	"huancuilou/internal/user/user_model"
+	"log"
+	"math/rand"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// AuditJob 缓存一致性检查任务的名称，AuditManualJob 为管理员手动发起的一次性检查任务，任务参数为 JSON 格式的 AuditOptions
+const (
+	AuditJob       = "user_cache_audit"
+	AuditManualJob = "user_cache_audit_manual"
+)
+
+// auditCheckFunc 检查一批对象，返回其中不一致的对象
+type auditCheckFunc func(ids []int) ([]*user_model.AuditMismatch, error)
+
+// Audit 比较 Redis 与 MySQL 中的物品库存、用户获赞数、关注和粉丝集合，报告不一致之处，并按 RepairFrom 修复
+// 发现不一致的对象会在 RecheckDelay 后重新检查一次，两次都不一致才报告，避免把检查期间正在进行的写入
+// （如秒杀消息还没有被消费）当作不一致
+func (us *UserService) Audit(ctx context.Context, options *user_model.AuditOptions) (*user_model.AuditReport, error) {
+	if err := validateAuditOptions(options); err != nil {
+		return nil, fmt.Errorf("UserService.Audit err: %w", err)
+	}
+
+	report := &user_model.AuditReport{
+		Options:    *options,
+		Checked:    make(map[string]int),
+		Mismatches: []*user_model.AuditMismatch{},
+		StartAt:    time.Now(),
+	}
+	// 按 AuditTargets 的顺序检查，以 Redis 为准时先由关注集合修复 user_follow，再检查粉丝集合
+	for _, target := range user_model.AuditTargets {
+		if len(options.Targets) > 0 && !slices.Contains(options.Targets, target) {
+			continue
+		}
+		var err error
+		switch target {
+		case user_model.AuditItemRemain:
+			err = us.auditItems(ctx, report)
+		case user_model.AuditUserLikes:
+			err = us.auditLikes(ctx, report)
+		case user_model.AuditFollows, user_model.AuditFans:
+			err = us.auditFollowSets(ctx, report, target)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("UserService.Audit err: 500: %w", err)
+		}
+	}
+	report.EndAt = time.Now()
+	return report, nil
+}
+
+func validateAuditOptions(options *user_model.AuditOptions) error {
+	if options.SampleSize < 0 {
+		return fmt.Errorf("400: 抽样个数不能小于 0")
+	}
+	if options.RepairFrom != user_model.AuditRepairNone && options.RepairFrom != user_model.AuditRepairMySQL &&
+		options.RepairFrom != user_model.AuditRepairRedis {
+		return fmt.Errorf("400: 修复依据只能是 mysql 或 redis")
+	}
+	for _, target := range options.Targets {
+		if !slices.Contains(user_model.AuditTargets, target) {
+			return fmt.Errorf("400: 未知的检查对象 %s", target)
+		}
+	}
+	return nil
+}
+
+// ScheduleAudit 校验参数后设置立即运行的一次性检查任务，由调度器在持有租约的实例上运行，结果写入日志和任务的运行记录
+// 全量扫描耗时较长，不在请求中同步运行
+func (us *UserService) ScheduleAudit(options *user_model.AuditOptions) error {
+	if err := validateAuditOptions(options); err != nil {
+		return fmt.Errorf("UserService.ScheduleAudit err: %w", err)
+	}
+	payload, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("UserService.ScheduleAudit err: 500: %w", err)
+	}
+	if err := us.jobService.ScheduleOnce(AuditManualJob, time.Now(), string(payload), 0, 0); err != nil {
+		return fmt.Errorf("UserService.ScheduleAudit err: 400: %w", err)
+	}
+	return nil
+}
+
+// RunAuditJob 调度器中的缓存一致性检查任务，任务参数为空时按配置抽样检查，结果写入日志
+func (us *UserService) RunAuditJob(ctx context.Context, jc *job_service.JobContext) error {
+	options := &user_model.AuditOptions{
+		SampleSize: us.config.Audit.SampleSize,
+		RepairFrom: us.config.Audit.RepairFrom,
+	}
+	if jc.Job.Payload != "" {
+		options = &user_model.AuditOptions{}
+		if err := json.Unmarshal([]byte(jc.Job.Payload), options); err != nil {
+			return fmt.Errorf("UserService.RunAuditJob err: 解析任务参数失败:%w", err)
+		}
+	}
+	report, err := us.Audit(ctx, options)
+	if err != nil {
+		return fmt.Errorf("UserService.RunAuditJob err:%w", err)
+	}
+	for _, mismatch := range report.Mismatches {
+		log.Printf("缓存不一致: 对象=%s, ID=%d, MySQL=%q, Redis=%q, %s, 已修复=%t",
+			mismatch.Target, mismatch.ID, mismatch.MySQL, mismatch.Redis, mismatch.Detail, mismatch.Repaired)
+	}
+	log.Printf("缓存一致性检查完成: 检查%v, 不一致%d处", report.Checked, len(report.Mismatches))
+	return nil
+}
+
+// auditItems 检查物品库存，全量扫描时还会检查只存在于 Redis 中的物品
+func (us *UserService) auditItems(ctx context.Context, report *user_model.AuditReport) error {
+	items, err := us.userRepository.GetAllItems()
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if report.Options.SampleSize > 0 {
+		rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		ids = ids[:min(len(ids), report.Options.SampleSize)]
+	} else {
+		cachedIDs, err := us.userCacheRepository.GetItemIDs()
+		if err != nil {
+			return err
+		}
+		for _, id := range cachedIDs {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return us.auditBatch(ctx, report, user_model.AuditItemRemain, ids, us.checkItems, us.repairItem)
+}
+
+func (us *UserService) checkItems(ids []int) ([]*user_model.AuditMismatch, error) {
+	items, err := us.userRepository.GetItemsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	dbUsers, err := us.userRepository.CountItemUsers(ids)
+	if err != nil {
+		return nil, err
+	}
+	remains, err := us.userCacheRepository.GetItemRemains(ids)
+	if err != nil {
+		return nil, err
+	}
+	cacheUsers, err := us.userCacheRepository.CountItemUsers(ids)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []*user_model.AuditMismatch
+	for _, id := range ids {
+		item, inDB := items[id]
+		remain, inCache := remains[id]
+		if inDB && inCache && item.Remain == remain {
+			continue
+		}
+		mismatch := &user_model.AuditMismatch{
+			Target: user_model.AuditItemRemain,
+			ID:     id,
+			Key:    fmt.Sprintf("hcl:user:item:%d:info", id),
+			Detail: fmt.Sprintf("MySQL 中已选择%d人, Redis 中已选择%d人", dbUsers[id], cacheUsers[id]),
+		}
+		if inDB {
+			mismatch.MySQL = strconv.Itoa(item.Remain)
+		}
+		if inCache {
+			mismatch.Redis = strconv.Itoa(remain)
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches, nil
+}
+
+// repairItem 以 MySQL 为准时重写物品缓存，物品不存在时删除缓存；以 Redis 为准时只修改库存
+// 以 MySQL 为准时库存由容量减去已选择的人数得出，而不是直接使用 MySQL 中的库存，
+// 秒杀进行中 MySQL 的库存落后于已被抢占的名额，直接覆盖会导致超卖
+func (us *UserService) repairItem(mismatch *user_model.AuditMismatch, from string) error {
+	if from == user_model.AuditRepairRedis {
+		if mismatch.MySQL == "" || mismatch.Redis == "" {
+			return fmt.Errorf("一方没有该物品，无法以 Redis 为准修复")
+		}
+		remain, err := strconv.Atoi(mismatch.Redis)
+		if err != nil {
+			return err
+		}
+		return us.userRepository.SetItemRemain(mismatch.ID, remain)
+	}
+	if mismatch.MySQL == "" {
+		return us.userCacheRepository.DeleteItem(mismatch.ID)
+	}
+	item, err := us.userRepository.GetItemByID(mismatch.ID)
+	if err != nil {
+		return err
+	}
+	dbUsers, err := us.userRepository.CountItemUsers([]int{mismatch.ID})
+	if err != nil {
+		return err
+	}
+	remain, err := us.userCacheRepository.RepairItem(item, dbUsers[mismatch.ID])
+	if err != nil {
+		return err
+	}
+	mismatch.Detail += fmt.Sprintf("; 修复后的库存为%d", remain)
+	return nil
+}
+
+// auditLikes 检查用户获赞数，全量扫描时还会检查只存在于 Redis 中的用户
+func (us *UserService) auditLikes(ctx context.Context, report *user_model.AuditReport) error {
+	seen := make(map[int]bool)
+	err := us.auditUserBatches(ctx, report.Options.SampleSize, func(ids []int) error {
+		for _, id := range ids {
+			seen[id] = true
+		}
+		return us.auditBatch(ctx, report, user_model.AuditUserLikes, ids, us.checkLikes, us.repairLikes)
+	})
+	if err != nil || report.Options.SampleSize > 0 {
+		return err
+	}
+	cachedIDs, err := us.userCacheRepository.GetLikeUserIDs()
+	if err != nil {
+		return err
+	}
+	var orphanIDs []int
+	for _, id := range cachedIDs {
+		if !seen[id] {
+			orphanIDs = append(orphanIDs, id)
+		}
+	}
+	return us.auditBatch(ctx, report, user_model.AuditUserLikes, orphanIDs, us.checkLikes, us.repairLikes)
+}
+
+func (us *UserService) checkLikes(ids []int) ([]*user_model.AuditMismatch, error) {
+	likes, err := us.userRepository.GetLikesByUserIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	scores, err := us.userCacheRepository.GetLikeScores(ids)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []*user_model.AuditMismatch
+	for _, id := range ids {
+		like, inDB := likes[id]
+		score, inCache := scores[id]
+		if inDB && inCache && like == score {
+			continue
+		}
+		mismatch := &user_model.AuditMismatch{
+			Target: user_model.AuditUserLikes,
+			ID:     id,
+			Key:    "hcl:user:likes",
+		}
+		if inDB {
+			mismatch.MySQL = strconv.Itoa(like)
+		} else {
+			mismatch.Detail = "MySQL 中没有该用户"
+		}
+		if inCache {
+			mismatch.Redis = strconv.Itoa(score)
+		} else {
+			mismatch.Detail = "获赞有序集合中没有该用户"
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches, nil
+}
+
+// repairLikes 以 MySQL 为准时重写有序集合中的分数，用户不存在时移出集合；以 Redis 为准时只修改两边都有的用户
+func (us *UserService) repairLikes(mismatch *user_model.AuditMismatch, from string) error {
+	if from == user_model.AuditRepairRedis {
+		if mismatch.MySQL == "" || mismatch.Redis == "" {
+			return fmt.Errorf("一方没有该用户，无法以 Redis 为准修复")
+		}
+		likes, err := strconv.Atoi(mismatch.Redis)
+		if err != nil {
+			return err
+		}
+		return us.userRepository.SetLikes(mismatch.ID, likes)
+	}
+	if mismatch.MySQL == "" {
+		return us.userCacheRepository.RemoveLikes(mismatch.ID)
+	}
+	likes, err := strconv.Atoi(mismatch.MySQL)
+	if err != nil {
+		return err
+	}
+	return us.userCacheRepository.SetLikes(mismatch.ID, likes)
+}
+
+// auditFollowSets 检查 MySQL 中存在的用户的关注集合或粉丝集合，kind 为 follows 或 fans
+func (us *UserService) auditFollowSets(ctx context.Context, report *user_model.AuditReport, kind string) error {
+	check := func(ids []int) ([]*user_model.AuditMismatch, error) {
+		return us.checkFollowSets(kind, ids)
+	}
+	return us.auditUserBatches(ctx, report.Options.SampleSize, func(ids []int) error {
+		return us.auditBatch(ctx, report, kind, ids, check, us.repairFollowSet)
+	})
+}
+
+func (us *UserService) checkFollowSets(kind string, ids []int) ([]*user_model.AuditMismatch, error) {
+	var dbSets map[int][]int
+	var err error
+	if kind == user_model.AuditFollows {
+		dbSets, err = us.userRepository.GetFollowIDs(ids)
+	} else {
+		dbSets, err = us.userRepository.GetFanIDs(ids)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cacheSets, err := us.userCacheRepository.GetFollowSets(kind, ids)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []*user_model.AuditMismatch
+	for _, id := range ids {
+		missing := utils.Difference(dbSets[id], cacheSets[id])
+		extra := utils.Difference(cacheSets[id], dbSets[id])
+		if len(missing) == 0 && len(extra) == 0 {
+			continue
+		}
+		mismatches = append(mismatches, &user_model.AuditMismatch{
+			Target:  kind,
+			ID:      id,
+			Key:     fmt.Sprintf("hcl:user:%s:%d", kind, id),
+			MySQL:   strconv.Itoa(len(dbSets[id])),
+			Redis:   strconv.Itoa(len(cacheSets[id])),
+			Missing: missing,
+			Extra:   extra,
+			Detail:  fmt.Sprintf("Redis 中缺少%d个, 多出%d个", len(missing), len(extra)),
+		})
+	}
+	return mismatches, nil
+}
+
+// repairFollowSet 修复一个用户的关注或粉丝集合
+// 以 Redis 为准时只按关注集合修改 user_follow；粉丝集合与关注集合冗余，总是按 MySQL 修复，
+// 因此以 Redis 为准修复时应同时检查两者，先检查的关注集合修复 user_follow 后，粉丝集合随之修复
+func (us *UserService) repairFollowSet(mismatch *user_model.AuditMismatch, from string) error {
+	missing, extra := mismatch.Missing, mismatch.Extra
+	if from == user_model.AuditRepairMySQL || mismatch.Target == user_model.AuditFans {
+		return us.userCacheRepository.UpdateFollowSet(mismatch.Target, mismatch.ID, missing, extra)
+	}
+
+	tx := us.userRepository.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	for _, followID := range extra {
+		if err := us.userRepository.AddFollows(tx, &user_model.UserFollow{
+			UserID:   mismatch.ID,
+			FollowID: followID,
+			CreateAt: time.Now(),
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, followID := range missing {
+		if err := us.userRepository.RemoveFollows(tx, mismatch.ID, followID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// auditUserBatches 按 ID 顺序分批遍历全部用户，抽样时只随机取一批
+func (us *UserService) auditUserBatches(ctx context.Context, sampleSize int, fn func(ids []int) error) error {
+	if sampleSize > 0 {
+		ids, err := us.userRepository.SampleUserIDs(sampleSize)
+		if err != nil {
+			return err
+		}
+		return fn(ids)
+	}
+	batchSize := us.config.Audit.BatchSize
+	afterID := 0
+	for ctx.Err() == nil {
+		ids, err := us.userRepository.GetUserIDsAfter(afterID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if err := fn(ids); err != nil {
+				return err
+			}
+			afterID = ids[len(ids)-1]
+		}
+		if len(ids) < batchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// auditBatch 检查一批对象，将两次检查都不一致的对象加入报告，并在指定了修复依据时逐个修复
+func (us *UserService) auditBatch(ctx context.Context, report *user_model.AuditReport, target string, ids []int,
+	check auditCheckFunc, repair func(mismatch *user_model.AuditMismatch, from string) error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	report.Checked[target] += len(ids)
+	mismatches, err := check(ids)
+	if err != nil || len(mismatches) == 0 {
+		return err
+	}
+
+	timer := time.NewTimer(us.config.Audit.RecheckDelay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return ctx.Err()
+	case <-timer.C:
+	}
+	recheckIDs := make([]int, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		recheckIDs = append(recheckIDs, mismatch.ID)
+	}
+	if mismatches, err = check(recheckIDs); err != nil {
+		return err
+	}
+
+	for _, mismatch := range mismatches {
+		if report.Options.RepairFrom != user_model.AuditRepairNone {
+			if err := repair(mismatch, report.Options.RepairFrom); err != nil {
+				mismatch.Detail += fmt.Sprintf("; 修复失败: %v", err)
+			} else {
+				mismatch.Repaired = true
+			}
+		}
+		report.Mismatches = append(report.Mismatches, mismatch)
+	}
+	return nil
+}
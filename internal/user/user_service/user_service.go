@@ -1,12 +1,16 @@
 package user_service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/streadway/amqp"
 	"gorm.io/gorm"
 	"huancuilou/common/utils"
 	"huancuilou/configs"
+	"huancuilou/internal/job/job_service"
+	"huancuilou/internal/moderation/moderation_model"
+	"huancuilou/internal/moderation/moderation_service"
 	"huancuilou/internal/user/user_model"
 	"huancuilou/internal/user/user_repository"
 	"log"
@@ -16,20 +20,28 @@ import (
 	"time"
 )
 
+// ChooseItemJob 秒杀消费者任务的名称，由调度器在持有租约的一个实例上运行
+const ChooseItemJob = "user_choose_item_consumer"
+
 // UserService 处理业务逻辑
 type UserService struct {
 	userRepository      *user_repository.UserRepository
 	config              *configs.Config
 	userMdbRepository   *user_repository.UserMemoryDBRepository
 	userCacheRepository *user_repository.UserCacheRepository
+	moderationService   *moderation_service.ModerationService
+	jobService          *job_service.JobService
 }
 
-func NewUserService(userRepository *user_repository.UserRepository, config *configs.Config, userMemoryDBRepository *user_repository.UserMemoryDBRepository, userCacheRepository *user_repository.UserCacheRepository) *UserService {
+func NewUserService(userRepository *user_repository.UserRepository, config *configs.Config, userMemoryDBRepository *user_repository.UserMemoryDBRepository, userCacheRepository *user_repository.UserCacheRepository,
+	moderationService *moderation_service.ModerationService, jobService *job_service.JobService) *UserService {
 	return &UserService{
 		userRepository:      userRepository,
 		config:              config,
 		userMdbRepository:   userMemoryDBRepository,
 		userCacheRepository: userCacheRepository,
+		moderationService:   moderationService,
+		jobService:          jobService,
 	}
 }
 
@@ -66,6 +78,14 @@ func (us *UserService) SendCode(phoneNumber string) error {
 	return nil
 }
 
+// RemoveExpiredCodes 清理本实例内存中过期的验证码
+func (us *UserService) RemoveExpiredCodes() error {
+	if removed := us.userMdbRepository.RemoveExpiredCodes(); removed > 0 {
+		log.Printf("清理过期验证码%d个", removed)
+	}
+	return nil
+}
+
 // Login 一键登录注册
 func (us *UserService) Login(userCode *user_model.UserCode) (*user_model.User, error) {
 	//比对验证码
@@ -148,12 +168,60 @@ func (us *UserService) AddAdminByPhoneNumber(phoneNumber string) error {
 }
 
 func (us *UserService) UpdateUserInfo(userID int, user *user_model.User) error {
-	if err := us.userRepository.UpdateUserInfo(userID, user); err != nil {
+	userName, err := us.filterUserField(userID, moderation_model.ContentUserName, user.UserName)
+	if err != nil {
+		return fmt.Errorf("UserService.UpdateUserInfo err: %w", err)
+	}
+	biography, err := us.filterUserField(userID, moderation_model.ContentUserBiography, user.Biography)
+	if err != nil {
+		return fmt.Errorf("UserService.UpdateUserInfo err: %w", err)
+	}
+	user.UserName = userName
+	user.Biography = biography
+
+	if err := us.userRepository.UpdateUserInfo(us.userRepository.DB, userID, user); err != nil {
 		return fmt.Errorf("UserService.UpdateUserInfo err: 500:更新用户信息出错:%w", err)
 	}
 	return nil
 }
 
+// filterUserField 对用户名、个人简介做敏感词处理
+// 需要人工审核的内容先提交到审核队列并返回空字符串，暂不更新该字段，审核通过后再生效
+func (us *UserService) filterUserField(userID int, contentType string, text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	filtered, result, err := us.moderationService.Filter(text)
+	if err != nil {
+		return "", err
+	}
+	if result.Action == moderation_model.ActionReview {
+		if err := us.moderationService.Submit(us.userRepository.DB, contentType, userID, userID, filtered, result.Words); err != nil {
+			return "", fmt.Errorf("500: %w", err)
+		}
+		log.Printf("用户%d的%s已提交审核", userID, contentType)
+		return "", nil
+	}
+	return filtered, nil
+}
+
+// ApproveModeratedUserInfo 审核通过后更新用户名或个人简介
+func (us *UserService) ApproveModeratedUserInfo(tx *gorm.DB, record *moderation_model.ModerationRecord) (func(), error) {
+	user := &user_model.User{}
+	switch record.ContentType {
+	case moderation_model.ContentUserName:
+		user.UserName = record.Content
+	case moderation_model.ContentUserBiography:
+		user.Biography = record.Content
+	default:
+		return nil, fmt.Errorf("UserService.ApproveModeratedUserInfo err: 未知的内容类型: %s", record.ContentType)
+	}
+	if err := us.userRepository.UpdateUserInfo(tx, record.ContentID, user); err != nil {
+		return nil, fmt.Errorf("UserService.ApproveModeratedUserInfo err: %w", err)
+	}
+	return nil, nil
+}
+
 func (us *UserService) AddScore(scoreRecord *user_model.ScoreRecord) error {
 	if err := us.userRepository.AddScore(scoreRecord); err != nil {
 		return fmt.Errorf("UserService.AddScore 数据库操作错误:添加积分记录错误:%w", err)
@@ -354,7 +422,8 @@ func (us *UserService) GetAllItems() ([]*user_model.CommunityItem, error) {
 	return items, nil
 }
 
-func (us *UserService) ChooseItem(userID int, itemID int) error {
+// ChooseItem 将选择物品的结果写入 MySQL，token 为秒杀消费者任务的防护令牌
+func (us *UserService) ChooseItem(userID int, itemID int, token int64) error {
 	tx := us.userRepository.DB.Begin()
 	if tx.Error != nil {
 		return fmt.Errorf("UserService.ChooseItem err:%w", tx.Error)
@@ -364,6 +433,20 @@ func (us *UserService) ChooseItem(userID int, itemID int) error {
 			tx.Rollback()
 		}
 	}()
+	if err := us.jobService.CheckFence(tx, token); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("UserService.ChooseItem err:%w", err)
+	}
+	// 消息在写入后、确认前消费者退出时会重新投递，已经写入过的不再重复扣减库存
+	chosen, err := us.userRepository.HasChosenItem(tx, userID, itemID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("UserService.ChooseItem err:%w", err)
+	}
+	if chosen {
+		tx.Rollback()
+		return nil
+	}
 	userItem := &user_model.UserItem{
 		UserID:   userID,
 		ItemID:   itemID,
@@ -436,114 +519,141 @@ func (us *UserService) ChooseItemPublisher(userID int, itemID int) error {
 	return nil
 }
 
+// handleChooseItemMessage 处理一条选择物品的消息并确认
+// 格式错误的消息直接丢弃；已经写入过的消息（重复投递）直接确认；
+// 防护令牌过期或数据库出错时消息重新入队并返回错误，由消费者退出后按任务的重试策略重新运行
+func (us *UserService) handleChooseItemMessage(msg amqp.Delivery, token int64) error {
+	log.Printf("收到消息: %s", msg.Body)
+	var userID, itemID int
+	stringParts := strings.Split(string(msg.Body), ",")
+	err := fmt.Errorf("无效消息格式: %s", msg.Body)
+	if len(stringParts) >= 2 {
+		if userID, err = strconv.Atoi(stringParts[0]); err == nil {
+			itemID, err = strconv.Atoi(stringParts[1])
+		}
+	}
+	if err != nil {
+		log.Printf("解析消息失败，丢弃消息: %v", err)
+		return msg.Reject(false)
+	}
+
+	err = us.ChooseItem(userID, itemID, token)
+	if err != nil && !strings.Contains(err.Error(), "Error 1062 (23000): Duplicate entry") {
+		log.Printf("选课失败，消息重新入队: 用户=%d, 物品=%d, 错误=%v", userID, itemID, err)
+		if nackErr := msg.Nack(false, true); nackErr != nil {
+			return fmt.Errorf("%w; 消息重新入队失败: %v", err, nackErr)
+		}
+		return err
+	}
+	if err != nil {
+		log.Printf("选课记录已存在: 用户=%d, 物品=%d", userID, itemID)
+	} else {
+		log.Printf("选课成功: 用户=%d, 物品=%d", userID, itemID)
+	}
+	return msg.Ack(false)
+}
+
+// AddChooseItemConsumer 设置秒杀消费者的开启时间段
+// 消费者是调度器中的一次性任务，在 begin 时由持有调度器租约的实例运行到 end，该实例宕机时由其他实例接管
 func (us *UserService) AddChooseItemConsumer(begin time.Time, end time.Time) error {
 	if time.Now().After(end) {
 		return fmt.Errorf("UserService.AddChooseItemConsumer err: 400: 结束时间不能小于当前时间")
 	}
-	if begin == end {
-		return fmt.Errorf("UserService.AddChooseItemConsumer err: 400: 开始时间不能等于结束时间")
+	if !begin.Before(end) {
+		return fmt.Errorf("UserService.AddChooseItemConsumer err: 400: 开始时间必须早于结束时间")
 	}
+	payload := strconv.FormatInt(end.Unix(), 10)
+	if err := us.jobService.ScheduleOnce(ChooseItemJob, begin, payload, 3, 5); err != nil {
+		return fmt.Errorf("UserService.AddChooseItemConsumer err: 500: %w", err)
+	}
+	return nil
+}
 
-	go func() {
-		if begin.After(time.Now()) {
-			time.Sleep(time.Until(begin))
-		}
+// RunChooseItemConsumer 调度器中的秒杀消费者任务，任务参数为结束时间的时间戳
+func (us *UserService) RunChooseItemConsumer(ctx context.Context, jc *job_service.JobContext) error {
+	endUnix, err := strconv.ParseInt(jc.Job.Payload, 10, 64)
+	if err != nil {
+		return fmt.Errorf("UserService.RunChooseItemConsumer err: 任务参数错误: %w", err)
+	}
+	end := time.Unix(endUnix, 0)
+	if !time.Now().Before(end) {
+		return nil
+	}
+	if err := us.consumeChooseItem(ctx, jc.Token, end); err != nil {
+		return fmt.Errorf("UserService.RunChooseItemConsumer err: %w", err)
+	}
+	return nil
+}
 
-		conn, err := amqp.Dial(us.config.RabbitMQ.DSN)
-		if err != nil {
-			log.Printf("UserService.ChooseItemConsumer err: %v", err)
-		}
-		defer func(conn *amqp.Connection) {
-			err := conn.Close()
-			if err != nil {
-				log.Printf("UserService.ChooseItemConsumer conn.Close err: %v", err)
-			}
-		}(conn)
-		ch, err := conn.Channel()
-		if err != nil {
-			log.Printf("UserService.ChooseItemConsumer err: %v", err)
+// consumeChooseItem 消费选择物品的消息，直到 end 或失去租约
+func (us *UserService) consumeChooseItem(ctx context.Context, token int64, end time.Time) error {
+	conn, err := amqp.Dial(us.config.RabbitMQ.DSN)
+	if err != nil {
+		return fmt.Errorf("UserService.consumeChooseItem err: %w", err)
+	}
+	defer func(conn *amqp.Connection) {
+		if err := conn.Close(); err != nil {
+			log.Printf("UserService.consumeChooseItem conn.Close err: %v", err)
 		}
-		defer func(ch *amqp.Channel) {
-			err := ch.Close()
-			if err != nil {
-				log.Printf("UserService.ChooseItemConsumer ch.Close err: %v", err)
-			}
-		}(ch)
-
-		q, err := ch.QueueDeclare(
-			"hcl_user_choose_item",
-			us.config.RabbitMQ.Durable,
-			false,
-			false,
-			false,
-			nil,
-		)
-		if err != nil {
-			log.Printf("UserService.ChooseItemConsumer err: %v", err)
+	}(conn)
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("UserService.consumeChooseItem err: %w", err)
+	}
+	defer func(ch *amqp.Channel) {
+		if err := ch.Close(); err != nil {
+			log.Printf("UserService.consumeChooseItem ch.Close err: %v", err)
 		}
+	}(ch)
 
-		// 获取接收消息的Delivery通道
-		messages, err := ch.Consume(
-			q.Name, // queue
-			"",     // consumer
-			true,   // auto-ack
-			false,  // exclusive
-			false,  // no-local
-			false,  // no-wait
-			nil,    // args
-		)
-		if err != nil {
-			log.Printf("Failed to register a consumer: %v", err)
-		}
+	q, err := ch.QueueDeclare(
+		"hcl_user_choose_item",
+		us.config.RabbitMQ.Durable,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("UserService.consumeChooseItem err: %w", err)
+	}
+
+	// 手动确认消息，写入 MySQL 成功后才确认；每次只预取一条，未确认的消息在消费者退出后重新投递给其他消费者
+	if err := ch.Qos(1, 0, false); err != nil {
+		return fmt.Errorf("UserService.consumeChooseItem err: %w", err)
+	}
+	// 获取接收消息的Delivery通道
+	messages, err := ch.Consume(
+		q.Name, // queue
+		"",     // consumer
+		false,  // auto-ack
+		false,  // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return fmt.Errorf("UserService.consumeChooseItem err: %w", err)
+	}
 
-		stopCh := make(chan struct{})
-
-		go func() {
-			for {
-				select {
-				case msg, ok := <-messages:
-					if !ok {
-						log.Println("消息通道已关闭")
-						return
-					}
-					log.Printf("收到消息: %s", msg.Body)
-					stringParts := strings.Split(string(msg.Body), ",")
-					if len(stringParts) < 2 {
-						log.Printf("无效消息格式: %s", msg.Body)
-						continue
-					}
-
-					userID, err := strconv.Atoi(stringParts[0])
-					if err != nil {
-						log.Printf("解析用户ID失败: %v", err)
-						continue
-					}
-
-					itemID, err := strconv.Atoi(stringParts[1])
-					if err != nil {
-						log.Printf("解析物品ID失败: %v", err)
-						continue
-					}
-
-					if err := us.ChooseItem(userID, itemID); err != nil {
-						log.Printf("选课失败: 用户=%d, 物品=%d, 错误=%v", userID, itemID, err)
-					} else {
-						log.Printf("选课成功: 用户=%d, 物品=%d", userID, itemID)
-					}
-				case <-stopCh:
-					log.Println("收到停止信号，退出消息处理")
-					return
-				}
+	log.Printf("开启消费者 监听中……")
+	timer := time.NewTimer(time.Until(end))
+	defer timer.Stop()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("UserService.consumeChooseItem err: 消息通道已关闭")
 			}
-		}()
-
-		log.Printf("开启消费者 监听中……")
-
-		duration := end.Sub(time.Now())
-		time.Sleep(duration)
-		close(stopCh)
-
-		log.Printf("关闭消费者")
-	}()
-	return nil
+			if err := us.handleChooseItemMessage(msg, token); err != nil {
+				return fmt.Errorf("UserService.consumeChooseItem err: %w", err)
+			}
+		case <-timer.C:
+			log.Printf("关闭消费者")
+			return nil
+		case <-ctx.Done():
+			log.Printf("失去租约，关闭消费者")
+			return ctx.Err()
+		}
+	}
 }
@@ -1,16 +1,43 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"huancuilou/configs"
 	"huancuilou/initial"
 	"huancuilou/internal/article/article_controller"
+	"huancuilou/internal/article/article_model"
 	"huancuilou/internal/article/article_repository"
 	"huancuilou/internal/article/article_service"
+	"huancuilou/internal/comment/comment_controller"
+	"huancuilou/internal/comment/comment_repository"
+	"huancuilou/internal/comment/comment_service"
+	"huancuilou/internal/feed/feed_controller"
+	"huancuilou/internal/feed/feed_repository"
+	"huancuilou/internal/feed/feed_service"
+	"huancuilou/internal/job/job_controller"
+	"huancuilou/internal/job/job_repository"
+	"huancuilou/internal/job/job_service"
+	"huancuilou/internal/moderation/moderation_controller"
+	"huancuilou/internal/moderation/moderation_model"
+	"huancuilou/internal/moderation/moderation_repository"
+	"huancuilou/internal/moderation/moderation_service"
+	"huancuilou/internal/notification/notification_controller"
+	"huancuilou/internal/notification/notification_repository"
+	"huancuilou/internal/notification/notification_service"
+	"huancuilou/internal/upload/upload_controller"
+	"huancuilou/internal/upload/upload_repository"
+	"huancuilou/internal/upload/upload_service"
 	"huancuilou/internal/user/user_controller"
+	"huancuilou/internal/user/user_model"
 	"huancuilou/internal/user/user_repository"
 	"huancuilou/internal/user/user_service"
 	"huancuilou/routers"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 func main() {
@@ -21,27 +48,240 @@ func main() {
 		log.Fatalf("初始化数据库失败：%v", err)
 	}
 
+	//后台任务相关包的依赖注入
+	jobRepository := job_repository.NewJobRepository(db)
+	leaseRepository := job_repository.NewLeaseRepository(RedisClient)
+	fenceRepository := job_repository.NewFenceRepository(db)
+	jobService := job_service.NewJobService(jobRepository, leaseRepository, fenceRepository, &cfg)
+	jobController := job_controller.NewJobController(jobService)
+
+	//审核相关包的依赖注入
+	moderationRepository := moderation_repository.NewModerationRepository(db)
+	moderationService := moderation_service.NewModerationService(moderationRepository, &cfg)
+	moderationController := moderation_controller.NewModerationController(moderationService)
+	if err = moderationService.LoadWords(); err != nil {
+		log.Fatalf("加载敏感词失败：%v", err)
+	}
+
 	//用户相关包的依赖注入
 	userRepository := user_repository.NewUserRepository(db)
 	userMdbRepository := user_repository.NewUserMemoryDBRepository()
 	userCacheRepository := user_repository.NewUserCacheRepository(RedisClient)
-	userService := user_service.NewUserService(userRepository, &cfg, userMdbRepository, userCacheRepository)
+	userService := user_service.NewUserService(userRepository, &cfg, userMdbRepository, userCacheRepository, moderationService, jobService)
 	userController := user_controller.NewUserController(userService, cfg.Jwt)
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAudit(userService, os.Args[2:]))
+	}
 
 	//文章相关包的依赖注入
 	articleRepository := article_repository.NewArticleRepository(db)
 	articleCacheRepository := article_repository.NewArticleCacheRepository(RedisClient)
-	articleService := article_service.NewArticleService(articleRepository, articleCacheRepository)
+	if err = articleRepository.EnsureSearchIndex(); err != nil {
+		log.Fatalf("初始化文章全文索引失败：%v", err)
+	}
+	if err = articleRepository.EnsureUpdateAtColumn(); err != nil {
+		log.Fatalf("初始化文章修改时间列失败：%v", err)
+	}
+	if err = articleRepository.EnsureAckIndex(); err != nil {
+		log.Fatalf("初始化确认已读唯一索引失败：%v", err)
+	}
+	articleService := article_service.NewArticleService(articleRepository, articleCacheRepository, moderationService, jobService, &cfg)
 	articleController := article_controller.NewArticleController(articleService)
+	if err = articleService.WarmUpCaches(); err != nil {
+		log.Fatalf("预热文章缓存失败：%v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExport(articleService, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(articleService, os.Args[2:]))
+	}
+
+	//关注流相关包的依赖注入
+	feedCacheRepository := feed_repository.NewFeedCacheRepository(RedisClient)
+	feedService := feed_service.NewFeedService(feedCacheRepository, articleRepository, articleService, userCacheRepository, &cfg)
+	feedController := feed_controller.NewFeedController(feedService)
+	articleService.RegisterPublishHandler(feedService.FanOut)
+
+	//通知相关包的依赖注入
+	notificationRepository := notification_repository.NewNotificationRepository(db)
+	notificationService := notification_service.NewNotificationService(notificationRepository)
+	notificationController := notification_controller.NewNotificationController(notificationService)
+
+	//评论相关包的依赖注入
+	commentRepository := comment_repository.NewCommentRepository(db)
+	commentCacheRepository := comment_repository.NewCommentCacheRepository(RedisClient)
+	commentService := comment_service.NewCommentService(commentRepository, commentCacheRepository, articleRepository, articleCacheRepository, notificationService, moderationService, &cfg)
+	commentController := comment_controller.NewCommentController(commentService)
+
+	//上传相关包的依赖注入
+	storage, err := upload_repository.NewStorage(cfg.Upload)
+	if err != nil {
+		log.Fatalf("初始化文件存储失败：%v", err)
+	}
+	uploadRepository := upload_repository.NewUploadRepository(db)
+	uploadService := upload_service.NewUploadService(uploadRepository, storage, articleRepository, userRepository, &cfg)
+	uploadController := upload_controller.NewUploadController(uploadService)
 
-	Router := routers.SetUpRouters(userController, articleController)
+	//注册各类内容的审核结果处理方
+	moderationService.RegisterHandler(moderation_model.ContentUserName, moderation_service.ReviewHandler{Approve: userService.ApproveModeratedUserInfo})
+	moderationService.RegisterHandler(moderation_model.ContentUserBiography, moderation_service.ReviewHandler{Approve: userService.ApproveModeratedUserInfo})
+	moderationService.RegisterHandler(moderation_model.ContentArticle, moderation_service.ReviewHandler{Reject: articleService.RejectModeratedArticle})
+	moderationService.RegisterHandler(moderation_model.ContentComment, moderation_service.ReviewHandler{
+		Approve: commentService.ApproveModeratedComment,
+		Reject:  commentService.RejectModeratedComment,
+	})
 
+	//注册调度器中的任务，调度器中的任务只在持有调度器租约的一个实例上运行
+	jobService.RegisterHandler(article_service.LikeFlushJob, articleService.FlushLikesJob)
+	jobService.RegisterHandler(article_service.OutboxRelayJob, articleService.RelayOutboxJob)
+	jobService.RegisterHandler(article_service.HotRecomputeJob, articleService.RecomputeHotJob)
+	jobService.RegisterHandler(article_service.SimilarRecomputeJob, articleService.RecomputeSimilarJob)
+	jobService.RegisterHandler(article_service.PinExpireJob, articleService.ExpirePinsJob)
+	jobService.RegisterHandler(user_service.ChooseItemJob, userService.RunChooseItemConsumer)
+	jobService.RegisterHandler(user_service.AuditJob, userService.RunAuditJob)
+	jobService.RegisterHandler(user_service.AuditManualJob, userService.RunAuditJob)
+	if err = jobService.EnsureCronJob(article_service.LikeFlushJob, "@every "+cfg.Article.LikeFlushMinInterval.String(), 0, 0); err != nil {
+		log.Fatalf("创建点赞回写任务失败：%v", err)
+	}
+	if err = jobService.EnsureCronJob(article_service.OutboxRelayJob, "@every "+cfg.Article.OutboxRelayInterval.String(), 0, 0); err != nil {
+		log.Fatalf("创建缓存发件箱任务失败：%v", err)
+	}
+	if err = jobService.EnsureCronJob(article_service.HotRecomputeJob, cfg.Article.Hot.RecomputeSpec, 0, 0); err != nil {
+		log.Fatalf("创建文章热度重算任务失败：%v", err)
+	}
+	if err = jobService.EnsureCronJob(article_service.SimilarRecomputeJob, cfg.Article.Recommend.RecomputeSpec, 0, 0); err != nil {
+		log.Fatalf("创建文章相似度计算任务失败：%v", err)
+	}
+	if err = jobService.EnsureCronJob(article_service.PinExpireJob, "@every "+cfg.Article.PinExpireInterval.String(), 0, 0); err != nil {
+		log.Fatalf("创建取消过期置顶任务失败：%v", err)
+	}
+	if err = jobService.EnsureCronJob(user_service.AuditJob, cfg.Audit.Spec, 0, 0); err != nil {
+		log.Fatalf("创建缓存一致性检查任务失败：%v", err)
+	}
 	go func() {
-		articleService.PeriodicUpdateLikes(cfg.Article.UpdateLikesInterval)
+		jobService.RunExclusive(job_service.SchedulerJob, jobService.RunScheduler)
 	}()
 
+	//敏感词重新加载和过期验证码清理在每个实例上运行
+	if err = jobService.RunLocal("moderation_reload_words", "@every "+cfg.Moderation.ReloadInterval.String(), moderationService.LoadWords); err != nil {
+		log.Fatalf("启动敏感词重新加载任务失败：%v", err)
+	}
+	if err = jobService.RunLocal("user_remove_expired_codes", "@every "+cfg.Code.ExpireDuration.String(), userService.RemoveExpiredCodes); err != nil {
+		log.Fatalf("启动过期验证码清理任务失败：%v", err)
+	}
+
+	Router := routers.SetUpRouters(userController, articleController, commentController, notificationController, moderationController, uploadController, jobController, feedController)
+
 	if err = Router.Run(":8080"); err != nil {
 		log.Fatalf("初始化路由失败：%v", err)
 	}
 
 }
+
+// runAudit 以命令行方式运行 Redis 与 MySQL 的一致性检查，报告以 JSON 输出，存在未修复的不一致时返回 1
+// 用法：huancuilou audit [-targets item_remain,user_likes,follows,fans] [-sample 0] [-repair mysql|redis]
+func runAudit(userService *user_service.UserService, args []string) int {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	targets := flags.String("targets", "", "检查对象，用逗号分隔，为空时检查全部")
+	sample := flags.Int("sample", 0, "每类对象随机抽样的个数，0 表示全量扫描")
+	repair := flags.String("repair", "", "修复时作为准确数据的一方（mysql 或 redis），为空时只报告")
+	_ = flags.Parse(args)
+
+	options := &user_model.AuditOptions{SampleSize: *sample, RepairFrom: *repair}
+	if *targets != "" {
+		options.Targets = strings.Split(*targets, ",")
+	}
+	report, err := userService.Audit(context.Background(), options)
+	if err != nil {
+		log.Printf("一致性检查失败：%v", err)
+		return 2
+	}
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Printf("输出检查结果失败：%v", err)
+		return 2
+	}
+	os.Stdout.Write(append(output, '\n'))
+	for _, mismatch := range report.Mismatches {
+		if !mismatch.Repaired {
+			return 1
+		}
+	}
+	return 0
+}
+
+// runExport 以命令行方式导出文章，默认输出到标准输出
+// 用法：huancuilou export [-format json|csv] [-kind 类型] [-o 文件]
+func runExport(articleService *article_service.ArticleService, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", article_model.TransferJSON, "文件格式（json 或 csv）")
+	kind := flags.String("kind", "", "只导出该类型的文章，为空时导出全部")
+	outputPath := flags.String("o", "", "输出文件，为空时输出到标准输出")
+	_ = flags.Parse(args)
+
+	records, err := articleService.ExportArticles(*kind)
+	if err != nil {
+		log.Printf("导出文章失败：%v", err)
+		return 2
+	}
+	output := os.Stdout
+	if *outputPath != "" {
+		if output, err = os.Create(*outputPath); err != nil {
+			log.Printf("创建输出文件失败：%v", err)
+			return 2
+		}
+		defer output.Close()
+	}
+	if err := article_service.WriteArticleRecords(output, *format, records); err != nil {
+		log.Printf("写入导出文件失败：%v", err)
+		return 2
+	}
+	log.Printf("导出文章%d篇", len(records))
+	return 0
+}
+
+// runImport 以命令行方式导入文章，报告以 JSON 输出，存在导入失败的行时返回 1
+// 用法：huancuilou import -manager 管理员ID [-format json|csv] [-dry-run] 文件
+func runImport(articleService *article_service.ArticleService, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "文件格式（json 或 csv），为空时由文件扩展名判断")
+	managerID := flags.Int("manager", 0, "没有指定管理员的行使用的管理员 ID")
+	dryRun := flags.Bool("dry-run", false, "只校验不写入")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Printf("用法：huancuilou import -manager 管理员ID [-format json|csv] [-dry-run] 文件")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("打开导入文件失败：%v", err)
+		return 2
+	}
+	defer file.Close()
+	records, rowErrors, err := articleService.ReadArticleRecords(file, *format)
+	if err != nil {
+		log.Printf("读取导入文件失败：%v", err)
+		return 2
+	}
+	report, err := articleService.ImportArticles(records, rowErrors, &article_model.ImportOptions{DryRun: *dryRun, ManagerID: *managerID})
+	if err != nil {
+		log.Printf("导入文章失败：%v", err)
+		return 2
+	}
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Printf("输出导入结果失败：%v", err)
+		return 2
+	}
+	os.Stdout.Write(append(output, '\n'))
+	if len(report.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -4,11 +4,20 @@ import (
 	"github.com/gin-gonic/gin"
 	"huancuilou/common/utils"
 	"huancuilou/internal/article/article_controller"
+	"huancuilou/internal/comment/comment_controller"
+	"huancuilou/internal/feed/feed_controller"
+	"huancuilou/internal/job/job_controller"
+	"huancuilou/internal/moderation/moderation_controller"
+	"huancuilou/internal/notification/notification_controller"
+	"huancuilou/internal/upload/upload_controller"
 	"huancuilou/internal/user/user_controller"
 )
 
 // SetUpRouters 设置路由
-func SetUpRouters(userController *user_controller.UserController, articleController *article_controller.ArticleController) *gin.Engine {
+func SetUpRouters(userController *user_controller.UserController, articleController *article_controller.ArticleController,
+	commentController *comment_controller.CommentController, notificationController *notification_controller.NotificationController,
+	moderationController *moderation_controller.ModerationController, uploadController *upload_controller.UploadController,
+	jobController *job_controller.JobController, feedController *feed_controller.FeedController) *gin.Engine {
 	r := gin.Default()
 
 	userGroup := r.Group("/user")
@@ -32,16 +41,94 @@ func SetUpRouters(userController *user_controller.UserController, articleControl
 		userGroup.GET("/get-all-items", utils.JwtInterceptor(), userController.GetAllItems)
 		userGroup.GET("/choose-item/:itemID", utils.JwtInterceptor(), userController.ChooseItem)
 		userGroup.GET("/add-item-consumer", utils.AdminOnlyMiddleware(), userController.AddChooseItemConsumer)
+		userGroup.POST("/audit", utils.AdminOnlyMiddleware(), userController.Audit)
 	}
 
 	articleGroup := r.Group("/article")
 	{
 		articleGroup.POST("", utils.AdminOnlyMiddleware(), articleController.AddArticle)
 		articleGroup.GET("/get-all-article", utils.JwtInterceptor(), articleController.GetAllArticle)
+		articleGroup.GET("/search", utils.JwtInterceptor(), articleController.SearchArticle)
+		articleGroup.GET("/get-all-article-by-tag", utils.JwtInterceptor(), articleController.GetAllArticleByTag)
+		articleGroup.GET("/tag/autocomplete", utils.AdminOnlyMiddleware(), articleController.AutocompleteTags)
+		articleGroup.GET("/tag/popular", utils.JwtInterceptor(), articleController.GetPopularTags)
+		articleGroup.GET("/hot", utils.JwtInterceptor(), articleController.GetHotArticles)
+		articleGroup.GET("/rss", articleController.GetRSS)
+		articleGroup.GET("/atom", articleController.GetAtom)
+		articleGroup.GET("/recommend", utils.JwtInterceptor(), articleController.RecommendArticles)
+		articleGroup.GET("/favorites", utils.JwtInterceptor(), articleController.GetFavorites)
+		articleGroup.GET("/analytics/views", utils.AdminOnlyMiddleware(), articleController.GetTopViewedArticles)
+		articleGroup.GET("/analytics/views/:articleID", utils.AdminOnlyMiddleware(), articleController.GetViewStats)
+		articleGroup.GET("/export", utils.AdminOnlyMiddleware(), articleController.ExportArticles)
+		articleGroup.POST("/import", utils.AdminOnlyMiddleware(), articleController.ImportArticles)
+		articleGroup.GET("/ack/report/:articleID", utils.AdminOnlyMiddleware(), articleController.GetAckReport)
+		articleGroup.GET("/ack/outstanding/:articleID", utils.AdminOnlyMiddleware(), articleController.ExportOutstandingResidents)
+		articleGroup.POST("/ack/:articleID", utils.JwtInterceptor(), articleController.AcknowledgeArticle)
+		articleGroup.PUT("/ack/require/:articleID", utils.AdminOnlyMiddleware(), articleController.SetRequireAck)
 		articleGroup.GET("/:articleID", utils.JwtInterceptor(), articleController.GetArticle)
 		articleGroup.GET("/add-likes/:articleID", utils.JwtInterceptor(), articleController.AddLikes)
 		articleGroup.DELETE("/remove-likes/:articleID", utils.JwtInterceptor(), articleController.RemoveLikes)
+		articleGroup.GET("/likers/:articleID", utils.JwtInterceptor(), articleController.GetLikers)
+		articleGroup.GET("/add-favorite/:articleID", utils.JwtInterceptor(), articleController.AddFavorite)
+		articleGroup.DELETE("/remove-favorite/:articleID", utils.JwtInterceptor(), articleController.RemoveFavorite)
 		articleGroup.PUT("", utils.AdminOnlyMiddleware(), articleController.UpdateArticle)
+		articleGroup.PUT("/likes/rebuild", utils.AdminOnlyMiddleware(), articleController.RebuildLikes)
+		articleGroup.PUT("/likes/flush", utils.AdminOnlyMiddleware(), articleController.FlushLikes)
+		articleGroup.PUT("/cache/rebuild", utils.AdminOnlyMiddleware(), articleController.RebuildCaches)
+		articleGroup.PUT("/pin/:articleID", utils.AdminOnlyMiddleware(), articleController.PinArticle)
+		articleGroup.DELETE("/pin/:articleID", utils.AdminOnlyMiddleware(), articleController.UnpinArticle)
+	}
+
+	feedGroup := r.Group("/feed")
+	{
+		feedGroup.GET("", utils.JwtInterceptor(), feedController.GetFeed)
+	}
+
+	commentGroup := r.Group("/comment")
+	{
+		commentGroup.POST("", utils.JwtInterceptor(), commentController.AddComment)
+		commentGroup.GET("/get-comments/:articleID", utils.JwtInterceptor(), commentController.GetComments)
+		commentGroup.GET("/get-replies/:commentID", utils.JwtInterceptor(), commentController.GetReplies)
+		commentGroup.GET("/add-likes/:commentID", utils.JwtInterceptor(), commentController.AddLikes)
+		commentGroup.DELETE("/remove-likes/:commentID", utils.JwtInterceptor(), commentController.RemoveLikes)
+		commentGroup.DELETE("/:commentID", utils.JwtInterceptor(), commentController.DeleteComment)
+		commentGroup.PUT("/moderate/:commentID", utils.AdminOnlyMiddleware(), commentController.ModerateComment)
+	}
+
+	notificationGroup := r.Group("/notification")
+	{
+		notificationGroup.GET("", utils.JwtInterceptor(), notificationController.GetNotifications)
+		notificationGroup.GET("/unread-count", utils.JwtInterceptor(), notificationController.CountUnread)
+		notificationGroup.PUT("/read/:notificationID", utils.JwtInterceptor(), notificationController.MarkRead)
+		notificationGroup.PUT("/read-all", utils.JwtInterceptor(), notificationController.MarkAllRead)
+	}
+
+	moderationGroup := r.Group("/moderation")
+	{
+		moderationGroup.GET("/records", utils.AdminOnlyMiddleware(), moderationController.GetRecords)
+		moderationGroup.PUT("/approve/:recordID", utils.AdminOnlyMiddleware(), moderationController.Approve)
+		moderationGroup.PUT("/reject/:recordID", utils.AdminOnlyMiddleware(), moderationController.Reject)
+		moderationGroup.GET("/words", utils.AdminOnlyMiddleware(), moderationController.GetWords)
+		moderationGroup.POST("/words", utils.AdminOnlyMiddleware(), moderationController.AddWord)
+		moderationGroup.DELETE("/words", utils.AdminOnlyMiddleware(), moderationController.RemoveWord)
+	}
+
+	uploadGroup := r.Group("/upload")
+	{
+		uploadGroup.POST("/avatar", utils.JwtInterceptor(), uploadController.UploadAvatar)
+		uploadGroup.POST("/article/:articleID", utils.AdminOnlyMiddleware(), uploadController.UploadAttachment)
+		uploadGroup.GET("/article/:articleID", utils.JwtInterceptor(), uploadController.GetAttachments)
+		uploadGroup.DELETE("/attachment/:attachmentID", utils.AdminOnlyMiddleware(), uploadController.DeleteAttachment)
+		uploadGroup.GET("/file/*key", uploadController.GetFile)
+	}
+
+	jobGroup := r.Group("/job")
+	{
+		jobGroup.GET("", utils.AdminOnlyMiddleware(), jobController.GetJobs)
+		jobGroup.GET("/:jobID/runs", utils.AdminOnlyMiddleware(), jobController.GetRuns)
+		jobGroup.PUT("/:jobID/pause", utils.AdminOnlyMiddleware(), jobController.PauseJob)
+		jobGroup.PUT("/:jobID/resume", utils.AdminOnlyMiddleware(), jobController.ResumeJob)
+		jobGroup.PUT("/:jobID/trigger", utils.AdminOnlyMiddleware(), jobController.TriggerJob)
 	}
 
 	return r